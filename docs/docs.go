@@ -24,9 +24,49 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/bids": {
+        "/admin/apikeys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List all the api keys known to the server, without their secrets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "List api keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "get string by ID",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Issue a new api key with the given role and scopes. The token is only returned once.",
                 "consumes": [
                     "application/json"
                 ],
@@ -34,24 +74,132 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Bids"
+                    "Admin"
                 ],
-                "summary": "Post a new bid",
+                "summary": "Issue a new api key",
+                "parameters": [
+                    {
+                        "description": "APIKey",
+                        "name": "APIKey",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.APIKeyCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/apikeys/{keyid}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Permanently revoke an api key",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Revoke an api key",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "itemuuid",
-                        "name": "itemuuid",
+                        "description": "keyid",
+                        "name": "keyid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/apikeys/{keyid}/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replace the secret of an api key, optionally keeping the old one valid for a grace period",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Rotate an api key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "keyid",
+                        "name": "keyid",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Bid",
-                        "name": "Bid",
+                        "description": "Rotation",
+                        "name": "Rotation",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/bidtracker.Bid"
+                            "$ref": "#/definitions/api.APIKeyRotateRequest"
                         }
                     }
                 ],
@@ -59,7 +207,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.ResponseBid"
+                            "$ref": "#/definitions/api.Response"
                         }
                     },
                     "400": {
@@ -68,8 +216,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.Response"
                         }
                     },
-                    "422": {
-                        "description": "Unprocessable Entity",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/api.Response"
                         }
@@ -77,24 +237,63 @@ const docTemplate = `{
                 }
             }
         },
-        "/bids/{itemuuid}": {
+        "/admin/reports/shill": {
             "get": {
-                "description": "get string by ID",
-                "consumes": [
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Flag users showing shill bidding or collusion patterns: bidding up one seller's items without winning, bursts of bids from new users and pairs of users taking turns",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Report suspicious bidding",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reports/shill/{useruuid}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Clear a user found to be flagged by mistake: their bids are accepted again even when blocking is enabled, and the report leaves them out",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Bids"
+                    "Admin"
                 ],
-                "summary": "Get all current bids on an item",
+                "summary": "Clear the shill bidding flags of a user",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "itemuuid",
-                        "name": "itemuuid",
+                        "description": "useruuid",
+                        "name": "useruuid",
                         "in": "path",
                         "required": true
                     }
@@ -103,7 +302,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.ResponseGetBids"
+                            "$ref": "#/definitions/api.Response"
                         }
                     },
                     "400": {
@@ -112,8 +311,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.Response"
                         }
                     },
-                    "422": {
-                        "description": "Unprocessable Entity",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/api.Response"
                         }
@@ -121,9 +332,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/bids/{itemuuid}/winning": {
+        "/analytics": {
             "get": {
-                "description": "get string by ID",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the number of items, bids and bidders, the bid rate, the sum of the leading bids and a histogram of the bid amounts across all items",
                 "consumes": [
                     "application/json"
                 ],
@@ -131,23 +347,22 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Bids"
+                    "Analytics"
                 ],
-                "summary": "Get currently winning bids",
+                "summary": "Get analytics across all items",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "itemuuid",
-                        "name": "itemuuid",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "description": "number of histogram buckets, 10 by default and at most 100",
+                        "name": "buckets",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.ResponseBid"
+                            "$ref": "#/definitions/api.ResponseSystemAnalytics"
                         }
                     },
                     "400": {
@@ -156,8 +371,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.Response"
                         }
                     },
-                    "422": {
-                        "description": "Unprocessable Entity",
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/api.Response"
                         }
@@ -165,9 +380,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{useruuid}/bids": {
+        "/analytics/items/{itemuuid}": {
             "get": {
-                "description": "Get all the bids of a user by its uuid",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the number of bids and bidders, the bid rate, the price over time in buckets and a histogram of the bid amounts on an item",
                 "consumes": [
                     "application/json"
                 ],
@@ -175,23 +395,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Analytics"
                 ],
-                "summary": "Get all the bids of a user",
+                "summary": "Get analytics of an item",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "useruuid",
-                        "name": "useruuid",
+                        "description": "itemuuid",
+                        "name": "itemuuid",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "width of a price series bucket in seconds, 60 by default",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of histogram buckets, 10 by default and at most 100",
+                        "name": "buckets",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.ResponseGetBids"
+                            "$ref": "#/definitions/api.ResponseItemAnalytics"
                         }
                     },
                     "400": {
@@ -200,55 +432,1633 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.Response"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/api.Response"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "api.Response": {
-            "type": "object",
-            "properties": {
-                "data": {},
-                "message": {
-                    "type": "string"
-                },
-                "status": {
-                    "type": "integer"
-                }
-            }
-        },
-        "api.ResponseBid": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "$ref": "#/definitions/bidtracker.Bid"
-                },
-                "message": {
-                    "type": "string"
-                },
-                "status": {
-                    "type": "integer"
-                }
-            }
         },
-        "api.ResponseGetBids": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/bidtracker.Bid"
-                    }
+        "/bids": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "get string by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bids"
+                ],
+                "summary": "Post a new bid",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "itemuuid",
+                        "name": "itemuuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Bid",
+                        "name": "Bid",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/bidtracker.Bid"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseBid"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/bids/{itemuuid}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "get string by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bids"
+                ],
+                "summary": "Get all current bids on an item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "itemuuid",
+                        "name": "itemuuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, 100 by default",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "NextCursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "time",
+                            "amount"
+                        ],
+                        "type": "string",
+                        "description": "sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "minimum amount",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "maximum amount",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "minimum timestamp",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximum timestamp",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only bids of this user",
+                        "name": "useruuid",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseGetBids"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/bids/{itemuuid}/leaderboard": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the top users on an item, ranked by their best bid",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bids"
+                ],
+                "summary": "Get the leaderboard of an item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "itemuuid",
+                        "name": "itemuuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of users, 10 by default and at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseLeaderboard"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/bids/{itemuuid}/rank/{useruuid}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the rank of a user among all the bidders of an item, and the best bid it comes from",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bids"
+                ],
+                "summary": "Get the rank of a user on an item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "itemuuid",
+                        "name": "itemuuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "useruuid",
+                        "name": "useruuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseRank"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/bids/{itemuuid}/winning": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "get string by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bids"
+                ],
+                "summary": "Get currently winning bids",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "itemuuid",
+                        "name": "itemuuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseBid"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/bids:batch": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Insert an array of bids in order, with a result per bid telling whether it was accepted, why not, and whether it became the leading bid of its item.\nEvery valid bid is accepted unless atomic is set, then either all of them are or none is, and the response is a 422 with the results.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bids"
+                ],
+                "summary": "Post many bids at once",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "all-or-nothing instead of best-effort",
+                        "name": "atomic",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Bids, at most 1000",
+                        "name": "Bids",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/bidtracker.Bid"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseBidBatch"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseBidBatch"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/export/bids": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stream every bid, or only the bids of an item, ordered by itemuuid and then in the order they were placed",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "Export bids in bulk",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "jsonl (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only export the bids of this item",
+                        "name": "itemuuid",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/graphql": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Query items with their winning and top bids, bids and the portfolio of users, place bids with the placeBid mutation,\nor subscribe to leaderChanged with Accept: text/event-stream, which streams a next event per result and a complete event at the end.\nEach field checks the permission of the matching REST route.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GraphQL"
+                ],
+                "summary": "Run a GraphQL query, mutation or subscription",
+                "parameters": [
+                    {
+                        "description": "GraphQL request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.GraphQLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.GraphQLResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.GraphQLResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Report that the process is alive",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/import/bids": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Record the bids of a csv or json lines body one row at a time, as they were placed at their timestamp. Invalid rows are reported and skipped, a dry run only reports.\nThe csv header must have the itemuuid, useruuid, timestamp and amount columns.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "Import historical bids in bulk",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "jsonl (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only validate the rows",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/import/items": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Add the items of a csv or json lines body one row at a time. Invalid rows are reported and skipped, a dry run only reports.\nThe csv header must have the itemuuid and title columns, it may have description, selleruuid, endtime, and categories, images and attributes (as key=value) separated by |.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "Import items in bulk",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "jsonl (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only validate the rows",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/items": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the item catalogue ordered by title, optionally only the items of a seller or in a category",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Items"
+                ],
+                "summary": "List items",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "only items of this seller",
+                        "name": "selleruuid",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only items in this category",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseItems"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Put a new item up for auction. A uuid is generated if none is provided, and sellers default to their own uuid.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Items"
+                ],
+                "summary": "Create an item",
+                "parameters": [
+                    {
+                        "description": "Item",
+                        "name": "Item",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/bidtracker.Item"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/items/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Full text search over item titles and descriptions, narrowed down by category, status and price range.\nFacet counts cover every item matching the text, before the facets are applied.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Items"
+                ],
+                "summary": "Search items",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "words every matching title or description contains",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "comma separated categories, items in any of them match",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "open, closing_soon or closed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "lowest current price, inclusive",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "highest current price, inclusive",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "relevance, ending_soonest, most_bids or highest_price",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, 20 by default and at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseItemSearch"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/items/{itemuuid}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the catalogue metadata of an item",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Items"
+                ],
+                "summary": "Get an item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "itemuuid",
+                        "name": "itemuuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replace the catalogue metadata of an item, its bids are kept. Only moderators change the end of an auction that has bids or has closed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Items"
+                ],
+                "summary": "Update an item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "itemuuid",
+                        "name": "itemuuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Item",
+                        "name": "Item",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/bidtracker.Item"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Expose the metrics of the service in the prometheus text format",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Prometheus metrics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Report whether recovery is complete, the server is not draining and every registered check, e.g. storage, passes",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseReadiness"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseReadiness"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{useruuid}/bids": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get all the bids of a user by its uuid",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get all the bids of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "useruuid",
+                        "name": "useruuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, 100 by default",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "NextCursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "time",
+                            "amount"
+                        ],
+                        "type": "string",
+                        "description": "sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "minimum amount",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "maximum amount",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "minimum timestamp",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximum timestamp",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only bids on this item",
+                        "name": "itemuuid",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseGetBids"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{useruuid}/portfolio": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the standing of a user on every item they bid on: their highest bid, the leading amount, whether they are winning, outbid, won or lost, and when the auction ends",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get the portfolio of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "useruuid",
+                        "name": "useruuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponsePortfolio"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/version": {
+            "get": {
+                "description": "Get the version and build time of the running binary",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Build information",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ResponseBuildInfo"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.APIKeyCreateRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "Role of the client in the access policy, defaults to bidder",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "ttl": {
+                    "description": "TTL is the lifetime of the key in seconds, 0 means it never expires",
+                    "type": "integer"
+                },
+                "useruuid": {
+                    "description": "UserUUID optionally binds the key to a user",
+                    "type": "string"
+                }
+            }
+        },
+        "api.APIKeyRotateRequest": {
+            "type": "object",
+            "properties": {
+                "grace": {
+                    "description": "Grace is the number of seconds the old secret stays valid",
+                    "type": "integer"
+                },
+                "ttl": {
+                    "description": "TTL resets the lifetime of the key in seconds, 0 keeps the current expiry",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.BuildInfo": {
+            "type": "object",
+            "properties": {
+                "buildtime": {
+                    "type": "string"
+                },
+                "goversion": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.GraphQLRequest": {
+            "type": "object",
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "api.GraphQLResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "object"
+                    }
+                }
+            }
+        },
+        "api.Readiness": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "ready": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "api.Response": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "description": "RequestID is the X-Request-ID of the request",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseBid": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/bidtracker.Bid"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseBidBatch": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/bidtracker.BidBatchResult"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseBuildInfo": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/api.BuildInfo"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseGetBids": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bidtracker.Bid"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "nextCursor": {
+                    "description": "NextCursor is passed as the cursor query parameter to fetch the next page",
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseImportReport": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/bidtracker.ImportReport"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseItem": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/bidtracker.Item"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseItemAnalytics": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/bidtracker.ItemAnalytics"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseItemSearch": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/bidtracker.ItemSearchResult"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseItems": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bidtracker.Item"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseLeaderboard": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bidtracker.LeaderboardEntry"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponsePortfolio": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bidtracker.PortfolioEntry"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseRank": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/bidtracker.LeaderboardEntry"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseReadiness": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/api.Readiness"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestID": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ResponseSystemAnalytics": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/bidtracker.SystemAnalytics"
                 },
                 "message": {
                     "type": "string"
                 },
+                "requestID": {
+                    "type": "string"
+                },
                 "status": {
                     "type": "integer"
                 }
@@ -270,6 +2080,426 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "bidtracker.BidBatchResult": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bidtracker.BidResult"
+                    }
+                }
+            }
+        },
+        "bidtracker.BidResult": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "boolean"
+                },
+                "bid": {
+                    "$ref": "#/definitions/bidtracker.Bid"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "leader": {
+                    "description": "Leader is set if the bid became the current winning bid of its item",
+                    "type": "boolean"
+                },
+                "reason": {
+                    "$ref": "#/definitions/bidtracker.RejectReason"
+                }
+            }
+        },
+        "bidtracker.HistogramBucket": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "max": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                }
+            }
+        },
+        "bidtracker.ImportError": {
+            "type": "object",
+            "properties": {
+                "line": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "bidtracker.ImportReport": {
+            "type": "object",
+            "properties": {
+                "dryrun": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "description": "Errors lists the first MaxImportErrors failed rows",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bidtracker.ImportError"
+                    }
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "integer"
+                }
+            }
+        },
+        "bidtracker.Item": {
+            "type": "object",
+            "properties": {
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "endtime": {
+                    "description": "EndTime is the unix timestamp at which the auction closes, 0 if it never does",
+                    "type": "integer"
+                },
+                "images": {
+                    "description": "Images are absolute http(s) URLs or references to an image store",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "itemuuid": {
+                    "type": "string"
+                },
+                "selleruuid": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "bidtracker.ItemAnalytics": {
+            "type": "object",
+            "properties": {
+                "bidcount": {
+                    "type": "integer"
+                },
+                "bidsperminute": {
+                    "type": "number"
+                },
+                "firstbid": {
+                    "type": "integer"
+                },
+                "histogram": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bidtracker.HistogramBucket"
+                    }
+                },
+                "itemuuid": {
+                    "type": "string"
+                },
+                "lastbid": {
+                    "type": "integer"
+                },
+                "priceseries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bidtracker.PricePoint"
+                    }
+                },
+                "uniquebidders": {
+                    "type": "integer"
+                }
+            }
+        },
+        "bidtracker.ItemFacets": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "priceranges": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bidtracker.PriceRangeFacet"
+                    }
+                },
+                "status": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "bidtracker.ItemSearchResult": {
+            "type": "object",
+            "properties": {
+                "facets": {
+                    "$ref": "#/definitions/bidtracker.ItemFacets"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bidtracker.ItemSummary"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "bidtracker.ItemStatus": {
+            "type": "string",
+            "enum": [
+                "open",
+                "closing_soon",
+                "closed"
+            ],
+            "x-enum-varnames": [
+                "ItemOpen",
+                "ItemClosingSoon",
+                "ItemClosed"
+            ]
+        },
+        "bidtracker.ItemSummary": {
+            "type": "object",
+            "properties": {
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "bidcount": {
+                    "type": "integer"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "currentprice": {
+                    "type": "number"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "endtime": {
+                    "description": "EndTime is the unix timestamp at which the auction closes, 0 if it never does",
+                    "type": "integer"
+                },
+                "images": {
+                    "description": "Images are absolute http(s) URLs or references to an image store",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "itemuuid": {
+                    "type": "string"
+                },
+                "selleruuid": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/bidtracker.ItemStatus"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "bidtracker.LeaderboardEntry": {
+            "type": "object",
+            "properties": {
+                "bestbid": {
+                    "$ref": "#/definitions/bidtracker.Bid"
+                },
+                "rank": {
+                    "type": "integer"
+                },
+                "useruuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "bidtracker.PortfolioEntry": {
+            "type": "object",
+            "properties": {
+                "bidcount": {
+                    "type": "integer"
+                },
+                "endtime": {
+                    "description": "EndTime is the unix timestamp at which the auction closes, 0 if it never does",
+                    "type": "integer"
+                },
+                "highestbid": {
+                    "description": "HighestBid is the best bid of the user on this item",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/bidtracker.Bid"
+                        }
+                    ]
+                },
+                "itemuuid": {
+                    "type": "string"
+                },
+                "leadingamount": {
+                    "description": "LeadingAmount is the current highest bid on this item, by anyone",
+                    "type": "number"
+                },
+                "status": {
+                    "$ref": "#/definitions/bidtracker.PortfolioStatus"
+                }
+            }
+        },
+        "bidtracker.PortfolioStatus": {
+            "type": "string",
+            "enum": [
+                "winning",
+                "outbid",
+                "won",
+                "lost"
+            ],
+            "x-enum-varnames": [
+                "StatusWinning",
+                "StatusOutbid",
+                "StatusWon",
+                "StatusLost"
+            ]
+        },
+        "bidtracker.PricePoint": {
+            "type": "object",
+            "properties": {
+                "close": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "high": {
+                    "type": "number"
+                },
+                "low": {
+                    "type": "number"
+                },
+                "open": {
+                    "type": "number"
+                },
+                "start": {
+                    "type": "integer"
+                }
+            }
+        },
+        "bidtracker.PriceRangeFacet": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "max": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                }
+            }
+        },
+        "bidtracker.RejectReason": {
+            "type": "string",
+            "enum": [
+                "unknown_item",
+                "auction_closed",
+                "bid_too_low",
+                "shill_bid",
+                "batch_aborted"
+            ],
+            "x-enum-varnames": [
+                "RejectUnknownItem",
+                "RejectAuctionClosed",
+                "RejectBidTooLow",
+                "RejectShillBid",
+                "RejectBatchAborted"
+            ]
+        },
+        "bidtracker.SystemAnalytics": {
+            "type": "object",
+            "properties": {
+                "bidcount": {
+                    "type": "integer"
+                },
+                "bidsperminute": {
+                    "type": "number"
+                },
+                "firstbid": {
+                    "type": "integer"
+                },
+                "histogram": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bidtracker.HistogramBucket"
+                    }
+                },
+                "itemcount": {
+                    "type": "integer"
+                },
+                "itemswithbids": {
+                    "type": "integer"
+                },
+                "lastbid": {
+                    "type": "integer"
+                },
+                "leadingvolume": {
+                    "description": "LeadingVolume sums the current winning amount of every item",
+                    "type": "number"
+                },
+                "uniquebidders": {
+                    "type": "integer"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1 @@
+cd pkg/rpc/bidtrackerpb && protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative bidtracker.proto
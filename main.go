@@ -1,17 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	_ "github.com/ansrivas/bid-tracker/docs" // docs is generated by Swag CLI, you have to import it.
 	app "github.com/ansrivas/bid-tracker/pkg/api"
-	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/ansrivas/bid-tracker/pkg/cli"
+	"github.com/ansrivas/bid-tracker/pkg/config"
+	"github.com/ansrivas/bid-tracker/pkg/rpc"
+	"github.com/ansrivas/bid-tracker/pkg/tracing"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/recover"
-	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
 	"github.com/gofiber/swagger"
@@ -35,16 +43,68 @@ var (
 // @license.url http://www.apache.org/licenses/LICENSE-2.0.html
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
+	// Without a command, or with flags only, the server is started as before commands existed
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch {
+		case args[0] == "serve":
+			args = args[1:]
+		case args[0] == "help":
+			fmt.Fprintln(os.Stderr, "Usage: bid-tracker [serve] [flags], runs the server, see bid-tracker serve -h")
+			cli.Usage(os.Stderr)
+			return
+		case cli.IsCommand(args[0]):
+			err := cli.Run(args, cli.Env{Getenv: os.Getenv, Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr})
+			if err != nil && !errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	// serve returns instead of exiting, so that its deferred calls, e.g. flushing the spans, run first
+	if err := serve(args); err != nil {
+		log.Error().Msg(err.Error())
+		os.Exit(1)
+	}
+}
+
+// serve runs the server until it receives SIGINT or SIGTERM
+func serve(args []string) error {
 	fmt.Printf("Current version is: %s and buildtime is: %s\n", Version, BuildTime)
 
-	biddableItems := []uuid.UUID{
-		uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a")),
-		uuid.Must(uuid.FromString("b16ab43e-aa13-4079-b8c5-592e81312c01")),
+	cfg, err := config.Load(args, os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to load the configuration %w", err)
+	}
+
+	// Rejected bids are logged at debug level
+	logLevel, _ := zerolog.ParseLevel(cfg.LogLevel)
+	zerolog.SetGlobalLevel(logLevel)
+
+	health := app.NewHealth()
+	for name, check := range cfg.ReadinessChecks() {
+		health.AddCheck(name, check)
 	}
-	bidTracker := bidtracker.NewBidManagement(biddableItems...)
 
-	server := fiber.New()
+	bidTracker, err := cfg.NewTracker()
+	if err != nil {
+		return fmt.Errorf("Failed to create the bid tracker %w", err)
+	}
+	if err := cfg.Restore(bidTracker); err != nil {
+		return fmt.Errorf("Failed to restore the state %w", err)
+	}
+
+	// Bulk imports are read as they are received instead of being buffered whole, the other routes
+	// still reject bodies over the default body limit
+	server := fiber.New(fiber.Config{StreamRequestBody: true})
 
 	server.Get("/swagger/*", swagger.HandlerDefault) // default
 
@@ -56,35 +116,124 @@ func main() {
 	// Enable pprof end point
 	// server.Use(pprof.New())
 
-	// Logging middleware - also commented out for now
-	// server.Use(logger.New(logger.Config{
-	// 	// Optional
-	// 	Format: "${time} ${method} ${path} - ${ip} - ${status} - ${latency}\n",
-	// }))
-
 	server.Use(recover.New())
 
+	// The grpc service shares the metrics, rate limits, api keys and policy of the http api
+	metrics := app.NewMetrics()
+	rateLimiters := app.NewRateLimiters(cfg.Limits)
+	rpcOptions := []rpc.Option{rpc.WithMetrics(metrics), rpc.WithRateLimiters(rateLimiters)}
+	routeOptions := []app.RegisterRoutesOption{
+		app.RegisterWithAPIVersion(cfg.APIVersion),
+		app.RegisterWithAPIProxyPrefix(cfg.ProxyPrefix),
+		app.RegisterWithRateLimiters(rateLimiters),
+		app.RegisterWithMetrics(metrics),
+		app.RegisterWithAccessLog(log.Logger),
+		app.RegisterWithHealth(health),
+		app.RegisterWithBuildInfo(app.NewBuildInfo(Version, BuildTime)),
+	}
+
+	// Api keys are only enforced once an admin key has been handed over to bootstrap the store
+	apiKeys, err := cfg.NewKeyStore()
+	if err != nil {
+		return fmt.Errorf("Failed to create the api key store %w", err)
+	}
+	if apiKeys != nil {
+		routeOptions = append(routeOptions, app.RegisterWithAPIKeys(apiKeys))
+		if keysFile := cfg.KeysFile(); keysFile != "" {
+			// Issued, rotated and revoked keys are saved right away so that a crash does not undo them
+			routeOptions = append(routeOptions, app.RegisterWithAPIKeysFile(keysFile))
+		}
+		rpcOptions = append(rpcOptions, rpc.WithAPIKeys(apiKeys))
+	}
+
+	if cfg.APIVersion2 != "" {
+		routeOptions = append(routeOptions, app.RegisterWithAPIVersion2(cfg.APIVersion2))
+	}
+
+	if cfg.ProblemDetails {
+		routeOptions = append(routeOptions, app.RegisterWithProblemDetails())
+	}
+
+	if policyFile := cfg.Auth.PolicyFile; policyFile != "" {
+		policy, err := app.LoadPolicyFile(policyFile)
+		if err != nil {
+			return fmt.Errorf("Failed to load the access policy %w", err)
+		}
+		routeOptions = append(routeOptions, app.RegisterWithPolicy(policy))
+		rpcOptions = append(rpcOptions, rpc.WithPolicy(policy))
+	}
+
+	// The OTEL_EXPORTER_OTLP_* variables configure the otlp exporter further
+	spanExporter, err := tracing.NewExporter(context.Background(), cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("Failed to configure tracing %w", err)
+	}
+	if spanExporter != nil {
+		tracerProvider := tracing.NewTracerProvider(spanExporter, cfg.Tracing)
+		// Deferred calls run after the shutdown below, flushing the spans of the drained requests,
+		// and on every failure from here on since serve returns rather than exits
+		defer tracerProvider.Shutdown(context.Background())
+		routeOptions = append(routeOptions, app.RegisterWithTracing(tracerProvider))
+	}
+
 	api := app.NewAPIWithSettings(bidTracker, server)
-	err := app.RegisterRoutes(api,
-		app.RegisterWithAPIVersion("/api/v1"),
-	)
+	err = app.RegisterRoutes(api, routeOptions...)
 	if err != nil {
-		log.Error().Msgf("Failed to register routes %s", err.Error())
-		os.Exit(1)
+		return fmt.Errorf("Failed to register routes %w", err)
 	}
 
-	// Register a ctrl-c handler
-	errc := make(chan error)
+	serveErr := make(chan error, 2)
 	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-		errc <- fmt.Errorf("%s", <-c)
+		serveErr <- api.FiberApp().Listen(cfg.Listen)
 	}()
 
-	go func() {
-		errc <- api.FiberApp().Listen(":3000")
-	}()
+	if cfg.GRPCListen != "" {
+		grpcServer, err := rpc.NewServer(bidTracker, rpcOptions...)
+		if err != nil {
+			return fmt.Errorf("Failed to create the grpc server %w", err)
+		}
+		lis, err := net.Listen("tcp", cfg.GRPCListen)
+		if err != nil {
+			return fmt.Errorf("Failed to listen for grpc %w", err)
+		}
+		// Open watches are ended and the grpc server drained along with the http server
+		api.OnShutdown(grpcServer.Shutdown)
+		go func() {
+			serveErr <- grpcServer.Serve(lis)
+		}()
+	}
 
-	log.Info().Msgf("Exiting server. Message: %v", <-errc)
+	// Readiness only passes once the state and the api keys are restored and the servers are started
+	health.SetRecovered()
+
+	// Failures from here on are returned once everything is shut down, the process still exits with an error
+	var failures []error
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case sig := <-signals:
+		log.Info().Msgf("Received %s, shutting down", sig)
+	case err := <-serveErr:
+		log.Error().Msgf("Server stopped, shutting down %v", err)
+		failures = append(failures, fmt.Errorf("Server stopped %w", err))
+	}
+
+	// Fail readiness first so that load balancers stop routing new requests,
+	// then stop accepting connections and let in-flight requests finish before the state is persisted
+	health.SetDraining()
+	time.Sleep(cfg.ShutdownDelay)
 
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := api.Shutdown(ctx); err != nil {
+		failures = append(failures, fmt.Errorf("Failed to shut down gracefully %w", err))
+	}
+	if err := cfg.Persist(bidTracker); err != nil {
+		failures = append(failures, fmt.Errorf("Failed to persist the state %w", err))
+	}
+	if err := cfg.PersistKeys(apiKeys); err != nil {
+		failures = append(failures, fmt.Errorf("Failed to persist the api keys %w", err))
+	}
+	log.Info().Msg("Exiting server")
+	return errors.Join(failures...)
 }
@@ -0,0 +1,111 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// RequestIDHeader carries the id of a request, it is generated when the client does not send one
+	RequestIDHeader = fiber.HeaderXRequestID
+
+	// maxRequestIDLength bounds the request ids accepted from clients
+	maxRequestIDLength = 128
+
+	localsRequestID    = "requestid"
+	localsErrorMessage = "errormessage"
+)
+
+// validRequestID keeps ids sent by clients to printable ascii without spaces, so they are safe to log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDOf returns the id of the request, empty if the requestID middleware did not run
+func requestIDOf(c *fiber.Ctx) string {
+	id, _ := c.Locals(localsRequestID).(string)
+	return id
+}
+
+// requestID propagates the X-Request-ID of the client or generates one, and echoes it in the response.
+// The logger stored in the user context carries the id, handlers log through zerolog.Ctx.
+func requestID(logger zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.Must(uuid.NewV4()).String()
+		}
+		c.Locals(localsRequestID, id)
+		c.Set(RequestIDHeader, id)
+
+		requestLogger := logger.With().Str("request_id", id).Logger()
+		c.SetUserContext(requestLogger.WithContext(c.UserContext()))
+		return c.Next()
+	}
+}
+
+// accessLog writes one line per request, at error level for server errors
+func accessLog(c *fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+	code := responseStatus(c, err)
+
+	logger := zerolog.Ctx(c.UserContext())
+	event := logger.Info()
+	if code >= fiber.StatusInternalServerError {
+		event = logger.Error()
+	}
+
+	event = event.
+		Str("method", c.Method()).
+		Str("path", c.Path()).
+		Str("route", c.Route().Path).
+		Int("status", code).
+		Dur("latency", time.Since(start)).
+		Int("bytes", len(c.Response().Body())).
+		Str("ip", c.IP())
+	if key, ok := c.Locals(localsAPIKey).(apikey.Key); ok {
+		event = event.Str("apikey", key.ID)
+	}
+	if message, ok := c.Locals(localsErrorMessage).(string); ok {
+		event = event.Str("error", message)
+	}
+	if err != nil {
+		event = event.Err(err)
+	}
+	event.Msg("access")
+	return err
+}
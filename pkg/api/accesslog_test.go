@@ -0,0 +1,90 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLogAndRequestID(t *testing.T) {
+	assert := assert.New(t)
+
+	var logs bytes.Buffer
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New())
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1"), RegisterWithAccessLog(zerolog.New(&logs).Level(zerolog.DebugLevel))))
+
+	// WHEN a bid on an unknown item comes with a request id
+	body := `{"itemuuid":"cef31b6b-cdeb-4035-8d42-a4f33b2d02fe","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":10}`
+	req := httptest.NewRequest("POST", "/api/v1/bids", bytes.NewBufferString(body))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add(RequestIDHeader, "checkout-42")
+	resp, _ := api.server.Test(req)
+
+	// THEN the id is echoed in the header and the envelope
+	assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+	assert.Equal("checkout-42", resp.Header.Get(RequestIDHeader))
+	response := new(Response)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(response))
+	assert.Equal("checkout-42", response.RequestID)
+
+	// THEN the rejection and the access are logged with the id
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if !assert.Len(lines, 2) {
+		return
+	}
+	var rejection, access map[string]interface{}
+	assert.Nil(json.Unmarshal([]byte(lines[0]), &rejection))
+	assert.Nil(json.Unmarshal([]byte(lines[1]), &access))
+	assert.Equal("debug", rejection["level"])
+	assert.Equal("unknown_item", rejection["reason"])
+	assert.Equal("checkout-42", rejection["request_id"])
+	assert.Equal("info", access["level"])
+	assert.Equal("checkout-42", access["request_id"])
+	assert.Equal("/api/v1/bids", access["route"])
+	assert.Equal(float64(fiber.StatusNotFound), access["status"])
+	assert.Contains(access["error"], "Failed to insert the bid")
+
+	// WHEN the client sends no usable id, one is generated
+	req = httptest.NewRequest("GET", "/api/v1/bids/"+itemUUID.String(), nil)
+	req.Header.Add(RequestIDHeader, "has spaces")
+	resp, _ = api.server.Test(req)
+	generated := resp.Header.Get(RequestIDHeader)
+	assert.NotEqual("has spaces", generated)
+	_, err := uuid.FromString(generated)
+	assert.Nil(err)
+
+	// THEN pages of bids carry it as well
+	page := new(ResponseGetBids)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(page))
+	assert.Equal(generated, page.RequestID)
+}
@@ -22,14 +22,38 @@
 package api
 
 import (
+	"context"
+	"sync"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
 	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
 	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/pkg/errors"
 )
 
 // API is the base struct for this implementation
 type API struct {
 	itemsBid *bidtracker.BidManagement
 	server   *fiber.App
+
+	// apiKeys is nil unless api key authentication was enabled in RegisterRoutes
+	apiKeys *apikey.Store
+	// apiKeysFile is where the api keys are saved after every change, they are only kept in memory if empty
+	apiKeysFile string
+	policy      *Policy
+
+	// health is nil unless the probes were registered in RegisterRoutes
+	health *Health
+
+	// metrics is nil unless the routes are instrumented
+	metrics *Metrics
+
+	// graphQL is the schema served on URLGraphQL, built in RegisterRoutes
+	graphQL *graphql.Schema
+
+	shutdownMu    sync.Mutex
+	shutdownHooks []func(ctx context.Context) error
 }
 
 // NewAPI returns the pointer to a new api instance
@@ -50,3 +74,39 @@ func NewAPIWithSettings(itemsBid *bidtracker.BidManagement, app *fiber.App) *API
 func (api *API) FiberApp() *fiber.App {
 	return api.server
 }
+
+// OnShutdown registers hook to run once in-flight requests are drained, e.g. to close streams with a final message
+func (api *API) OnShutdown(hook func(ctx context.Context) error) {
+	api.shutdownMu.Lock()
+	defer api.shutdownMu.Unlock()
+
+	api.shutdownHooks = append(api.shutdownHooks, hook)
+}
+
+// Shutdown marks the server as draining, ends the subscriptions, stops accepting connections and waits for
+// in-flight requests until ctx is done, then runs the shutdown hooks in reverse order of registration, even if the wait timed out.
+func (api *API) Shutdown(ctx context.Context) error {
+	if api.health != nil {
+		api.health.SetDraining()
+	}
+	// Streams never finish on their own, they would hold the shutdown until ctx is done
+	if api.itemsBid != nil {
+		api.itemsBid.CloseWatches()
+	}
+
+	err := api.server.ShutdownWithContext(ctx)
+	if err != nil {
+		err = errors.WithMessage(err, "Failed to drain the in-flight requests")
+	}
+
+	api.shutdownMu.Lock()
+	hooks := api.shutdownHooks
+	api.shutdownMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if hookErr := hooks[i](ctx); hookErr != nil && err == nil {
+			err = errors.WithMessage(hookErr, "Failed to run a shutdown hook")
+		}
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// APIKeyHeader is the header machine clients use to send their api key.
+// A bearer token in the Authorization header is accepted as well.
+const APIKeyHeader = "X-API-Key"
+
+// localsAPIKey is the fiber.Ctx locals key holding the authenticated apikey.Key
+const localsAPIKey = "apikey"
+
+func apiKeyFromRequest(c *fiber.Ctx) string {
+	if token := c.Get(APIKeyHeader); token != "" {
+		return token
+	}
+	if auth := c.Get(fiber.HeaderAuthorization); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
+}
+
+// sendForbidden is the single 403 response returned whenever a request is denied by the access policy
+func sendForbidden(c *fiber.Ctx, permission Permission) error {
+	msg := fmt.Sprintf("Permission denied, %s is required", permission)
+	return SendJSON(c, fiber.StatusForbidden, msg, EmptyResponse)
+}
+
+// authorize returns a middleware which only lets requests through carrying an api key
+// whose role is granted the permission by the policy, and whose scopes cover it.
+// Every request is allowed if no api key store has been registered.
+func (api *API) authorize(permission Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if api.apiKeys == nil {
+			return c.Next()
+		}
+
+		token := apiKeyFromRequest(c)
+		if token == "" {
+			return SendJSON(c, fiber.StatusUnauthorized, "Missing api key", EmptyResponse)
+		}
+
+		key, err := api.apiKeys.Authenticate(token)
+		if err != nil {
+			msg := errors.WithMessage(err, "Failed to authenticate").Error()
+			return SendJSON(c, fiber.StatusUnauthorized, msg, EmptyResponse)
+		}
+
+		if !api.policy.Permits(key, permission) {
+			return sendForbidden(c, permission)
+		}
+
+		c.Locals(localsAPIKey, key)
+		return c.Next()
+	}
+}
+
+// actsFor reports whether the caller may act for the given user, see Policy.ActsFor.
+// Everything is allowed if no api key store has been registered.
+func (api *API) actsFor(c *fiber.Ctx, useruuid uuid.UUID) bool {
+	key, ok := c.Locals(localsAPIKey).(apikey.Key)
+	if !ok {
+		return api.apiKeys == nil
+	}
+	return api.policy.ActsFor(key, useruuid)
+}
+
+// moderates reports whether the caller may moderate items, e.g. change the end of an auction that has bids.
+// Everything is allowed if no api key store has been registered.
+func (api *API) moderates(c *fiber.Ctx) bool {
+	key, ok := c.Locals(localsAPIKey).(apikey.Key)
+	if !ok {
+		return api.apiKeys == nil
+	}
+	return api.policy.Allows(Role(key.Role), PermissionItemsModerate)
+}
+
+// bidsFor reports whether the caller may place bids for the given user, see Policy.BidsFor.
+// Everything is allowed if no api key store has been registered.
+func (api *API) bidsFor(c *fiber.Ctx, useruuid uuid.UUID) bool {
+	key, ok := c.Locals(localsAPIKey).(apikey.Key)
+	if !ok {
+		return api.apiKeys == nil
+	}
+	return api.policy.BidsFor(key, useruuid)
+}
+
+// callerUUID returns the user the api key of the caller is bound to, if any
+func callerUUID(c *fiber.Ctx) uuid.UUID {
+	if key, ok := c.Locals(localsAPIKey).(apikey.Key); ok {
+		return key.UserUUID
+	}
+	return uuid.Nil
+}
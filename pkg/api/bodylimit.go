@@ -0,0 +1,51 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// limitBody rejects the requests with a body over the BodyLimit of the app. A server streaming request bodies
+// does not enforce it, the body is then read up to the limit so that the handlers parse it as usual.
+func limitBody(c *fiber.Ctx) error {
+	limit := c.App().Config().BodyLimit
+	if c.Request().Header.ContentLength() > limit {
+		c.Context().SetConnectionClose()
+		return SendJSON(c, fiber.StatusRequestEntityTooLarge, "Request body too large", EmptyResponse)
+	}
+
+	if stream := c.Context().RequestBodyStream(); stream != nil {
+		body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+		if err != nil {
+			return SendJSON(c, fiber.StatusBadRequest, "Failed to read the request body", EmptyResponse)
+		}
+		if len(body) > limit {
+			c.Context().SetConnectionClose()
+			return SendJSON(c, fiber.StatusRequestEntityTooLarge, "Request body too large", EmptyResponse)
+		}
+		c.Request().SetBody(body)
+	}
+	return c.Next()
+}
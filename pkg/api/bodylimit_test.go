@@ -0,0 +1,81 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitBody(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New(fiber.Config{StreamRequestBody: true, BodyLimit: 256, DisableStartupMessage: true}))
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1")))
+
+	bid := `{"itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":10}`
+	post := func(path string, body io.Reader) int {
+		req := httptest.NewRequest("POST", path, body)
+		req.Header.Add(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := api.server.Test(req)
+		if !assert.Nil(err) {
+			return 0
+		}
+		return resp.StatusCode
+	}
+
+	// WHEN the body is within the limit, it is parsed as usual
+	assert.Equal(fiber.StatusOK, post("/api/v1/bids", strings.NewReader(bid)))
+
+	// WHEN its length is over the limit, it is rejected before being read
+	large := bid + strings.Repeat(" ", 256)
+	assert.Equal(fiber.StatusRequestEntityTooLarge, post("/api/v1/bids", strings.NewReader(large)))
+	assert.Equal(fiber.StatusRequestEntityTooLarge, post("/api/v1/graphql", strings.NewReader(large)))
+
+	// WHEN the body is chunked, it is read up to the limit
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+	go api.server.Listener(listener)
+	defer api.server.Shutdown()
+
+	chunked := func(body string) int {
+		// the length of a MultiReader is unknown, the body is sent in chunks
+		resp, err := http.Post("http://"+listener.Addr().String()+"/api/v1/bids", fiber.MIMEApplicationJSON,
+			io.MultiReader(strings.NewReader(body)))
+		if !assert.Nil(err) {
+			return 0
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(fiber.StatusOK, chunked(strings.Replace(bid, "10", "20", 1)))
+	assert.Equal(fiber.StatusRequestEntityTooLarge, chunked(large))
+}
@@ -0,0 +1,91 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// errorKind is a class of errors answered with the same status and problem code, or grpc code
+type errorKind struct {
+	err      error
+	status   int
+	code     string
+	grpcCode codes.Code
+}
+
+// errorKinds maps the errors of the tracker and the key store to their response. Every handler,
+// of the grpc api too, goes through it, so the same error always gets the same status and code.
+var errorKinds = []errorKind{
+	{bidtracker.ErrInvalidArgument, fiber.StatusBadRequest, "invalid_argument", codes.InvalidArgument},
+	{bidtracker.ErrItemNotFound, fiber.StatusNotFound, "item_not_found", codes.NotFound},
+	{bidtracker.ErrUserNotFound, fiber.StatusNotFound, "user_not_found", codes.NotFound},
+	{bidtracker.ErrNoBids, fiber.StatusNotFound, "no_bids", codes.NotFound},
+	{apikey.ErrKeyNotFound, fiber.StatusNotFound, "api_key_not_found", codes.NotFound},
+	{bidtracker.ErrItemExists, fiber.StatusConflict, "item_exists", codes.AlreadyExists},
+	{apikey.ErrRevokedKey, fiber.StatusConflict, "api_key_revoked", codes.FailedPrecondition},
+	{bidtracker.ErrAuctionClosed, fiber.StatusUnprocessableEntity, "auction_closed", codes.FailedPrecondition},
+	{bidtracker.ErrBidTooLow, fiber.StatusUnprocessableEntity, "bid_too_low", codes.FailedPrecondition},
+	{bidtracker.ErrAuctionEndLocked, fiber.StatusUnprocessableEntity, "auction_end_locked", codes.FailedPrecondition},
+	{bidtracker.ErrShillBid, fiber.StatusUnprocessableEntity, "shill_bid", codes.PermissionDenied},
+	{bidtracker.ErrBatchAborted, fiber.StatusUnprocessableEntity, "batch_aborted", codes.FailedPrecondition},
+}
+
+// errorKindOf returns the kind of err, errors of no known kind are internal errors
+func errorKindOf(err error) errorKind {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind
+		}
+	}
+	return errorKind{status: fiber.StatusInternalServerError, code: problemCode(fiber.StatusInternalServerError), grpcCode: codes.Internal}
+}
+
+// title is the short summary of the kind shared by all its errors
+func (kind errorKind) title() string {
+	if kind.err == nil {
+		return http.StatusText(kind.status)
+	}
+	return kind.err.Error()
+}
+
+// errorStatus maps an error of the tracker or the key store to the status code of its response
+func errorStatus(err error) int {
+	return errorKindOf(err).status
+}
+
+// GRPCCode maps an error of the tracker or the key store to the code of its grpc status
+func GRPCCode(err error) codes.Code {
+	return errorKindOf(err).grpcCode
+}
+
+// problemCode is the problem code of the errors known by their status only, e.g. too_many_requests
+func problemCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
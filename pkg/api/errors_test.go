@@ -0,0 +1,84 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestErrorStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		err      error
+		want     int
+		wantGRPC codes.Code
+	}{
+		{bidtracker.ErrInvalidArgument, fiber.StatusBadRequest, codes.InvalidArgument},
+		{fmt.Errorf("Failed to fetch the item. %w", bidtracker.ErrItemNotFound), fiber.StatusNotFound, codes.NotFound},
+		{bidtracker.ErrUserNotFound, fiber.StatusNotFound, codes.NotFound},
+		{bidtracker.ErrNoBids, fiber.StatusNotFound, codes.NotFound},
+		{apikey.ErrKeyNotFound, fiber.StatusNotFound, codes.NotFound},
+		{bidtracker.ErrItemExists, fiber.StatusConflict, codes.AlreadyExists},
+		{apikey.ErrRevokedKey, fiber.StatusConflict, codes.FailedPrecondition},
+		{&bidtracker.BidRejectedError{Reason: bidtracker.RejectAuctionClosed, Err: bidtracker.ErrAuctionClosed}, fiber.StatusUnprocessableEntity, codes.FailedPrecondition},
+		{&bidtracker.BidRejectedError{Reason: bidtracker.RejectBidTooLow, Err: bidtracker.ErrBidTooLow}, fiber.StatusUnprocessableEntity, codes.FailedPrecondition},
+		{fmt.Errorf("Failed to update the item: %w", bidtracker.ErrAuctionEndLocked), fiber.StatusUnprocessableEntity, codes.FailedPrecondition},
+		{bidtracker.ErrShillBid, fiber.StatusUnprocessableEntity, codes.PermissionDenied},
+		{bidtracker.ErrBatchAborted, fiber.StatusUnprocessableEntity, codes.FailedPrecondition},
+		{fmt.Errorf("disk full"), fiber.StatusInternalServerError, codes.Internal},
+	}
+	for _, test := range tests {
+		assert.Equal(test.want, errorStatus(test.err), test.err.Error())
+		assert.Equal(test.wantGRPC, GRPCCode(test.err), test.err.Error())
+	}
+}
+
+func TestHandlerErrorStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New())
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1")))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/v1/users/ae8f7716-867b-4479-b455-c5769e7475ba/bids", fiber.StatusNotFound},
+		{"/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a/winning", fiber.StatusNotFound},
+		{"/api/v1/bids/cef31b6b-cdeb-4035-8d42-a4f33b2d02fe/leaderboard", fiber.StatusNotFound},
+		{"/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a?cursor=bogus", fiber.StatusBadRequest},
+	}
+	for _, test := range tests {
+		resp, _ := api.server.Test(httptest.NewRequest("GET", test.path, nil))
+		assert.Equal(test.want, resp.StatusCode, test.path)
+	}
+}
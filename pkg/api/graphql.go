@@ -0,0 +1,599 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofrs/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/pkg/errors"
+)
+
+// graphQLCallerKey is the context key of the apikey.Key of the caller, seen by the resolvers
+type graphQLCallerKey struct{}
+
+// graphQLCaller returns the api key of the caller, the zero key if api keys are not enabled
+func graphQLCaller(ctx context.Context) apikey.Key {
+	key, _ := ctx.Value(graphQLCallerKey{}).(apikey.Key)
+	return key
+}
+
+// graphQLAuthorize checks the permission for a field the way authorize does for a route,
+// since a single GraphQL request can read and write through several fields
+func (api *API) graphQLAuthorize(ctx context.Context, permission Permission) error {
+	if api.apiKeys == nil {
+		return nil
+	}
+	if !api.policy.Permits(graphQLCaller(ctx), permission) {
+		return fmt.Errorf("Permission denied, %s is required", permission)
+	}
+	return nil
+}
+
+// graphQLUUID parses the uuid argument name, uuid.Nil is returned if it is absent
+func graphQLUUID(args map[string]interface{}, name string) (uuid.UUID, error) {
+	value, _ := args[name].(string)
+	if value == "" {
+		return uuid.Nil, nil
+	}
+	id, err := uuid.FromString(value)
+	if err != nil {
+		return uuid.Nil, errors.WithMessagef(err, "%s can not be parsed successfully", name)
+	}
+	return id, nil
+}
+
+// graphQLBidQuery reads the limit and cursor arguments of a paginated field
+func graphQLBidQuery(args map[string]interface{}) (bidtracker.BidQuery, error) {
+	query := bidtracker.BidQuery{}
+	query.Limit, _ = args["limit"].(int)
+	query.Cursor, _ = args["cursor"].(string)
+	return query, query.Validate()
+}
+
+// graphQLResult drops the value returned along with an error, which would be sent as data otherwise
+func graphQLResult(value interface{}, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// nonNilStrings turns nil into an empty list, lists of items are never null
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// optionalUUID is null for uuid.Nil, e.g. the seller of items created without metadata
+func optionalUUID(id uuid.UUID) interface{} {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id.String()
+}
+
+// graphQLSchema builds the schema served on URLGraphQL. Ids are the uuids of the tracker and
+// timestamps are unix seconds, like in the REST api.
+func (api *API) graphQLSchema() (graphql.Schema, error) {
+	bidType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Bid",
+		Fields: graphql.Fields{
+			"itemUuid": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.Bid).ItemUUID.String(), nil
+				},
+			},
+			"userUuid": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.Bid).UserUUID.String(), nil
+				},
+			},
+			"timestamp": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.Bid).Timestamp, nil
+				},
+			},
+			"amount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.Bid).Amount, nil
+				},
+			},
+		},
+	})
+
+	bidPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BidPage",
+		Fields: graphql.Fields{
+			"bids": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bidType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.BidPage).Bids, nil
+				},
+			},
+			"nextCursor": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Passed as the cursor argument to fetch the next page, null on the last page",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if cursor := p.Source.(bidtracker.BidPage).NextCursor; cursor != "" {
+						return cursor, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	pageArgs := graphql.FieldConfigArgument{
+		"limit":  &graphql.ArgumentConfig{Type: graphql.Int, Description: fmt.Sprintf("page size, %d by default", bidtracker.DefaultBidPageLimit)},
+		"cursor": &graphql.ArgumentConfig{Type: graphql.String},
+	}
+
+	leaderboardEntryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LeaderboardEntry",
+		Fields: graphql.Fields{
+			"rank": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.LeaderboardEntry).Rank, nil
+				},
+			},
+			"userUuid": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.LeaderboardEntry).UserUUID.String(), nil
+				},
+			},
+			"bestBid": &graphql.Field{
+				Type: graphql.NewNonNull(bidType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.LeaderboardEntry).BestBid, nil
+				},
+			},
+		},
+	})
+
+	attributeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Attribute",
+		Fields: graphql.Fields{
+			"key":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"value": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	itemType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.Fields{
+			"itemUuid": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.Item).UUID.String(), nil
+				},
+			},
+			"title": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.Item).Title, nil
+				},
+			},
+			"description": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.Item).Description, nil
+				},
+			},
+			"categories": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return nonNilStrings(p.Source.(bidtracker.Item).Categories), nil
+				},
+			},
+			"sellerUuid": &graphql.Field{
+				Type: graphql.ID,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return optionalUUID(p.Source.(bidtracker.Item).SellerUUID), nil
+				},
+			},
+			"images": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return nonNilStrings(p.Source.(bidtracker.Item).Images), nil
+				},
+			},
+			"attributes": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(attributeType))),
+				Description: "Sorted by key",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					attributes := p.Source.(bidtracker.Item).Attributes
+					keys := make([]string, 0, len(attributes))
+					for key := range attributes {
+						keys = append(keys, key)
+					}
+					sort.Strings(keys)
+					list := make([]map[string]interface{}, len(keys))
+					for i, key := range keys {
+						list[i] = map[string]interface{}{"key": key, "value": attributes[key]}
+					}
+					return list, nil
+				},
+			},
+			"endTime": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Unix timestamp at which the auction closes, 0 if it never does",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.Item).EndTime, nil
+				},
+			},
+			"winningBid": &graphql.Field{
+				Type:        bidType,
+				Description: "Null until the first bid",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					bid, err := api.itemsBid.CurrentWinningBid(p.Source.(bidtracker.Item).UUID)
+					if err != nil {
+						return nil, nil
+					}
+					return *bid, nil
+				},
+			},
+			"topBids": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(leaderboardEntryType))),
+				Description: "The best bid of each user, best first",
+				Args: graphql.FieldConfigArgument{
+					"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: bidtracker.DefaultLeaderboardSize},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit, _ := p.Args["limit"].(int)
+					if limit < 1 || limit > bidtracker.MaxLeaderboardSize {
+						return nil, fmt.Errorf("limit must be between 1 and %d", bidtracker.MaxLeaderboardSize)
+					}
+					return graphQLResult(api.itemsBid.Leaderboard(p.Source.(bidtracker.Item).UUID, limit))
+				},
+			},
+			"bids": &graphql.Field{
+				Type: graphql.NewNonNull(bidPageType),
+				Args: pageArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					query, err := graphQLBidQuery(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					return graphQLResult(api.itemsBid.ListBids(p.Source.(bidtracker.Item).UUID, query))
+				},
+			},
+		},
+	})
+
+	portfolioStatusType := graphql.NewEnum(graphql.EnumConfig{
+		Name: "PortfolioStatus",
+		Values: graphql.EnumValueConfigMap{
+			"WINNING": &graphql.EnumValueConfig{Value: bidtracker.StatusWinning},
+			"OUTBID":  &graphql.EnumValueConfig{Value: bidtracker.StatusOutbid},
+			"WON":     &graphql.EnumValueConfig{Value: bidtracker.StatusWon},
+			"LOST":    &graphql.EnumValueConfig{Value: bidtracker.StatusLost},
+		},
+	})
+
+	portfolioEntryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PortfolioEntry",
+		Fields: graphql.Fields{
+			"item": &graphql.Field{
+				Type: graphql.NewNonNull(itemType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := api.graphQLAuthorize(p.Context, PermissionItemsRead); err != nil {
+						return nil, err
+					}
+					return graphQLResult(api.itemsBid.GetItem(p.Source.(bidtracker.PortfolioEntry).ItemUUID))
+				},
+			},
+			"highestBid": &graphql.Field{
+				Type:        graphql.NewNonNull(bidType),
+				Description: "The best bid of the user on the item",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.PortfolioEntry).HighestBid, nil
+				},
+			},
+			"bidCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.PortfolioEntry).BidCount, nil
+				},
+			},
+			"leadingAmount": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "The current highest bid on the item, by anyone",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.PortfolioEntry).LeadingAmount, nil
+				},
+			},
+			"status": &graphql.Field{
+				Type: graphql.NewNonNull(portfolioStatusType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.PortfolioEntry).Status, nil
+				},
+			},
+		},
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"userUuid": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(uuid.UUID).String(), nil
+				},
+			},
+			"bids": &graphql.Field{
+				Type: graphql.NewNonNull(bidPageType),
+				Args: pageArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					query, err := graphQLBidQuery(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					page, err := api.itemsBid.ListBidsByUser(p.Source.(uuid.UUID), query)
+					if err != nil {
+						// Users without bids are not known to the tracker
+						return bidtracker.BidPage{Bids: []bidtracker.Bid{}}, nil
+					}
+					return page, nil
+				},
+			},
+			"portfolio": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(portfolioEntryType))),
+				Description: "The standing of the user on every item they bid on",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					portfolio, err := api.itemsBid.GetPortfolio(p.Source.(uuid.UUID))
+					if err != nil {
+						return []bidtracker.PortfolioEntry{}, nil
+					}
+					return portfolio, nil
+				},
+			},
+		},
+	})
+
+	itemEventType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ItemEvent",
+		Fields: graphql.Fields{
+			"bid": &graphql.Field{
+				Type: graphql.NewNonNull(bidType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.ItemEvent).Bid, nil
+				},
+			},
+			"leader": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Whether the bid became the winning bid",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.ItemEvent).Leader, nil
+				},
+			},
+			"winningBid": &graphql.Field{
+				Type: graphql.NewNonNull(bidType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bidtracker.ItemEvent).WinningBid, nil
+				},
+			},
+		},
+	})
+
+	itemArgs := graphql.FieldConfigArgument{
+		"itemUuid": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"item": &graphql.Field{
+				Type: itemType,
+				Args: itemArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := api.graphQLAuthorize(p.Context, PermissionItemsRead); err != nil {
+						return nil, err
+					}
+					itemuuid, err := graphQLUUID(p.Args, "itemUuid")
+					if err != nil {
+						return nil, err
+					}
+					return graphQLResult(api.itemsBid.GetItem(itemuuid))
+				},
+			},
+			"bids": &graphql.Field{
+				Type: graphql.NewNonNull(bidPageType),
+				Args: graphql.FieldConfigArgument{
+					"itemUuid": itemArgs["itemUuid"],
+					"limit":    pageArgs["limit"],
+					"cursor":   pageArgs["cursor"],
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					itemuuid, err := graphQLUUID(p.Args, "itemUuid")
+					if err != nil {
+						return nil, err
+					}
+					query, err := graphQLBidQuery(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					return graphQLResult(api.itemsBid.ListBids(itemuuid, query))
+				},
+			},
+			"user": &graphql.Field{
+				Type:        graphql.NewNonNull(userType),
+				Description: "The user the api key of the caller is bound to, unless userUuid is given",
+				Args: graphql.FieldConfigArgument{
+					"userUuid": &graphql.ArgumentConfig{Type: graphql.ID},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					useruuid, err := graphQLUUID(p.Args, "userUuid")
+					if err != nil {
+						return nil, err
+					}
+					if useruuid == uuid.Nil {
+						useruuid = graphQLCaller(p.Context).UserUUID
+					}
+					if useruuid == uuid.Nil {
+						return nil, errors.New("userUuid is required, the api key of the caller is not bound to a user")
+					}
+					return useruuid, nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"placeBid": &graphql.Field{
+				Type:        graphql.NewNonNull(bidType),
+				Description: "Places a bid for the user the api key of the caller is bound to, unless userUuid is given",
+				Args: graphql.FieldConfigArgument{
+					"itemUuid":  itemArgs["itemUuid"],
+					"amount":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)},
+					"userUuid":  &graphql.ArgumentConfig{Type: graphql.ID},
+					"timestamp": &graphql.ArgumentConfig{Type: graphql.Int, Description: "unix timestamp of the bid, now by default"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := api.graphQLAuthorize(p.Context, PermissionBidsCreate); err != nil {
+						return nil, err
+					}
+					bid := bidtracker.Bid{Timestamp: time.Now().Unix()}
+					bid.Amount, _ = p.Args["amount"].(float64)
+					if timestamp, ok := p.Args["timestamp"].(int); ok {
+						bid.Timestamp = int64(timestamp)
+					}
+					var err error
+					if bid.ItemUUID, err = graphQLUUID(p.Args, "itemUuid"); err != nil {
+						return nil, err
+					}
+					if bid.UserUUID, err = graphQLUUID(p.Args, "userUuid"); err != nil {
+						return nil, err
+					}
+					if bid.UserUUID == uuid.Nil {
+						bid.UserUUID = graphQLCaller(p.Context).UserUUID
+					}
+					if bid.UserUUID == uuid.Nil {
+						return nil, errors.New("userUuid is required, the api key of the caller is not bound to a user")
+					}
+					if api.apiKeys != nil && !api.policy.BidsFor(graphQLCaller(p.Context), bid.UserUUID) {
+						return nil, fmt.Errorf("Permission denied, %s is required to bid for another user", PermissionBidsCreateAny)
+					}
+
+					if err := api.itemsBid.InsertBidContext(p.Context, &bid); err != nil {
+						return nil, errors.WithMessage(err, "Failed to insert the bid")
+					}
+					return bid, nil
+				},
+			},
+		},
+	})
+
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"leaderChanged": &graphql.Field{
+				Type:        graphql.NewNonNull(itemEventType),
+				Description: "Sends every bid on the item which becomes the winning bid, served as text/event-stream",
+				Args:        itemArgs,
+				Subscribe:   api.subscribeLeaderChanged,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					switch source := p.Source.(type) {
+					case bidtracker.ItemEvent:
+						return source, nil
+					case error:
+						return nil, source
+					}
+					return nil, errors.New("Subscriptions are only served with Accept: text/event-stream")
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:        query,
+		Mutation:     mutation,
+		Subscription: subscription,
+	})
+}
+
+// subscribeLeaderChanged watches the item of the leaderChanged subscription until its context is done.
+// The error ending the watch, if any, is sent as the last event.
+func (api *API) subscribeLeaderChanged(p graphql.ResolveParams) (interface{}, error) {
+	if err := api.graphQLAuthorize(p.Context, PermissionBidsRead); err != nil {
+		return nil, err
+	}
+	itemuuid, err := graphQLUUID(p.Args, "itemUuid")
+	if err != nil {
+		return nil, err
+	}
+	watch, err := api.itemsBid.WatchItem(itemuuid)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan interface{})
+	go func() {
+		defer close(events)
+		defer watch.Close()
+
+		for {
+			var event interface{}
+			select {
+			case <-p.Context.Done():
+				return
+			case itemEvent, ok := <-watch.Events():
+				if !ok {
+					if watch.Err() == nil {
+						return
+					}
+					event = watch.Err()
+				} else if !itemEvent.Leader {
+					continue
+				} else {
+					event = itemEvent
+				}
+			}
+
+			select {
+			case events <- event:
+			case <-p.Context.Done():
+				return
+			}
+			if _, stopped := event.(error); stopped {
+				return
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,91 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// GetHandlerSystemAnalytics godoc
+// @Summary Get analytics across all items
+// @Description Get the number of items, bids and bidders, the bid rate, the sum of the leading bids and a histogram of the bid amounts across all items
+// @Tags Analytics
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param buckets query int false "number of histogram buckets, 10 by default and at most 100"
+// @Success 200 {object} ResponseSystemAnalytics
+// @Failure 400 {object} Response
+// @Failure 429 {object} Response
+// @Router /analytics [get]
+// GetHandlerSystemAnalytics handles GET requests for the analytics across all items
+func (api *API) GetHandlerSystemAnalytics(c *fiber.Ctx) error {
+	query, err := parseAnalyticsQuery(c)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Invalid analytics query"))
+	}
+
+	analytics, err := api.itemsBid.SystemAnalytics(query)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to compute the analytics"))
+	}
+	return SendJSON(c, fiber.StatusOK, "Success", analytics)
+}
+
+// GetHandlerItemAnalytics godoc
+// @Summary Get analytics of an item
+// @Description Get the number of bids and bidders, the bid rate, the price over time in buckets and a histogram of the bid amounts on an item
+// @Tags Analytics
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param itemuuid path string true "itemuuid"
+// @Param interval query int false "width of a price series bucket in seconds, 60 by default"
+// @Param buckets query int false "number of histogram buckets, 10 by default and at most 100"
+// @Success 200 {object} ResponseItemAnalytics
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 429 {object} Response
+// @Router /analytics/items/{itemuuid} [get]
+// GetHandlerItemAnalytics handles GET requests for the analytics of an item
+func (api *API) GetHandlerItemAnalytics(c *fiber.Ctx) error {
+
+	var itemuuid uuid.UUID
+	var err error
+
+	if itemuuid, err = paramUUID(c, "itemuuid"); err != nil {
+		return SendError(c, err)
+	}
+
+	query, err := parseAnalyticsQuery(c)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Invalid analytics query"))
+	}
+
+	analytics, err := api.itemsBid.ItemAnalytics(itemuuid, query)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to compute the analytics"))
+	}
+	return SendJSON(c, fiber.StatusOK, "Success", analytics)
+}
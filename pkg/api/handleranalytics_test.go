@@ -0,0 +1,80 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHandlerAnalytics(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	userA := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+	userB := uuid.Must(uuid.FromString("f475091b-a8f1-4679-83bd-483b616e5260"))
+
+	api := NewAPI()
+	api.itemsBid = bidtracker.NewBidManagement(itemUUID)
+	api.server = fiber.New()
+	api.server.Get(URLAnalytics, api.GetHandlerSystemAnalytics)
+	api.server.Get(URLAnalyticsItem, api.GetHandlerItemAnalytics)
+
+	for i, user := range []uuid.UUID{userA, userB, userA} {
+		assert.Nil(api.itemsBid.InsertBid(&bidtracker.Bid{ItemUUID: itemUUID, UserUUID: user, Timestamp: int64(i * 30), Amount: float64(10 * (i + 1))}))
+	}
+
+	// WHEN
+	resp, _ := api.server.Test(httptest.NewRequest("GET", "/analytics/items/"+itemUUID.String()+"?interval=30&buckets=2", nil))
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	item := new(ResponseItemAnalytics)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(item))
+	assert.Equal(3, item.Data.BidCount)
+	assert.Equal(2, item.Data.UniqueBidders)
+	assert.Len(item.Data.PriceSeries, 3)
+	assert.Len(item.Data.Histogram, 2)
+
+	// WHEN
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/analytics", nil))
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	system := new(ResponseSystemAnalytics)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(system))
+	assert.Equal(1, system.Data.ItemsWithBids)
+	assert.Equal(30.0, system.Data.LeadingVolume)
+
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/analytics/items/cef31b6b-cdeb-4035-8d42-a4f33b2d02fe", nil))
+	assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/analytics?buckets=1000", nil))
+	assert.Equal(fiber.StatusBadRequest, resp.StatusCode)
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/analytics/items/"+itemUUID.String()+"?interval=0.5", nil))
+	assert.Equal(fiber.StatusBadRequest, resp.StatusCode)
+}
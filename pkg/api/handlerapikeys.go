@@ -0,0 +1,203 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// APIKeyCreateRequest is the body accepted to issue a new api key
+type APIKeyCreateRequest struct {
+	Name string `json:"name"`
+	// Role of the client in the access policy, defaults to bidder
+	Role string `json:"role"`
+	// UserUUID optionally binds the key to a user
+	UserUUID uuid.UUID `json:"useruuid"`
+	Scopes   []string  `json:"scopes"`
+	// TTL is the lifetime of the key in seconds, 0 means it never expires
+	TTL int64 `json:"ttl"`
+}
+
+// APIKeyRotateRequest is the body accepted to rotate an api key
+type APIKeyRotateRequest struct {
+	// Grace is the number of seconds the old secret stays valid
+	Grace int64 `json:"grace"`
+	// TTL resets the lifetime of the key in seconds, 0 keeps the current expiry
+	TTL int64 `json:"ttl"`
+}
+
+// APIKeyIssued carries a freshly issued token. It is the only time the token is returned.
+type APIKeyIssued struct {
+	Token string     `json:"token"`
+	Key   apikey.Key `json:"key"`
+}
+
+// GetHandlerAPIKeys godoc
+// @Summary List api keys
+// @Description List all the api keys known to the server, without their secrets
+// @Tags Admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Router /admin/apikeys [get]
+// GetHandlerAPIKeys handles GET requests to list api keys
+func (api *API) GetHandlerAPIKeys(c *fiber.Ctx) error {
+	return SendJSON(c, fiber.StatusOK, "Success", api.apiKeys.List())
+}
+
+// PostHandlerAPIKeyNew godoc
+// @Summary Issue a new api key
+// @Description Issue a new api key with the given role and scopes. The token is only returned once.
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param  APIKey body APIKeyCreateRequest true  "APIKey"
+// @Success 201 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 500 {object} Response
+// @Router /admin/apikeys [post]
+// PostHandlerAPIKeyNew handles POST requests to issue new api keys
+func (api *API) PostHandlerAPIKeyNew(c *fiber.Ctx) error {
+	req := new(APIKeyCreateRequest)
+	if err := c.BodyParser(req); err != nil {
+		msg := errors.WithMessage(err, "json body can not be parsed successfully").Error()
+		return SendJSON(c, fiber.StatusBadRequest, msg, EmptyResponse)
+	}
+
+	if len(req.Scopes) == 0 {
+		return SendError(c, invalidParam("scopes", errors.New("At least one scope is required")))
+	}
+	scopes := make([]apikey.Scope, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		scope, err := apikey.ParseScope(s)
+		if err != nil {
+			return SendError(c, invalidParam("scopes", err))
+		}
+		scopes = append(scopes, scope)
+	}
+	if req.TTL < 0 {
+		return SendError(c, invalidParam("ttl", errors.New("ttl can not be negative")))
+	}
+
+	role := Role(req.Role)
+	if role == "" {
+		role = RoleBidder
+	}
+	if !api.policy.HasRole(role) {
+		return SendError(c, invalidParam("role", errors.Errorf("Unknown role %s", role)))
+	}
+
+	token, key, err := api.apiKeys.Create(apikey.Spec{
+		Name:     req.Name,
+		Role:     string(role),
+		UserUUID: req.UserUUID,
+		Scopes:   scopes,
+		TTL:      time.Duration(req.TTL) * time.Second,
+	})
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to create the api key"))
+	}
+	if err := api.saveAPIKeys(); err != nil {
+		return SendError(c, err)
+	}
+	return SendJSON(c, fiber.StatusCreated, "Created the api key", APIKeyIssued{Token: token, Key: key})
+}
+
+// PostHandlerAPIKeyRotate godoc
+// @Summary Rotate an api key
+// @Description Replace the secret of an api key, optionally keeping the old one valid for a grace period
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param keyid path string true "keyid"
+// @Param  Rotation body APIKeyRotateRequest false  "Rotation"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 409 {object} Response
+// @Failure 500 {object} Response
+// @Router /admin/apikeys/{keyid}/rotate [post]
+// PostHandlerAPIKeyRotate handles POST requests to rotate an api key
+func (api *API) PostHandlerAPIKeyRotate(c *fiber.Ctx) error {
+	req := new(APIKeyRotateRequest)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(req); err != nil {
+			msg := errors.WithMessage(err, "json body can not be parsed successfully").Error()
+			return SendJSON(c, fiber.StatusBadRequest, msg, EmptyResponse)
+		}
+	}
+	if req.Grace < 0 || req.TTL < 0 {
+		return SendJSON(c, fiber.StatusBadRequest, "grace and ttl can not be negative", EmptyResponse)
+	}
+
+	token, key, err := api.apiKeys.Rotate(c.Params("keyid"),
+		time.Duration(req.Grace)*time.Second, time.Duration(req.TTL)*time.Second)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to rotate the api key"))
+	}
+	if err := api.saveAPIKeys(); err != nil {
+		return SendError(c, err)
+	}
+	return SendJSON(c, fiber.StatusOK, "Rotated the api key", APIKeyIssued{Token: token, Key: key})
+}
+
+// DeleteHandlerAPIKey godoc
+// @Summary Revoke an api key
+// @Description Permanently revoke an api key
+// @Tags Admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param keyid path string true "keyid"
+// @Success 200 {object} Response
+// @Failure 404 {object} Response
+// @Failure 500 {object} Response
+// @Router /admin/apikeys/{keyid} [delete]
+// DeleteHandlerAPIKey handles DELETE requests to revoke an api key
+func (api *API) DeleteHandlerAPIKey(c *fiber.Ctx) error {
+	key, err := api.apiKeys.Revoke(c.Params("keyid"))
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to revoke the api key"))
+	}
+	if err := api.saveAPIKeys(); err != nil {
+		return SendError(c, err)
+	}
+	return SendJSON(c, fiber.StatusOK, "Revoked the api key", key)
+}
+
+// saveAPIKeys writes the api keys to their file, if any, once a key has changed
+func (api *API) saveAPIKeys() error {
+	if api.apiKeysFile == "" {
+		return nil
+	}
+	return errors.WithMessage(api.apiKeys.Save(api.apiKeysFile), "Failed to save the api keys")
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type responseAPIKeyIssued struct {
+	Status  int
+	Message string
+	Data    APIKeyIssued
+}
+
+func newAPIWithKeys(t *testing.T) (*API, string) {
+	biddableItems := []uuid.UUID{
+		uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a")),
+	}
+	store := apikey.NewStore()
+	adminToken, _, err := store.Create(apikey.Spec{Name: "admin", Role: string(RoleAdmin), Scopes: []apikey.Scope{apikey.ScopeAdmin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(biddableItems...), fiber.New())
+	if err := RegisterRoutes(api, RegisterWithAPIVersion("/api/v1"), RegisterWithAPIKeys(store)); err != nil {
+		t.Fatal(err)
+	}
+	return api, adminToken
+}
+
+func TestAPIKeyScopesEnforced(t *testing.T) {
+	assert := assert.New(t)
+	api, adminToken := newAPIWithKeys(t)
+
+	// WHEN no key is provided
+	req := httptest.NewRequest("GET", "/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a", nil)
+	resp, _ := api.server.Test(req)
+	assert.Equal(fiber.StatusUnauthorized, resp.StatusCode)
+
+	// WHEN a read-only key is issued through the admin endpoint
+	req = httptest.NewRequest("POST", "/api/v1/admin/apikeys", bytes.NewBufferString(`{"name":"partner","scopes":["bids:read"]}`))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add(APIKeyHeader, adminToken)
+	resp, _ = api.server.Test(req)
+	assert.Equal(fiber.StatusCreated, resp.StatusCode)
+
+	issued := new(responseAPIKeyIssued)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(issued))
+	readToken := issued.Data.Token
+
+	// THEN it can read bids
+	req = httptest.NewRequest("GET", "/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a", nil)
+	req.Header.Add(fiber.HeaderAuthorization, "Bearer "+readToken)
+	resp, _ = api.server.Test(req)
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+
+	// THEN it can neither bid nor manage keys
+	jsonData := `{"useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba", "itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a", "timestamp":1351807721, "amount":30}`
+	req = httptest.NewRequest("POST", "/api/v1/bids", bytes.NewBufferString(jsonData))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add(APIKeyHeader, readToken)
+	resp, _ = api.server.Test(req)
+	assert.Equal(fiber.StatusForbidden, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", "/api/v1/admin/apikeys", nil)
+	req.Header.Add(APIKeyHeader, readToken)
+	resp, _ = api.server.Test(req)
+	assert.Equal(fiber.StatusForbidden, resp.StatusCode)
+}
+
+func TestAPIKeyRotateAndRevoke(t *testing.T) {
+	assert := assert.New(t)
+	api, adminToken := newAPIWithKeys(t)
+
+	token, key, err := api.apiKeys.Create(apikey.Spec{Name: "partner", Role: string(RoleBidder), Scopes: []apikey.Scope{apikey.ScopeBidsRead}})
+	assert.Nil(err)
+
+	// WHEN the key is rotated without grace
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/admin/apikeys/%s/rotate", key.ID), nil)
+	req.Header.Add(APIKeyHeader, adminToken)
+	resp, _ := api.server.Test(req)
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+
+	issued := new(responseAPIKeyIssued)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(issued))
+
+	// THEN only the new token works
+	req = httptest.NewRequest("GET", "/api/v1/users/ae8f7716-867b-4479-b455-c5769e7475ba/bids", nil)
+	req.Header.Add(APIKeyHeader, token)
+	resp, _ = api.server.Test(req)
+	assert.Equal(fiber.StatusUnauthorized, resp.StatusCode)
+
+	// WHEN the key is revoked
+	req = httptest.NewRequest("DELETE", fmt.Sprintf("/api/v1/admin/apikeys/%s", key.ID), nil)
+	req.Header.Add(APIKeyHeader, adminToken)
+	resp, _ = api.server.Test(req)
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+
+	// THEN the rotated token stops working as well
+	req = httptest.NewRequest("GET", "/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a", nil)
+	req.Header.Add(APIKeyHeader, issued.Data.Token)
+	resp, _ = api.server.Test(req)
+	assert.Equal(fiber.StatusUnauthorized, resp.StatusCode)
+
+	req = httptest.NewRequest("DELETE", "/api/v1/admin/apikeys/unknown", nil)
+	req.Header.Add(APIKeyHeader, adminToken)
+	resp, _ = api.server.Test(req)
+	assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+}
+
+func TestAPIKeysSavedOnChange(t *testing.T) {
+	assert := assert.New(t)
+
+	keysFile := filepath.Join(t.TempDir(), "bids.keys")
+	store := apikey.NewStore()
+	adminToken, _, err := store.Create(apikey.Spec{Name: "admin", Role: string(RoleAdmin), Scopes: []apikey.Scope{apikey.ScopeAdmin}})
+	assert.Nil(err)
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(), fiber.New())
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1"), RegisterWithAPIKeys(store), RegisterWithAPIKeysFile(keysFile)))
+
+	// WHEN a key is issued and then revoked
+	req := httptest.NewRequest("POST", "/api/v1/admin/apikeys", bytes.NewBufferString(`{"name":"partner","scopes":["bids:read"]}`))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add(APIKeyHeader, adminToken)
+	resp, _ := api.server.Test(req)
+	assert.Equal(fiber.StatusCreated, resp.StatusCode)
+	issued := new(responseAPIKeyIssued)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(issued))
+
+	req = httptest.NewRequest("DELETE", fmt.Sprintf("/api/v1/admin/apikeys/%s", issued.Data.Key.ID), nil)
+	req.Header.Add(APIKeyHeader, adminToken)
+	resp, _ = api.server.Test(req)
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+
+	// THEN a store restored from the keys file, as after a crash, still knows it is revoked
+	restored := apikey.NewStore()
+	assert.Nil(restored.Load(keysFile))
+	_, err = restored.Authenticate(issued.Data.Token)
+	assert.True(errors.Is(err, apikey.ErrRevokedKey))
+
+	// WHEN the keys can not be saved, the change is reported as failed
+	api = NewAPIWithSettings(bidtracker.NewBidManagement(), fiber.New())
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1"), RegisterWithAPIKeys(store),
+		RegisterWithAPIKeysFile(filepath.Join(t.TempDir(), "missing", "bids.keys"))))
+	req = httptest.NewRequest("POST", "/api/v1/admin/apikeys", bytes.NewBufferString(`{"name":"partner","scopes":["bids:read"]}`))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add(APIKeyHeader, adminToken)
+	resp, _ = api.server.Test(req)
+	assert.Equal(fiber.StatusInternalServerError, resp.StatusCode)
+}
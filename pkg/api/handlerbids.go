@@ -26,6 +26,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 )
 
 // PostHandlerBidNew godoc
@@ -34,11 +35,15 @@ import (
 // @Tags Bids
 // @Accept  json
 // @Produce  json
+// @Security ApiKeyAuth
 // @Param itemuuid path string true "itemuuid"
 // @Param  Bid body bidtracker.Bid true  "Bid"
 // @Success 200 {object} ResponseBid
 // @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
 // @Failure 422 {object} Response
+// @Failure 429 {object} Response
 // @Router /bids [post]
 // PostHandlerBidNew handles all the POST requests regarding creation of new bids
 func (api *API) PostHandlerBidNew(c *fiber.Ctx) error {
@@ -54,50 +59,136 @@ func (api *API) PostHandlerBidNew(c *fiber.Ctx) error {
 	// Associate the current user bid with the itemuuid
 	// userBid.ItemUUID = itemuuid
 
+	logger := zerolog.Ctx(c.UserContext())
+
 	userBid := new(bidtracker.Bid)
 	if err := c.BodyParser(userBid); err != nil {
+		logger.Debug().Err(err).Str("reason", "invalid_body").Msg("Bid rejected")
 		msg := errors.WithMessage(err, "json body can not be parsed successfully").Error()
 		return SendJSON(c, fiber.StatusBadRequest, msg, EmptyResponse)
 	}
+	// Only moderators and integrations bid for other users than the one of their api key
+	if !api.bidsFor(c, userBid.UserUUID) {
+		logger.Debug().Str("reason", "other_user").Msg("Bid rejected")
+		return sendForbidden(c, PermissionBidsCreateAny)
+	}
 
-	if err := api.itemsBid.InsertBid(userBid); err != nil {
-		msg := errors.WithMessage(err, "Failed to insert the bid").Error()
-		return SendJSON(c, fiber.StatusUnprocessableEntity, msg, EmptyResponse)
+	if err := api.itemsBid.InsertBidContext(c.UserContext(), userBid); err != nil {
+		var rejected *bidtracker.BidRejectedError
+		if errors.As(err, &rejected) {
+			logger.Debug().
+				Str("reason", string(rejected.Reason)).
+				Str("itemuuid", userBid.ItemUUID.String()).
+				Str("useruuid", userBid.UserUUID.String()).
+				Float64("amount", userBid.Amount).
+				Err(rejected.Err).
+				Msg("Bid rejected")
+		}
+		return SendError(c, errors.WithMessage(err, "Failed to insert the bid"))
 	}
 
 	return SendJSON(c, fiber.StatusOK, "Updated the bid", userBid)
 }
 
+// MaxBidBatchSize is the number of bids a batch may hold at most
+const MaxBidBatchSize = 1000
+
+// PostHandlerBidBatch godoc
+// @Summary Post many bids at once
+// @Description Insert an array of bids in order, with a result per bid telling whether it was accepted, why not, and whether it became the leading bid of its item.
+// @Description Every valid bid is accepted unless atomic is set, then either all of them are or none is, and the response is a 422 with the results.
+// @Tags Bids
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param atomic query bool false "all-or-nothing instead of best-effort"
+// @Param  Bids body []bidtracker.Bid true  "Bids, at most 1000"
+// @Success 200 {object} ResponseBidBatch
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 422 {object} ResponseBidBatch
+// @Failure 429 {object} Response
+// @Router /bids:batch [post]
+// PostHandlerBidBatch handles POST requests to insert many bids at once
+func (api *API) PostHandlerBidBatch(c *fiber.Ctx) error {
+	atomic, err := queryBool(c, "atomic")
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Invalid batch query"))
+	}
+
+	var bids []bidtracker.Bid
+	if err := c.BodyParser(&bids); err != nil {
+		msg := errors.WithMessage(err, "json body can not be parsed successfully").Error()
+		return SendJSON(c, fiber.StatusBadRequest, msg, EmptyResponse)
+	}
+	if len(bids) == 0 || len(bids) > MaxBidBatchSize {
+		msg := errors.Errorf("A batch must hold between 1 and %d bids, got %d", MaxBidBatchSize, len(bids)).Error()
+		return SendJSON(c, fiber.StatusBadRequest, msg, EmptyResponse)
+	}
+	for _, bid := range bids {
+		if !api.bidsFor(c, bid.UserUUID) {
+			return sendForbidden(c, PermissionBidsCreateAny)
+		}
+	}
+
+	result := api.itemsBid.InsertBidsContext(c.UserContext(), bids, atomic)
+	if result.Rejected > 0 {
+		zerolog.Ctx(c.UserContext()).Debug().
+			Int("accepted", result.Accepted).
+			Int("rejected", result.Rejected).
+			Bool("atomic", atomic).
+			Msg("Bids of a batch rejected")
+	}
+	if atomic && result.Rejected > 0 {
+		return SendJSON(c, fiber.StatusUnprocessableEntity, "Rejected the batch", result)
+	}
+	return SendJSON(c, fiber.StatusOK, "Inserted the batch", result)
+}
+
 // GetHandlerBids godoc
 // @Summary Get all current bids on an item
 // @Description get string by ID
 // @Tags Bids
 // @Accept  json
 // @Produce  json
+// @Security ApiKeyAuth
 // @Param itemuuid path string true "itemuuid"
+// @Param limit query int false "page size, 100 by default"
+// @Param cursor query string false "NextCursor of the previous page"
+// @Param sort query string false "sort field" Enums(time, amount)
+// @Param order query string false "sort order" Enums(asc, desc)
+// @Param min_amount query number false "minimum amount"
+// @Param max_amount query number false "maximum amount"
+// @Param from query int false "minimum timestamp"
+// @Param to query int false "maximum timestamp"
+// @Param useruuid query string false "only bids of this user"
 // @Success 200 {object} ResponseGetBids
 // @Failure 400 {object} Response
-// @Failure 422 {object} Response
+// @Failure 404 {object} Response
+// @Failure 429 {object} Response
 // @Router /bids/{itemuuid} [get]
-// GetHandlerBids handles all the GET requests regarding creation of new bids
+// GetHandlerBids handles all the GET requests to list the bids on an item, one page at a time
 func (api *API) GetHandlerBids(c *fiber.Ctx) error {
 
 	var itemuuid uuid.UUID
 	var err error
 
-	if itemuuid, err = uuid.FromString(c.Params("itemuuid")); err != nil {
-		msg := errors.WithMessage(err, "itemuuid can not be parsed successfully").Error()
-		return SendJSON(c, fiber.StatusBadRequest, msg, EmptyResponse)
+	if itemuuid, err = paramUUID(c, "itemuuid"); err != nil {
+		return SendError(c, err)
 
 	}
 
-	bids, err := api.itemsBid.GetBids(itemuuid)
+	query, err := parseBidQuery(c)
 	if err != nil {
-		msg := errors.WithMessage(err, "Failed to fetch the list of bids").Error()
-		return SendJSON(c, fiber.StatusUnprocessableEntity, msg, EmptyResponse)
+		return SendError(c, errors.WithMessage(err, "Invalid query"))
+	}
+
+	page, err := api.itemsBid.ListBids(itemuuid, query)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to fetch the list of bids"))
 
 	}
-	return SendJSON(c, fiber.StatusOK, "Success", bids)
+	return SendJSON(c, fiber.StatusOK, "Success", page)
 }
 
 // GetHandlerCurrentWinningBid godoc
@@ -106,10 +197,12 @@ func (api *API) GetHandlerBids(c *fiber.Ctx) error {
 // @Tags Bids
 // @Accept  json
 // @Produce  json
+// @Security ApiKeyAuth
 // @Param itemuuid path string true "itemuuid"
 // @Success 200 {object} ResponseBid
 // @Failure 400 {object} Response
-// @Failure 422 {object} Response
+// @Failure 404 {object} Response
+// @Failure 429 {object} Response
 // @Router /bids/{itemuuid}/winning [get]
 // GetHandlerCurrentWinningBid handles all the GET requests to get currently winning bids
 func (api *API) GetHandlerCurrentWinningBid(c *fiber.Ctx) error {
@@ -117,15 +210,86 @@ func (api *API) GetHandlerCurrentWinningBid(c *fiber.Ctx) error {
 	var itemuuid uuid.UUID
 	var err error
 
-	if itemuuid, err = uuid.FromString(c.Params("itemuuid")); err != nil {
-		msg := errors.WithMessage(err, "itemuuid can not be parsed successfully").Error()
-		return SendJSON(c, fiber.StatusBadRequest, msg, EmptyResponse)
+	if itemuuid, err = paramUUID(c, "itemuuid"); err != nil {
+		return SendError(c, err)
 	}
 
 	bid, err := api.itemsBid.CurrentWinningBid(itemuuid)
 	if err != nil {
-		msg := errors.WithMessage(err, "Failed to fetch the current winning bid").Error()
-		return SendJSON(c, fiber.StatusUnprocessableEntity, msg, EmptyResponse)
+		return SendError(c, errors.WithMessage(err, "Failed to fetch the current winning bid"))
 	}
 	return SendJSON(c, fiber.StatusOK, "Success", bid)
 }
+
+// GetHandlerLeaderboard godoc
+// @Summary Get the leaderboard of an item
+// @Description Get the top users on an item, ranked by their best bid
+// @Tags Bids
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param itemuuid path string true "itemuuid"
+// @Param limit query int false "number of users, 10 by default and at most 100"
+// @Success 200 {object} ResponseLeaderboard
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 429 {object} Response
+// @Router /bids/{itemuuid}/leaderboard [get]
+// GetHandlerLeaderboard handles all the GET requests to get the leaderboard of an item
+func (api *API) GetHandlerLeaderboard(c *fiber.Ctx) error {
+
+	var itemuuid uuid.UUID
+	var err error
+
+	if itemuuid, err = paramUUID(c, "itemuuid"); err != nil {
+		return SendError(c, err)
+	}
+
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		return SendError(c, err)
+	}
+	if limit < 0 || limit > bidtracker.MaxLeaderboardSize {
+		return SendError(c, invalidParam("limit", errors.Errorf("limit must be between 1 and %d", bidtracker.MaxLeaderboardSize)))
+	}
+
+	leaderboard, err := api.itemsBid.Leaderboard(itemuuid, limit)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to fetch the leaderboard"))
+	}
+	return SendJSON(c, fiber.StatusOK, "Success", leaderboard)
+}
+
+// GetHandlerUserRank godoc
+// @Summary Get the rank of a user on an item
+// @Description Get the rank of a user among all the bidders of an item, and the best bid it comes from
+// @Tags Bids
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param itemuuid path string true "itemuuid"
+// @Param useruuid path string true "useruuid"
+// @Success 200 {object} ResponseRank
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 429 {object} Response
+// @Router /bids/{itemuuid}/rank/{useruuid} [get]
+// GetHandlerUserRank handles all the GET requests to get the rank of a user on an item
+func (api *API) GetHandlerUserRank(c *fiber.Ctx) error {
+
+	var itemuuid, useruuid uuid.UUID
+	var err error
+
+	if itemuuid, err = paramUUID(c, "itemuuid"); err != nil {
+		return SendError(c, err)
+	}
+	if useruuid, err = paramUUID(c, "useruuid"); err != nil {
+		return SendError(c, err)
+	}
+
+	rank, err := api.itemsBid.UserRank(itemuuid, useruuid)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to fetch the rank"))
+	}
+	return SendJSON(c, fiber.StatusOK, "Success", rank)
+}
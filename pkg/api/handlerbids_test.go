@@ -22,8 +22,10 @@ package api
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
@@ -135,7 +137,7 @@ func TestGetHandlerBids(t *testing.T) {
 	}
 }
 
-func TestGetHandlerBidsUnprocessable(t *testing.T) {
+func TestGetHandlerBidsNotFound(t *testing.T) {
 	assert := assert.New(t)
 
 	biddableItems := []uuid.UUID{
@@ -158,7 +160,7 @@ func TestGetHandlerBidsUnprocessable(t *testing.T) {
 	resp, _ = api.server.Test(reqGetAll)
 
 	// THEN
-	assert.Equal(fiber.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(fiber.StatusNotFound, resp.StatusCode)
 
 }
 
@@ -211,7 +213,7 @@ func TestGetHandlerCurrentWinningBid(t *testing.T) {
 	}
 }
 
-func TestGetHandlerCurrentWinningBidUnprocessable(t *testing.T) {
+func TestGetHandlerCurrentWinningBidNotFound(t *testing.T) {
 	assert := assert.New(t)
 
 	biddableItems := []uuid.UUID{
@@ -233,6 +235,141 @@ func TestGetHandlerCurrentWinningBidUnprocessable(t *testing.T) {
 	resp, _ = api.server.Test(reqGetAll)
 
 	// THEN
-	want := fiber.StatusUnprocessableEntity
+	want := fiber.StatusNotFound
 	assert.Equal(want, resp.StatusCode)
 }
+
+func TestGetHandlerBidsPaginated(t *testing.T) {
+	assert := assert.New(t)
+
+	biddableItems := []uuid.UUID{
+		uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a")),
+	}
+	api := NewAPI()
+	api.itemsBid = bidtracker.NewBidManagement(biddableItems...)
+	api.server = fiber.New()
+
+	api.server.Post(URLBidItem, api.PostHandlerBidNew)
+	api.server.Get(URLBidGetAll, api.GetHandlerBids)
+
+	for i, amount := range []float64{30.0, 31.0, 32.0} {
+		jsonData := fmt.Sprintf(`{"useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba", "itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a", "timestamp":%d, "amount":%f}`, 1351807721+i, amount)
+		req := httptest.NewRequest("POST", "/bids", bytes.NewBuffer([]byte(jsonData)))
+		req.Header.Add("Content-Type", "application/json")
+		api.server.Test(req)
+	}
+
+	// WHEN
+	resp, _ := api.server.Test(httptest.NewRequest("GET", "/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a?limit=2&sort=amount&order=desc", nil))
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	page := new(ResponseGetBids)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(page))
+	if assert.Len(page.Data, 2) {
+		assert.Equal(32.0, page.Data[0].Amount)
+		assert.Equal(31.0, page.Data[1].Amount)
+	}
+	assert.NotEmpty(page.NextCursor)
+
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a?limit=2&sort=amount&order=desc&cursor="+page.NextCursor, nil))
+	page = new(ResponseGetBids)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(page))
+	if assert.Len(page.Data, 1) {
+		assert.Equal(30.0, page.Data[0].Amount)
+	}
+	assert.Empty(page.NextCursor)
+
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a?sort=popularity", nil))
+	assert.Equal(fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestGetHandlerLeaderboardAndRank(t *testing.T) {
+	assert := assert.New(t)
+
+	biddableItems := []uuid.UUID{
+		uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a")),
+	}
+	api := NewAPI()
+	api.itemsBid = bidtracker.NewBidManagement(biddableItems...)
+	api.server = fiber.New()
+
+	api.server.Post(URLBidItem, api.PostHandlerBidNew)
+	api.server.Get(URLBidGetLeaderboard, api.GetHandlerLeaderboard)
+	api.server.Get(URLBidGetRank, api.GetHandlerUserRank)
+
+	for i, user := range []string{"ae8f7716-867b-4479-b455-c5769e7475ba", "f475091b-a8f1-4679-83bd-483b616e5260"} {
+		jsonData := fmt.Sprintf(`{"useruuid":"%s", "itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a", "timestamp":1351807721, "amount":%f}`, user, 30.0+float64(i))
+		req := httptest.NewRequest("POST", "/bids", bytes.NewBuffer([]byte(jsonData)))
+		req.Header.Add("Content-Type", "application/json")
+		api.server.Test(req)
+	}
+
+	// WHEN
+	resp, _ := api.server.Test(httptest.NewRequest("GET", "/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a/leaderboard?limit=5", nil))
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	leaderboard := new(ResponseLeaderboard)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(leaderboard))
+	if assert.Len(leaderboard.Data, 2) {
+		assert.Equal("f475091b-a8f1-4679-83bd-483b616e5260", leaderboard.Data[0].UserUUID.String())
+	}
+
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a/rank/ae8f7716-867b-4479-b455-c5769e7475ba", nil))
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	rank := new(ResponseRank)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(rank))
+	assert.Equal(2, rank.Data.Rank)
+	assert.Equal(30.0, rank.Data.BestBid.Amount)
+
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a/rank/cef31b6b-cdeb-4035-8d42-a4f33b2d02fe", nil))
+	assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a/leaderboard?limit=1000", nil))
+	assert.Equal(fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestPostHandlerBidBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	api := NewAPI()
+	api.itemsBid = bidtracker.NewBidManagement(itemUUID)
+	api.server = fiber.New()
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1")))
+
+	batch := `[{"useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba", "itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a", "timestamp":1, "amount":30},
+		{"useruuid":"f475091b-a8f1-4679-83bd-483b616e5260", "itemuuid":"cef31b6b-cdeb-4035-8d42-a4f33b2d02fe", "timestamp":2, "amount":40}]`
+	post := func(query, body string) *http.Response {
+		req := httptest.NewRequest("POST", "/api/v1/bids:batch"+query, bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		resp, _ := api.server.Test(req)
+		return resp
+	}
+
+	// WHEN
+	resp := post("?atomic=true", batch)
+
+	// THEN
+	assert.Equal(fiber.StatusUnprocessableEntity, resp.StatusCode)
+	result := new(ResponseBidBatch)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(result))
+	assert.Equal(2, result.Data.Rejected)
+	assert.Equal(bidtracker.RejectBatchAborted, result.Data.Results[0].Reason)
+
+	// WHEN
+	resp = post("", batch)
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	result = new(ResponseBidBatch)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(result))
+	assert.Equal(1, result.Data.Accepted)
+	assert.True(result.Data.Results[0].Leader)
+	assert.Equal(bidtracker.RejectUnknownItem, result.Data.Results[1].Reason)
+
+	assert.Equal(fiber.StatusBadRequest, post("", "[]").StatusCode)
+	assert.Equal(fiber.StatusBadRequest, post("", "{}").StatusCode)
+	assert.Equal(fiber.StatusBadRequest, post("?atomic=maybe", batch).StatusCode)
+}
@@ -0,0 +1,176 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/rs/zerolog"
+)
+
+// graphQLKeepAlive is how often a comment is sent on idle subscriptions, noticing clients gone away
+const graphQLKeepAlive = 15 * time.Second
+
+// GraphQLRequest is the body of a request to the GraphQL endpoint
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQLResponse is the result of a GraphQL request, or of one event of a subscription
+type GraphQLResponse struct {
+	Data   interface{}                `json:"data,omitempty"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty" swaggertype:"array,object"`
+}
+
+// PostHandlerGraphQL godoc
+// @Summary Run a GraphQL query, mutation or subscription
+// @Description Query items with their winning and top bids, bids and the portfolio of users, place bids with the placeBid mutation,
+// @Description or subscribe to leaderChanged with Accept: text/event-stream, which streams a next event per result and a complete event at the end.
+// @Description Each field checks the permission of the matching REST route.
+// @Tags GraphQL
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param request body GraphQLRequest true "GraphQL request"
+// @Success 200 {object} GraphQLResponse
+// @Failure 400 {object} GraphQLResponse
+// @Failure 429 {object} Response
+// @Router /graphql [post]
+// PostHandlerGraphQL handles POST requests to the GraphQL endpoint
+func (api *API) PostHandlerGraphQL(c *fiber.Ctx) error {
+	request := new(GraphQLRequest)
+	if err := json.Unmarshal(c.Body(), request); err != nil {
+		return sendGraphQLError(c, "json body can not be parsed successfully. "+err.Error())
+	}
+	if request.Query == "" {
+		return sendGraphQLError(c, "query is required")
+	}
+
+	params := graphql.Params{
+		Schema:         *api.graphQL,
+		RequestString:  request.Query,
+		VariableValues: request.Variables,
+		OperationName:  request.OperationName,
+	}
+	caller := c.Locals(localsAPIKey)
+	if graphQLOperation(request) == ast.OperationTypeSubscription && strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream") {
+		// The stream outlives the request context, which is released once the handler returns
+		params.Context = context.WithValue(context.Background(), graphQLCallerKey{}, caller)
+		return api.streamGraphQL(c, params)
+	}
+
+	params.Context = context.WithValue(c.UserContext(), graphQLCallerKey{}, caller)
+	result := graphql.Do(params)
+	return c.Status(fiber.StatusOK).JSON(GraphQLResponse{Data: result.Data, Errors: result.Errors})
+}
+
+// graphQLOperation returns the type of the operation the request runs, "" if it can not be parsed
+func graphQLOperation(request *GraphQLRequest) string {
+	document, err := parser.Parse(parser.ParseParams{Source: request.Query})
+	if err != nil {
+		return ""
+	}
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if request.OperationName == "" || (operation.Name != nil && operation.Name.Value == request.OperationName) {
+			return operation.Operation
+		}
+	}
+	return ""
+}
+
+// sendGraphQLError answers requests which could not be read as GraphQL
+func sendGraphQLError(c *fiber.Ctx, msg string) error {
+	return c.Status(fiber.StatusBadRequest).JSON(GraphQLResponse{
+		Errors: []gqlerrors.FormattedError{{Message: msg}},
+	})
+}
+
+// streamGraphQL sends the results of a subscription as server-sent events until it ends or the client goes away
+func (api *API) streamGraphQL(c *fiber.Ctx, params graphql.Params) error {
+	ctx, cancel := context.WithCancel(params.Context)
+	params.Context = ctx
+	results := graphql.Subscribe(params)
+
+	metrics := api.metrics
+	logger := zerolog.Ctx(c.UserContext())
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if metrics != nil {
+			metrics.SubscriptionOpened()
+			defer metrics.SubscriptionClosed()
+		}
+		defer func() {
+			// results is only closed once nothing is left to send on it
+			cancel()
+			for range results {
+			}
+		}()
+
+		// The headers are only sent along with the first data, a comment sends them right away
+		fmt.Fprint(w, ":\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		keepAlive := time.NewTicker(graphQLKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case result, ok := <-results:
+				if !ok {
+					fmt.Fprint(w, "event: complete\ndata:\n\n")
+					w.Flush()
+					return
+				}
+				data, err := json.Marshal(GraphQLResponse{Data: result.Data, Errors: result.Errors})
+				if err != nil {
+					logger.Error().Err(err).Msg("Failed to encode a GraphQL result")
+					return
+				}
+				fmt.Fprintf(w, "event: next\ndata: %s\n\n", data)
+			case <-keepAlive.C:
+				fmt.Fprint(w, ":\n\n")
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type graphQLTestResponse struct {
+	Data   map[string]interface{}
+	Errors []struct{ Message string }
+}
+
+func postGraphQL(t *testing.T, api *API, token string, query string, variables map[string]interface{}) (int, graphQLTestResponse) {
+	body, _ := json.Marshal(GraphQLRequest{Query: query, Variables: variables})
+	req := httptest.NewRequest("POST", "/api/v1/graphql", bytes.NewBuffer(body))
+	req.Header.Add("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Add(APIKeyHeader, token)
+	}
+	resp, err := api.server.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := graphQLTestResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, response
+}
+
+func TestPostHandlerGraphQL(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := "b2f9ee6d-79fe-4b14-9c19-35a69a89219a"
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(uuid.Must(uuid.FromString(itemUUID))), fiber.New())
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1")))
+
+	// WHEN bids are placed with the mutation
+	placeBid := `mutation($item: ID!, $user: ID!, $amount: Float!) { placeBid(itemUuid: $item, userUuid: $user, amount: $amount, timestamp: 1351807721) { amount timestamp } }`
+	status, response := postGraphQL(t, api, "", placeBid, map[string]interface{}{"item": itemUUID, "user": "ae8f7716-867b-4479-b455-c5769e7475ba", "amount": 30})
+	assert.Equal(fiber.StatusOK, status)
+	assert.Empty(response.Errors)
+	assert.Equal(map[string]interface{}{"amount": 30.0, "timestamp": 1351807721.0}, response.Data["placeBid"])
+	postGraphQL(t, api, "", placeBid, map[string]interface{}{"item": itemUUID, "user": "f475091b-a8f1-4679-83bd-483b616e5260", "amount": 31})
+	postGraphQL(t, api, "", placeBid, map[string]interface{}{"item": itemUUID, "user": "ae8f7716-867b-4479-b455-c5769e7475ba", "amount": 32})
+
+	// THEN the item, its winning bid, top bids and bids are fetched in one request
+	_, response = postGraphQL(t, api, "", `query($item: ID!) {
+		item(itemUuid: $item) { itemUuid winningBid { amount } topBids(limit: 1) { rank userUuid } bids(limit: 2) { bids { amount } nextCursor } }
+	}`, map[string]interface{}{"item": itemUUID})
+	assert.Empty(response.Errors)
+	item := response.Data["item"].(map[string]interface{})
+	assert.Equal(itemUUID, item["itemUuid"])
+	assert.Equal(map[string]interface{}{"amount": 32.0}, item["winningBid"])
+	assert.Equal([]interface{}{map[string]interface{}{"rank": 1.0, "userUuid": "ae8f7716-867b-4479-b455-c5769e7475ba"}}, item["topBids"])
+	bids := item["bids"].(map[string]interface{})
+	assert.Len(bids["bids"], 2)
+	assert.NotNil(bids["nextCursor"])
+
+	// THEN the portfolio of a user is fetched along with their bids
+	_, response = postGraphQL(t, api, "", `{ user(userUuid: "f475091b-a8f1-4679-83bd-483b616e5260") { bids { bids { amount } } portfolio { status leadingAmount item { itemUuid } } } }`, nil)
+	assert.Empty(response.Errors)
+	assert.Equal(map[string]interface{}{
+		"bids": map[string]interface{}{"bids": []interface{}{map[string]interface{}{"amount": 31.0}}},
+		"portfolio": []interface{}{map[string]interface{}{
+			"status": "OUTBID", "leadingAmount": 32.0, "item": map[string]interface{}{"itemUuid": itemUUID},
+		}},
+	}, response.Data["user"])
+
+	// THEN rejected bids and unknown items are reported as errors
+	_, response = postGraphQL(t, api, "", placeBid, map[string]interface{}{"item": "b16ab43e-aa13-4079-b8c5-592e81312c01", "user": "ae8f7716-867b-4479-b455-c5769e7475ba", "amount": 40})
+	if assert.Len(response.Errors, 1) {
+		assert.Contains(response.Errors[0].Message, "Failed to insert the bid")
+	}
+	_, response = postGraphQL(t, api, "", `{ item(itemUuid: "b16ab43e-aa13-4079-b8c5-592e81312c01") { title } }`, nil)
+	assert.Len(response.Errors, 1)
+	assert.Nil(response.Data["item"])
+
+	// THEN a user is required without an api key bound to one
+	_, response = postGraphQL(t, api, "", `{ user { portfolio { status } } }`, nil)
+	assert.Len(response.Errors, 1)
+
+	// THEN subscriptions need an event stream
+	_, response = postGraphQL(t, api, "", `subscription { leaderChanged(itemUuid: "b2f9ee6d-79fe-4b14-9c19-35a69a89219a") { leader } }`, nil)
+	if assert.Len(response.Errors, 1) {
+		assert.Contains(response.Errors[0].Message, "text/event-stream")
+	}
+
+	// THEN requests which are not GraphQL are rejected
+	status, response = postGraphQL(t, api, "", "", nil)
+	assert.Equal(fiber.StatusBadRequest, status)
+	assert.Len(response.Errors, 1)
+}
+
+func TestGraphQLPermissions(t *testing.T) {
+	assert := assert.New(t)
+	api, _ := newAPIWithKeys(t)
+
+	userUUID := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+	readToken, _, err := api.apiKeys.Create(apikey.Spec{Name: "reader", Role: string(RoleBidder), UserUUID: userUUID, Scopes: []apikey.Scope{apikey.ScopeBidsRead}})
+	assert.Nil(err)
+	bidderToken, _, err := api.apiKeys.Create(apikey.Spec{Name: "bidder", Role: string(RoleBidder), UserUUID: userUUID, Scopes: []apikey.Scope{apikey.ScopeBidsRead, apikey.ScopeBidsWrite}})
+	assert.Nil(err)
+
+	placeBid := `mutation { placeBid(itemUuid: "b2f9ee6d-79fe-4b14-9c19-35a69a89219a", amount: 10) { userUuid } }`
+
+	// WHEN the key of the caller can only read
+	_, response := postGraphQL(t, api, readToken, placeBid, nil)
+	if assert.Len(response.Errors, 1) {
+		assert.Equal("Permission denied, bids:create is required", response.Errors[0].Message)
+	}
+
+	// WHEN the key of the caller can place bids, they are placed for its user
+	_, response = postGraphQL(t, api, bidderToken, placeBid, nil)
+	assert.Empty(response.Errors)
+	assert.Equal(map[string]interface{}{"userUuid": userUUID.String()}, response.Data["placeBid"])
+
+	// THEN the caller finds its own bids
+	_, response = postGraphQL(t, api, readToken, `{ user { userUuid portfolio { status } } }`, nil)
+	assert.Empty(response.Errors)
+	assert.Equal(map[string]interface{}{
+		"userUuid":  userUUID.String(),
+		"portfolio": []interface{}{map[string]interface{}{"status": "WINNING"}},
+	}, response.Data["user"])
+
+	// THEN requests without an api key are refused before running
+	req := httptest.NewRequest("POST", "/api/v1/graphql", strings.NewReader(`{"query":"{ user { userUuid } }"}`))
+	resp, _ := api.server.Test(req)
+	assert.Equal(fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestGraphQLSubscription(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	metrics := NewMetrics()
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New(fiber.Config{DisableStartupMessage: true}))
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1"), RegisterWithMetrics(metrics)))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(err) {
+		return
+	}
+	go api.server.Listener(listener)
+
+	// WHEN a client subscribes to the leader changes of an item
+	body := `{"query":"subscription { leaderChanged(itemUuid: \"b2f9ee6d-79fe-4b14-9c19-35a69a89219a\") { leader winningBid { amount } } }"}`
+	req, _ := http.NewRequest("POST", "http://"+listener.Addr().String()+"/api/v1/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.Nil(err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	// The watch is registered asynchronously, bids are placed until the first event arrives
+	stop := make(chan struct{})
+	go func() {
+		for amount := 1.0; ; amount++ {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+				api.itemsBid.InsertBid(&bidtracker.Bid{ItemUUID: itemUUID, UserUUID: uuid.Must(uuid.NewV4()), Amount: amount})
+			}
+		}
+	}()
+
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data: ") {
+			events = append(events, strings.TrimPrefix(line, "data: "))
+			if len(events) == 1 {
+				close(stop)
+				// THEN the stream ends with the shutdown of the server
+				go func() {
+					ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+					defer cancel()
+					api.Shutdown(ctx)
+				}()
+			}
+		}
+		if line == "event: complete" {
+			break
+		}
+	}
+
+	// THEN every event is a leader change, and the last one tells why the stream stopped
+	if assert.Len(events, 2) {
+		first := graphQLTestResponse{}
+		assert.Nil(json.Unmarshal([]byte(events[0]), &first))
+		event := first.Data["leaderChanged"].(map[string]interface{})
+		assert.Equal(true, event["leader"])
+
+		last := graphQLTestResponse{}
+		assert.Nil(json.Unmarshal([]byte(events[1]), &last))
+		if assert.Len(last.Errors, 1) {
+			assert.Equal(bidtracker.ErrWatchStopped.Error(), last.Errors[0].Message)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// exportContentTypes is the content type of each export format
+var exportContentTypes = map[string]string{
+	bidtracker.FormatJSONL: "application/x-ndjson",
+	bidtracker.FormatCSV:   "text/csv",
+}
+
+// PostHandlerImportItems godoc
+// @Summary Import items in bulk
+// @Description Add the items of a csv or json lines body one row at a time. Invalid rows are reported and skipped, a dry run only reports.
+// @Description The csv header must have the itemuuid and title columns, it may have description, selleruuid, endtime, and categories, images and attributes (as key=value) separated by |.
+// @Tags Import
+// @Accept  plain
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param format query string false "jsonl (default) or csv"
+// @Param dry_run query bool false "only validate the rows"
+// @Success 200 {object} ResponseImportReport
+// @Failure 400 {object} Response
+// @Failure 429 {object} Response
+// @Router /import/items [post]
+// PostHandlerImportItems handles POST requests to import items in bulk
+func (api *API) PostHandlerImportItems(c *fiber.Ctx) error {
+	format, dryRun, err := parseImportQuery(c)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Invalid import query"))
+	}
+
+	reader, err := bidtracker.NewItemReader(requestBody(c), format)
+	if err != nil {
+		return SendError(c, err)
+	}
+	report, err := api.itemsBid.ImportItems(reader, dryRun)
+	return sendImportReport(c, report, err)
+}
+
+// PostHandlerImportBids godoc
+// @Summary Import historical bids in bulk
+// @Description Record the bids of a csv or json lines body one row at a time, as they were placed at their timestamp. Invalid rows are reported and skipped, a dry run only reports.
+// @Description The csv header must have the itemuuid, useruuid, timestamp and amount columns.
+// @Tags Import
+// @Accept  plain
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param format query string false "jsonl (default) or csv"
+// @Param dry_run query bool false "only validate the rows"
+// @Success 200 {object} ResponseImportReport
+// @Failure 400 {object} Response
+// @Failure 429 {object} Response
+// @Router /import/bids [post]
+// PostHandlerImportBids handles POST requests to import historical bids in bulk
+func (api *API) PostHandlerImportBids(c *fiber.Ctx) error {
+	format, dryRun, err := parseImportQuery(c)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Invalid import query"))
+	}
+
+	reader, err := bidtracker.NewBidReader(requestBody(c), format)
+	if err != nil {
+		return SendError(c, err)
+	}
+	report, err := api.itemsBid.ImportBids(reader, dryRun)
+	return sendImportReport(c, report, err)
+}
+
+// sendImportReport sends the report, the rows imported before a read error are kept and reported along with it
+func sendImportReport(c *fiber.Ctx, report bidtracker.ImportReport, err error) error {
+	if err != nil {
+		return sendError(c, err, report)
+	}
+	if report.DryRun {
+		return SendJSON(c, fiber.StatusOK, "Dry run", report)
+	}
+	return SendJSON(c, fiber.StatusOK, "Success", report)
+}
+
+// GetHandlerExportBids godoc
+// @Summary Export bids in bulk
+// @Description Stream every bid, or only the bids of an item, ordered by itemuuid and then in the order they were placed
+// @Tags Import
+// @Produce  plain
+// @Security ApiKeyAuth
+// @Param format query string false "jsonl (default) or csv"
+// @Param itemuuid query string false "only export the bids of this item"
+// @Success 200 {string} string
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 429 {object} Response
+// @Router /export/bids [get]
+// GetHandlerExportBids handles GET requests to export bids in bulk
+func (api *API) GetHandlerExportBids(c *fiber.Ctx) error {
+	format := c.Query("format", bidtracker.FormatJSONL)
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		err := invalidParam("format", errors.Errorf("format must be %s or %s", bidtracker.FormatJSONL, bidtracker.FormatCSV))
+		return SendError(c, errors.WithMessage(err, "Invalid export query"))
+	}
+	itemuuid, err := queryUUID(c, "itemuuid")
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Invalid export query"))
+	}
+	if !itemuuid.IsNil() {
+		if _, err := api.itemsBid.GetItem(itemuuid); err != nil {
+			return SendError(c, err)
+		}
+	}
+
+	// The body is written after the handler returns, once the status and headers are sent,
+	// so a failure half way can only be logged
+	logger := zerolog.Ctx(c.UserContext())
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="bids.`+format+`"`)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		writer, _ := bidtracker.NewBidWriter(w, format)
+		count, err := api.itemsBid.ExportBids(writer, itemuuid)
+		if err == nil {
+			err = writer.Flush()
+		}
+		if err != nil {
+			logger.Error().Err(err).Int("bids", count).Msg("Failed to export the bids")
+		}
+	})
+	return nil
+}
+
+// requestBody reads the body as it is received when the server streams request bodies
+func requestBody(c *fiber.Ctx) io.Reader {
+	if stream := c.Context().RequestBodyStream(); stream != nil {
+		return stream
+	}
+	return bytes.NewReader(c.Body())
+}
@@ -0,0 +1,114 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportAndExport(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := "b2f9ee6d-79fe-4b14-9c19-35a69a89219a"
+	userUUID := "ae8f7716-867b-4479-b455-c5769e7475ba"
+
+	api := NewAPI()
+	api.itemsBid = bidtracker.NewBidManagement()
+	api.server = fiber.New(fiber.Config{StreamRequestBody: true})
+	api.server.Post(URLImportItems, api.PostHandlerImportItems)
+	api.server.Post(URLImportBids, api.PostHandlerImportBids)
+	api.server.Get(URLExportBids, api.GetHandlerExportBids)
+
+	items := "itemuuid,title,categories\n" + itemUUID + ",Camera,photo|vintage\nnot-a-uuid,Broken,\n"
+
+	// WHEN
+	resp, _ := api.server.Test(httptest.NewRequest("POST", "/import/items?format=csv&dry_run=true", strings.NewReader(items)))
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	report := new(ResponseImportReport)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(report))
+	assert.True(report.Data.DryRun)
+	assert.Equal(1, report.Data.Imported)
+	if assert.Len(report.Data.Errors, 1) {
+		assert.Equal(3, report.Data.Errors[0].Line)
+	}
+	_, err := api.itemsBid.GetItem(uuid.FromStringOrNil(itemUUID))
+	assert.NotNil(err)
+
+	// WHEN
+	resp, _ = api.server.Test(httptest.NewRequest("POST", "/import/items?format=csv", strings.NewReader(items)))
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	_, err = api.itemsBid.GetItem(uuid.FromStringOrNil(itemUUID))
+	assert.Nil(err)
+
+	bids := `{"itemuuid":"` + itemUUID + `","useruuid":"` + userUUID + `","timestamp":10,"amount":5}` + "\n" +
+		`{"itemuuid":"` + itemUUID + `","useruuid":"` + userUUID + `","timestamp":20,"amount":7}` + "\n"
+
+	// WHEN
+	resp, _ = api.server.Test(httptest.NewRequest("POST", "/import/bids", strings.NewReader(bids)))
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	report = new(ResponseImportReport)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(report))
+	assert.Equal(2, report.Data.Imported)
+	assert.Equal(0, report.Data.Failed)
+
+	// WHEN
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/export/bids", nil))
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	assert.Equal("application/x-ndjson", resp.Header.Get(fiber.HeaderContentType))
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(bids, string(body))
+
+	// WHEN
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/export/bids?format=csv&itemuuid="+itemUUID, nil))
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	assert.Equal("text/csv", resp.Header.Get(fiber.HeaderContentType))
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal("itemuuid,useruuid,timestamp,amount\n"+itemUUID+","+userUUID+",10,5\n"+itemUUID+","+userUUID+",20,7\n", string(body))
+
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/export/bids?format=xml", nil))
+	assert.Equal(fiber.StatusBadRequest, resp.StatusCode)
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/export/bids?itemuuid=cef31b6b-cdeb-4035-8d42-a4f33b2d02fe", nil))
+	assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+	resp, _ = api.server.Test(httptest.NewRequest("POST", "/import/bids?dry_run=maybe", strings.NewReader(bids)))
+	assert.Equal(fiber.StatusBadRequest, resp.StatusCode)
+	resp, _ = api.server.Test(httptest.NewRequest("POST", "/import/bids?format=csv", strings.NewReader("itemuuid\n")))
+	assert.Equal(fiber.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,217 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// GetHandlerItems godoc
+// @Summary List items
+// @Description List the item catalogue ordered by title, optionally only the items of a seller or in a category
+// @Tags Items
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param selleruuid query string false "only items of this seller"
+// @Param category query string false "only items in this category"
+// @Success 200 {object} ResponseItems
+// @Failure 400 {object} Response
+// @Failure 429 {object} Response
+// @Router /items [get]
+// GetHandlerItems handles GET requests to list the item catalogue
+func (api *API) GetHandlerItems(c *fiber.Ctx) error {
+	selleruuid, err := queryUUID(c, "selleruuid")
+	if err != nil {
+		return SendError(c, err)
+	}
+	return SendJSON(c, fiber.StatusOK, "Success", api.itemsBid.ListItems(selleruuid, c.Query("category")))
+}
+
+// GetHandlerItemSearch godoc
+// @Summary Search items
+// @Description Full text search over item titles and descriptions, narrowed down by category, status and price range.
+// @Description Facet counts cover every item matching the text, before the facets are applied.
+// @Tags Items
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param q query string false "words every matching title or description contains"
+// @Param category query string false "comma separated categories, items in any of them match"
+// @Param status query string false "open, closing_soon or closed"
+// @Param min_price query number false "lowest current price, inclusive"
+// @Param max_price query number false "highest current price, inclusive"
+// @Param sort query string false "relevance, ending_soonest, most_bids or highest_price"
+// @Param limit query int false "page size, 20 by default and at most 100"
+// @Param offset query int false "number of results to skip"
+// @Success 200 {object} ResponseItemSearch
+// @Failure 400 {object} Response
+// @Failure 429 {object} Response
+// @Router /items/search [get]
+// GetHandlerItemSearch handles GET requests to search the item catalogue
+func (api *API) GetHandlerItemSearch(c *fiber.Ctx) error {
+	search, err := parseItemSearch(c)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Invalid search"))
+	}
+
+	result, err := api.itemsBid.SearchItems(search)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to search the items"))
+	}
+	return SendJSON(c, fiber.StatusOK, "Success", result)
+}
+
+// GetHandlerItem godoc
+// @Summary Get an item
+// @Description Get the catalogue metadata of an item
+// @Tags Items
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param itemuuid path string true "itemuuid"
+// @Success 200 {object} ResponseItem
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 429 {object} Response
+// @Router /items/{itemuuid} [get]
+// GetHandlerItem handles GET requests for the metadata of an item
+func (api *API) GetHandlerItem(c *fiber.Ctx) error {
+
+	var itemuuid uuid.UUID
+	var err error
+
+	if itemuuid, err = paramUUID(c, "itemuuid"); err != nil {
+		return SendError(c, err)
+	}
+
+	item, err := api.itemsBid.GetItem(itemuuid)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to fetch the item"))
+	}
+	return SendJSON(c, fiber.StatusOK, "Success", item)
+}
+
+// PostHandlerItemNew godoc
+// @Summary Create an item
+// @Description Put a new item up for auction. A uuid is generated if none is provided, and sellers default to their own uuid.
+// @Tags Items
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param  Item body bidtracker.Item true  "Item"
+// @Success 201 {object} ResponseItem
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 409 {object} Response
+// @Failure 429 {object} Response
+// @Router /items [post]
+// PostHandlerItemNew handles POST requests to create new items
+func (api *API) PostHandlerItemNew(c *fiber.Ctx) error {
+	item := new(bidtracker.Item)
+	if err := c.BodyParser(item); err != nil {
+		msg := errors.WithMessage(err, "json body can not be parsed successfully").Error()
+		return SendJSON(c, fiber.StatusBadRequest, msg, EmptyResponse)
+	}
+
+	if item.UUID == uuid.Nil {
+		item.UUID = uuid.Must(uuid.NewV4())
+	}
+	if item.SellerUUID == uuid.Nil {
+		item.SellerUUID = callerUUID(c)
+	}
+	if !api.actsFor(c, item.SellerUUID) {
+		return sendForbidden(c, PermissionItemsModerate)
+	}
+
+	if err := item.Validate(); err != nil {
+		return SendError(c, errors.WithMessage(err, "Invalid item"))
+	}
+
+	if err := api.itemsBid.AddItem(*item); err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to create the item"))
+	}
+	return SendJSON(c, fiber.StatusCreated, "Created the item", *item)
+}
+
+// PutHandlerItem godoc
+// @Summary Update an item
+// @Description Replace the catalogue metadata of an item, its bids are kept. Only moderators change the end of an auction that has bids or has closed.
+// @Tags Items
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param itemuuid path string true "itemuuid"
+// @Param  Item body bidtracker.Item true  "Item"
+// @Success 200 {object} ResponseItem
+// @Failure 400 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Failure 422 {object} Response
+// @Failure 429 {object} Response
+// @Router /items/{itemuuid} [put]
+// PutHandlerItem handles PUT requests to update items
+func (api *API) PutHandlerItem(c *fiber.Ctx) error {
+
+	var itemuuid uuid.UUID
+	var err error
+
+	if itemuuid, err = paramUUID(c, "itemuuid"); err != nil {
+		return SendError(c, err)
+	}
+
+	item := new(bidtracker.Item)
+	if err := c.BodyParser(item); err != nil {
+		msg := errors.WithMessage(err, "json body can not be parsed successfully").Error()
+		return SendJSON(c, fiber.StatusBadRequest, msg, EmptyResponse)
+	}
+	item.UUID = itemuuid
+
+	existing, err := api.itemsBid.GetItem(itemuuid)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to update the item"))
+	}
+	if item.SellerUUID == uuid.Nil {
+		item.SellerUUID = existing.SellerUUID
+	}
+	// Both the current and the new seller must be manageable, so items can not be handed over to someone else
+	if !api.actsFor(c, existing.SellerUUID) || !api.actsFor(c, item.SellerUUID) {
+		return sendForbidden(c, PermissionItemsModerate)
+	}
+
+	if err := item.Validate(); err != nil {
+		return SendError(c, errors.WithMessage(err, "Invalid item"))
+	}
+
+	// Only moderators change the end of an auction that has bids or has closed
+	update := api.itemsBid.UpdateItem
+	if api.moderates(c) {
+		update = api.itemsBid.ModerateItem
+	}
+	if err := update(*item); err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to update the item"))
+	}
+	return SendJSON(c, fiber.StatusOK, "Updated the item", *item)
+}
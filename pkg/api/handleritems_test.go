@@ -0,0 +1,198 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemCatalogue(t *testing.T) {
+	assert := assert.New(t)
+	api, adminToken := newAPIWithKeys(t)
+
+	seller := uuid.Must(uuid.FromString("8f2f2a79-9091-44fb-9fe3-3eb5f0d76746"))
+	sellerToken, _, err := api.apiKeys.Create(apikey.Spec{
+		Name:     "seller",
+		Role:     string(RoleSeller),
+		UserUUID: seller,
+		Scopes:   []apikey.Scope{apikey.ScopeBidsRead, apikey.ScopeItemsWrite},
+	})
+	assert.Nil(err)
+	otherToken, _, err := api.apiKeys.Create(apikey.Spec{
+		Name:     "other seller",
+		Role:     string(RoleSeller),
+		UserUUID: uuid.Must(uuid.NewV4()),
+		Scopes:   []apikey.Scope{apikey.ScopeItemsWrite},
+	})
+	assert.Nil(err)
+
+	send := func(method, url, token, body string) (int, *ResponseItem) {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add(APIKeyHeader, token)
+		resp, _ := api.server.Test(req)
+		response := new(ResponseItem)
+		json.NewDecoder(resp.Body).Decode(response)
+		return resp.StatusCode, response
+	}
+
+	// WHEN a seller creates an item
+	status, created := send("POST", "/api/v1/items", sellerToken, `{"title":"Vintage camera","categories":["photography"],"images":["https://images.example.com/camera.jpg"],"attributes":{"condition":"used"}}`)
+	assert.Equal(fiber.StatusCreated, status)
+	assert.NotEqual(uuid.Nil, created.Data.UUID)
+	assert.Equal(seller, created.Data.SellerUUID, "The seller defaults to the caller")
+
+	// THEN it shows up in the catalogue
+	status, got := send("GET", "/api/v1/items/"+created.Data.UUID.String(), sellerToken, "")
+	assert.Equal(fiber.StatusOK, status)
+	assert.Equal("Vintage camera", got.Data.Title)
+	assert.Equal(map[string]string{"condition": "used"}, got.Data.Attributes)
+
+	// THEN another seller can neither update it nor create items for someone else
+	status, _ = send("PUT", "/api/v1/items/"+created.Data.UUID.String(), otherToken, `{"title":"Stolen camera"}`)
+	assert.Equal(fiber.StatusForbidden, status)
+	status, _ = send("POST", "/api/v1/items", otherToken, `{"title":"Fake","selleruuid":"`+seller.String()+`"}`)
+	assert.Equal(fiber.StatusForbidden, status)
+
+	// THEN the owner and admins can update it
+	status, updated := send("PUT", "/api/v1/items/"+created.Data.UUID.String(), sellerToken, `{"title":"Vintage camera","description":"Works fine"}`)
+	assert.Equal(fiber.StatusOK, status)
+	assert.Equal(seller, updated.Data.SellerUUID)
+	status, _ = send("PUT", "/api/v1/items/"+created.Data.UUID.String(), adminToken, `{"title":"Vintage camera, moderated","categories":["photography"]}`)
+	assert.Equal(fiber.StatusOK, status)
+
+	status, _ = send("POST", "/api/v1/items", sellerToken, `{"title":""}`)
+	assert.Equal(fiber.StatusBadRequest, status)
+	status, _ = send("GET", "/api/v1/items/cef31b6b-cdeb-4035-8d42-a4f33b2d02fe", sellerToken, "")
+	assert.Equal(fiber.StatusNotFound, status)
+
+	req := httptest.NewRequest("GET", "/api/v1/items?category=photography", nil)
+	req.Header.Add(APIKeyHeader, sellerToken)
+	resp, _ := api.server.Test(req)
+	items := new(ResponseItems)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(items))
+	if assert.Len(items.Data, 1) {
+		assert.Equal("Vintage camera, moderated", items.Data[0].Title)
+	}
+}
+
+func TestGetHandlerItemSearch(t *testing.T) {
+	assert := assert.New(t)
+	api, adminToken := newAPIWithKeys(t)
+
+	for _, body := range []string{
+		`{"title":"Vintage camera","description":"Film camera","categories":["photography"]}`,
+		`{"title":"Leather chair","description":"A vintage armchair","categories":["furniture"]}`,
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/items", bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add(APIKeyHeader, adminToken)
+		resp, _ := api.server.Test(req)
+		assert.Equal(fiber.StatusCreated, resp.StatusCode)
+	}
+
+	search := func(query string) (int, *ResponseItemSearch) {
+		req := httptest.NewRequest("GET", "/api/v1/items/search"+query, nil)
+		req.Header.Add(APIKeyHeader, adminToken)
+		resp, _ := api.server.Test(req)
+		response := new(ResponseItemSearch)
+		json.NewDecoder(resp.Body).Decode(response)
+		return resp.StatusCode, response
+	}
+
+	status, result := search("?q=vintage")
+	assert.Equal(fiber.StatusOK, status)
+	assert.Equal(2, result.Data.Total)
+	assert.Equal("Vintage camera", result.Data.Items[0].Title, "Title matches rank first")
+	assert.Equal(1, result.Data.Facets.Categories["furniture"])
+
+	status, result = search("?q=vintage&category=furniture&status=open")
+	assert.Equal(fiber.StatusOK, status)
+	if assert.Len(result.Data.Items, 1) {
+		assert.Equal("Leather chair", result.Data.Items[0].Title)
+	}
+
+	status, _ = search("?sort=cheapest")
+	assert.Equal(fiber.StatusBadRequest, status)
+	status, _ = search("?min_price=ten")
+	assert.Equal(fiber.StatusBadRequest, status)
+}
+
+func TestPutHandlerItemEndTime(t *testing.T) {
+	assert := assert.New(t)
+	api, adminToken := newAPIWithKeys(t)
+
+	seller := uuid.Must(uuid.FromString("8f2f2a79-9091-44fb-9fe3-3eb5f0d76746"))
+	sellerToken, _, err := api.apiKeys.Create(apikey.Spec{
+		Name:     "seller",
+		Role:     string(RoleSeller),
+		UserUUID: seller,
+		Scopes:   []apikey.Scope{apikey.ScopeBidsRead, apikey.ScopeItemsWrite},
+	})
+	assert.Nil(err)
+
+	send := func(method, url, token, body string) (int, *ResponseItem) {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add(APIKeyHeader, token)
+		resp, _ := api.server.Test(req)
+		response := new(ResponseItem)
+		json.NewDecoder(resp.Body).Decode(response)
+		return resp.StatusCode, response
+	}
+
+	end := time.Now().Add(time.Hour).Unix()
+	status, created := send("POST", "/api/v1/items", sellerToken, fmt.Sprintf(`{"title":"Vintage camera","endtime":%d}`, end))
+	assert.Equal(fiber.StatusCreated, status)
+	itemURL := "/api/v1/items/" + created.Data.UUID.String()
+
+	// WHEN the item has no bids yet, the seller can move its end
+	end += 60
+	status, _ = send("PUT", itemURL, sellerToken, fmt.Sprintf(`{"title":"Vintage camera","endtime":%d}`, end))
+	assert.Equal(fiber.StatusOK, status)
+
+	// WHEN the item has bids, the seller can neither end the auction early nor extend it
+	status, _ = send("POST", "/api/v1/bids", adminToken, `{"itemuuid":"`+created.Data.UUID.String()+`","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":10}`)
+	assert.Equal(fiber.StatusOK, status)
+	for _, endTime := range []int64{time.Now().Unix() - 1, end + 60, 0} {
+		status, _ = send("PUT", itemURL, sellerToken, fmt.Sprintf(`{"title":"Vintage camera","endtime":%d}`, endTime))
+		assert.Equal(fiber.StatusUnprocessableEntity, status)
+	}
+
+	// THEN the rest of the item can still be updated by the seller, and the end by an admin, who may moderate
+	status, updated := send("PUT", itemURL, sellerToken, fmt.Sprintf(`{"title":"Vintage camera","description":"Works fine","endtime":%d}`, end))
+	assert.Equal(fiber.StatusOK, status)
+	assert.Equal(end, updated.Data.EndTime)
+	status, updated = send("PUT", itemURL, adminToken, fmt.Sprintf(`{"title":"Vintage camera","endtime":%d}`, end+60))
+	assert.Equal(fiber.StatusOK, status)
+	assert.Equal(end+60, updated.Data.EndTime)
+}
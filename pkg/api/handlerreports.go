@@ -0,0 +1,67 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+// GetHandlerShillReport godoc
+// @Summary Report suspicious bidding
+// @Description Flag users showing shill bidding or collusion patterns: bidding up one seller's items without winning, bursts of bids from new users and pairs of users taking turns
+// @Tags Admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Router /admin/reports/shill [get]
+// GetHandlerShillReport handles GET requests for the shill bidding report
+func (api *API) GetHandlerShillReport(c *fiber.Ctx) error {
+	return SendJSON(c, fiber.StatusOK, "Success", api.itemsBid.ShillReport())
+}
+
+// DeleteHandlerShillFlags godoc
+// @Summary Clear the shill bidding flags of a user
+// @Description Clear a user found to be flagged by mistake: their bids are accepted again even when blocking is enabled, and the report leaves them out
+// @Tags Admin
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param useruuid path string true "useruuid"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response
+// @Failure 401 {object} Response
+// @Failure 403 {object} Response
+// @Failure 404 {object} Response
+// @Router /admin/reports/shill/{useruuid} [delete]
+// DeleteHandlerShillFlags handles DELETE requests to clear the shill bidding flags of a user
+func (api *API) DeleteHandlerShillFlags(c *fiber.Ctx) error {
+	useruuid, err := paramUUID(c, "useruuid")
+	if err != nil {
+		return SendError(c, err)
+	}
+	if err := api.itemsBid.ClearShillFlags(useruuid); err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to clear the shill flags"))
+	}
+	return SendJSON(c, fiber.StatusOK, "Cleared the shill flags", EmptyResponse)
+}
@@ -0,0 +1,111 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type responseShillReport struct {
+	Status  int
+	Message string
+	Data    []bidtracker.Suspicion
+}
+
+func TestGetHandlerShillReport(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	userA := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+	userB := uuid.Must(uuid.FromString("f475091b-a8f1-4679-83bd-483b616e5260"))
+
+	api := NewAPI()
+	api.itemsBid = bidtracker.NewBidManagement(itemUUID)
+	api.itemsBid.SetShillDetector(bidtracker.NewShillDetector(bidtracker.ShillDetectorConfig{AlternationMinBids: 4}))
+	api.server = fiber.New()
+	api.server.Get(URLAdminShillReport, api.GetHandlerShillReport)
+
+	for i, user := range []uuid.UUID{userA, userB, userA, userB} {
+		assert.Nil(api.itemsBid.InsertBid(&bidtracker.Bid{ItemUUID: itemUUID, UserUUID: user, Timestamp: int64(i), Amount: float64(i + 1)}))
+	}
+
+	// WHEN
+	resp, _ := api.server.Test(httptest.NewRequest("GET", "/admin/reports/shill", nil))
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	response := new(responseShillReport)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(response))
+	if assert.Len(response.Data, 1) {
+		assert.Equal(bidtracker.SuspicionAlternatingPair, response.Data[0].Kind)
+		assert.ElementsMatch([]uuid.UUID{userA, userB}, response.Data[0].Users)
+	}
+}
+
+func TestDeleteHandlerShillFlags(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	userA := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+	userB := uuid.Must(uuid.FromString("f475091b-a8f1-4679-83bd-483b616e5260"))
+
+	api := NewAPI()
+	api.itemsBid = bidtracker.NewBidManagement(itemUUID)
+	api.itemsBid.SetShillDetector(bidtracker.NewShillDetector(bidtracker.ShillDetectorConfig{AlternationMinBids: 4, Block: true}))
+	api.server = fiber.New()
+	api.server.Post(URLBidItem, api.PostHandlerBidNew)
+	api.server.Delete(URLAdminShillFlags, api.DeleteHandlerShillFlags)
+
+	for i, user := range []uuid.UUID{userA, userB, userA, userB} {
+		assert.Nil(api.itemsBid.InsertBid(&bidtracker.Bid{ItemUUID: itemUUID, UserUUID: user, Timestamp: int64(i), Amount: float64(i + 1)}))
+	}
+	bid := func() int {
+		body := fmt.Sprintf(`{"itemuuid": "%s", "useruuid": "%s", "amount": 10}`, itemUUID, userA)
+		req := httptest.NewRequest("POST", "/bids", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := api.server.Test(req)
+		return resp.StatusCode
+	}
+	assert.Equal(fiber.StatusUnprocessableEntity, bid())
+
+	// WHEN the user is unknown or the uuid is invalid
+	resp, _ := api.server.Test(httptest.NewRequest("DELETE", "/admin/reports/shill/"+uuid.Must(uuid.NewV4()).String(), nil))
+	assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+	resp, _ = api.server.Test(httptest.NewRequest("DELETE", "/admin/reports/shill/nope", nil))
+	assert.Equal(fiber.StatusBadRequest, resp.StatusCode)
+
+	// WHEN a moderator clears the flags of userA
+	resp, _ = api.server.Test(httptest.NewRequest("DELETE", "/admin/reports/shill/"+userA.String(), nil))
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+
+	// THEN the bids of userA are accepted again
+	assert.Equal(fiber.StatusOK, bid())
+}
@@ -33,10 +33,21 @@ import (
 // @Tags User
 // @Accept  json
 // @Produce  json
+// @Security ApiKeyAuth
 // @Param useruuid path string true "useruuid"
+// @Param limit query int false "page size, 100 by default"
+// @Param cursor query string false "NextCursor of the previous page"
+// @Param sort query string false "sort field" Enums(time, amount)
+// @Param order query string false "sort order" Enums(asc, desc)
+// @Param min_amount query number false "minimum amount"
+// @Param max_amount query number false "maximum amount"
+// @Param from query int false "minimum timestamp"
+// @Param to query int false "maximum timestamp"
+// @Param itemuuid query string false "only bids on this item"
 // @Success 200 {object} ResponseGetBids
 // @Failure 400 {object} Response
-// @Failure 500 {object} Response
+// @Failure 404 {object} Response
+// @Failure 429 {object} Response
 // @Router /users/{useruuid}/bids [get]
 // GetHandlerUserBidGetAll handles GET request to get all the bids of a user
 func (api *API) GetHandlerUserBidGetAll(c *fiber.Ctx) error {
@@ -44,15 +55,48 @@ func (api *API) GetHandlerUserBidGetAll(c *fiber.Ctx) error {
 	var useruuid uuid.UUID
 	var err error
 
-	if useruuid, err = uuid.FromString(c.Params("useruuid")); err != nil {
-		msg := errors.WithMessage(err, "useruuid can not be parsed successfully").Error()
-		return SendJSON(c, fiber.StatusBadRequest, msg, EmptyResponse)
+	if useruuid, err = paramUUID(c, "useruuid"); err != nil {
+		return SendError(c, err)
 	}
 
-	bids, err := api.itemsBid.GetBidsByUser(useruuid)
+	query, err := parseBidQuery(c)
 	if err != nil {
-		msg := errors.WithMessage(err, "Failed to fetch the bids for given useruuid").Error()
-		return SendJSON(c, fiber.StatusInternalServerError, msg, EmptyResponse)
+		return SendError(c, errors.WithMessage(err, "Invalid query"))
 	}
-	return SendJSON(c, fiber.StatusOK, "Success", bids)
+
+	page, err := api.itemsBid.ListBidsByUser(useruuid, query)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to fetch the bids for given useruuid"))
+	}
+	return SendJSON(c, fiber.StatusOK, "Success", page)
+}
+
+// GetHandlerUserPortfolio godoc
+// @Summary Get the portfolio of a user
+// @Description Get the standing of a user on every item they bid on: their highest bid, the leading amount, whether they are winning, outbid, won or lost, and when the auction ends
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param useruuid path string true "useruuid"
+// @Success 200 {object} ResponsePortfolio
+// @Failure 400 {object} Response
+// @Failure 404 {object} Response
+// @Failure 429 {object} Response
+// @Router /users/{useruuid}/portfolio [get]
+// GetHandlerUserPortfolio handles GET request to get the portfolio of a user
+func (api *API) GetHandlerUserPortfolio(c *fiber.Ctx) error {
+
+	var useruuid uuid.UUID
+	var err error
+
+	if useruuid, err = paramUUID(c, "useruuid"); err != nil {
+		return SendError(c, err)
+	}
+
+	portfolio, err := api.itemsBid.GetPortfolio(useruuid)
+	if err != nil {
+		return SendError(c, errors.WithMessage(err, "Failed to fetch the portfolio for given useruuid"))
+	}
+	return SendJSON(c, fiber.StatusOK, "Success", portfolio)
 }
@@ -115,3 +115,47 @@ func TestGetHandlerUserBidGetAll(t *testing.T) {
 		assert.Fail(fmt.Sprintf("Failed response from the server %d. %s", resp.StatusCode, string(body)))
 	}
 }
+
+type responsePortfolio struct {
+	Status  int
+	Message string
+	Data    []bidtracker.PortfolioEntry
+}
+
+func TestGetHandlerUserPortfolio(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	api := NewAPI()
+	api.itemsBid = bidtracker.NewBidManagement(itemUUID)
+	api.server = fiber.New()
+
+	api.server.Post(URLBidItem, api.PostHandlerBidNew)
+	api.server.Get(URLUserGetPortfolio, api.GetHandlerUserPortfolio)
+
+	for _, jsonData := range []string{
+		`{"useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","timestamp":1351807721,"amount":30}`,
+		`{"useruuid":"f475091b-a8f1-4679-83bd-483b616e5260","itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","timestamp":1351807722,"amount":31}`,
+	} {
+		req := httptest.NewRequest("POST", "/bids", bytes.NewBuffer([]byte(jsonData)))
+		req.Header.Add("Content-Type", "application/json")
+		api.server.Test(req)
+	}
+
+	// WHEN
+	resp, _ := api.server.Test(httptest.NewRequest("GET", "/users/ae8f7716-867b-4479-b455-c5769e7475ba/portfolio", nil))
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	response := new(responsePortfolio)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(response))
+	if assert.Len(response.Data, 1) {
+		assert.Equal(itemUUID, response.Data[0].ItemUUID)
+		assert.Equal(30.0, response.Data[0].HighestBid.Amount)
+		assert.Equal(31.0, response.Data[0].LeadingAmount)
+		assert.Equal(bidtracker.StatusOutbid, response.Data[0].Status)
+	}
+
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/users/cef31b6b-cdeb-4035-8d42-a4f33b2d02fe/portfolio", nil))
+	assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,186 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+// ReadinessCheckTimeout bounds the time a single readiness check may take
+const ReadinessCheckTimeout = 2 * time.Second
+
+// ReadinessCheck reports whether a dependency of the server is ready to serve, nil means ready
+type ReadinessCheck func(ctx context.Context) error
+
+// Health tells the orchestrator whether the server is alive and ready to take traffic.
+// It is ready once recovery is complete, while it is not draining and every registered check passes.
+type Health struct {
+	mu     sync.Mutex
+	checks map[string]ReadinessCheck
+
+	recovered atomic.Bool
+	draining  atomic.Bool
+}
+
+// NewHealth returns a Health waiting for recovery to complete
+func NewHealth() *Health {
+	return &Health{checks: make(map[string]ReadinessCheck)}
+}
+
+// AddCheck registers a readiness check under name, e.g. by a storage backend. A check with the same name is replaced.
+func (h *Health) AddCheck(name string, check ReadinessCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks[name] = check
+}
+
+// SetRecovered marks the state of the tracker as restored
+func (h *Health) SetRecovered() {
+	h.recovered.Store(true)
+}
+
+// SetDraining makes the server report itself as not ready, because it is shutting down
+func (h *Health) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Readiness is the outcome of every readiness check, "ok" or the reason it failed
+type Readiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Check runs every readiness check concurrently
+func (h *Health) Check(ctx context.Context) Readiness {
+	h.mu.Lock()
+	checks := make(map[string]ReadinessCheck, len(h.checks)+2)
+	for name, check := range h.checks {
+		checks[name] = check
+	}
+	h.mu.Unlock()
+
+	checks["recovery"] = func(context.Context) error {
+		if !h.recovered.Load() {
+			return errors.New("recovery is in progress")
+		}
+		return nil
+	}
+	checks["draining"] = func(context.Context) error {
+		if h.draining.Load() {
+			return errors.New("the server is shutting down")
+		}
+		return nil
+	}
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, check ReadinessCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, ReadinessCheckTimeout)
+			defer cancel()
+			results[i] = check(checkCtx)
+		}(i, checks[name])
+	}
+	wg.Wait()
+
+	readiness := Readiness{Ready: true, Checks: make(map[string]string, len(names))}
+	for i, name := range names {
+		readiness.Checks[name] = "ok"
+		if results[i] != nil {
+			readiness.Ready = false
+			readiness.Checks[name] = results[i].Error()
+		}
+	}
+	return readiness
+}
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"buildtime"`
+	GoVersion string `json:"goversion"`
+}
+
+// NewBuildInfo fills in the go version of the running binary
+func NewBuildInfo(version, buildTime string) BuildInfo {
+	return BuildInfo{Version: version, BuildTime: buildTime, GoVersion: runtime.Version()}
+}
+
+// GetHandlerHealthz godoc
+// @Summary Liveness probe
+// @Description Report that the process is alive
+// @Tags Health
+// @Produce  json
+// @Success 200 {object} Response
+// @Router /healthz [get]
+// GetHandlerHealthz handles GET requests for the liveness probe
+func (h *Health) GetHandlerHealthz(c *fiber.Ctx) error {
+	return SendJSON(c, fiber.StatusOK, "Alive", EmptyResponse)
+}
+
+// GetHandlerReadyz godoc
+// @Summary Readiness probe
+// @Description Report whether recovery is complete, the server is not draining and every registered check, e.g. storage, passes
+// @Tags Health
+// @Produce  json
+// @Success 200 {object} ResponseReadiness
+// @Failure 503 {object} ResponseReadiness
+// @Router /readyz [get]
+// GetHandlerReadyz handles GET requests for the readiness probe
+func (h *Health) GetHandlerReadyz(c *fiber.Ctx) error {
+	readiness := h.Check(c.UserContext())
+	if !readiness.Ready {
+		return SendJSON(c, fiber.StatusServiceUnavailable, "Not ready", readiness)
+	}
+	return SendJSON(c, fiber.StatusOK, "Ready", readiness)
+}
+
+// GetHandlerVersion godoc
+// @Summary Build information
+// @Description Get the version and build time of the running binary
+// @Tags Health
+// @Produce  json
+// @Success 200 {object} ResponseBuildInfo
+// @Router /version [get]
+// GetHandlerVersion returns the handler for GET requests for the build information
+func GetHandlerVersion(info BuildInfo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return SendJSON(c, fiber.StatusOK, "Success", info)
+	}
+}
@@ -0,0 +1,88 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	assert := assert.New(t)
+
+	health := NewHealth()
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(), fiber.New())
+	assert.Nil(RegisterRoutes(api,
+		RegisterWithAPIVersion("/api/v1"),
+		RegisterWithAPIProxyPrefix("/prefix"),
+		RegisterWithHealth(health),
+		RegisterWithBuildInfo(NewBuildInfo("1.2.3", "2020-01-01T00:00:00Z")),
+	))
+
+	readyz := func() (int, Readiness) {
+		resp, _ := api.server.Test(httptest.NewRequest("GET", "/prefix/readyz", nil))
+		response := new(ResponseReadiness)
+		json.NewDecoder(resp.Body).Decode(response)
+		return resp.StatusCode, response.Data
+	}
+
+	resp, _ := api.server.Test(httptest.NewRequest("GET", "/prefix/healthz", nil))
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+
+	// WHEN recovery is not complete THEN the server is not ready
+	status, readiness := readyz()
+	assert.Equal(fiber.StatusServiceUnavailable, status)
+	assert.Equal("recovery is in progress", readiness.Checks["recovery"])
+
+	health.SetRecovered()
+	status, readiness = readyz()
+	assert.Equal(fiber.StatusOK, status)
+	assert.Equal(map[string]string{"recovery": "ok", "draining": "ok"}, readiness.Checks)
+
+	// WHEN a registered check fails THEN the server is not ready
+	storageErr := errors.New("disk full")
+	health.AddCheck("storage", func(ctx context.Context) error { return storageErr })
+	status, readiness = readyz()
+	assert.Equal(fiber.StatusServiceUnavailable, status)
+	assert.Equal("disk full", readiness.Checks["storage"])
+
+	storageErr = nil
+	health.SetDraining()
+	status, readiness = readyz()
+	assert.Equal(fiber.StatusServiceUnavailable, status)
+	assert.False(readiness.Ready)
+	assert.Equal("ok", readiness.Checks["storage"])
+
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/prefix/version", nil))
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	version := new(ResponseBuildInfo)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(version))
+	assert.Equal("1.2.3", version.Data.Version)
+	assert.NotEmpty(version.Data.GoVersion)
+}
@@ -0,0 +1,178 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// IdempotencyKeyHeader lets clients retry a POST safely: the response to the first request carrying
+	// a key is replayed to the later requests of the same client with the same key, instead of running them again
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotencyReplayedHeader is set to true on the responses replayed for a repeated idempotency key
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	// IdempotencyTTL is how long a response is kept for its idempotency key
+	IdempotencyTTL = 24 * time.Hour
+
+	// maxIdempotencyKeys bounds the responses kept, the oldest one is dropped to make room
+	maxIdempotencyKeys = 10000
+
+	// maxIdempotencyKeyLength bounds the length of the keys sent by clients
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotentResponse is the response to the first request with a key, done once it has been sent
+type idempotentResponse struct {
+	fingerprint [sha256.Size]byte
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyStore keeps the responses of the POST requests sent with an idempotency key
+type idempotencyStore struct {
+	sync.Mutex
+	responses map[string]*idempotentResponse
+	now       func() time.Time
+}
+
+func newIdempotencyStore() *idempotencyStore {
+	return &idempotencyStore{
+		responses: make(map[string]*idempotentResponse),
+		now:       time.Now,
+	}
+}
+
+// begin returns the response already recorded for scope, or records that a request with
+// the fingerprint is in progress and returns nil
+func (s *idempotencyStore) begin(scope string, fingerprint [sha256.Size]byte) *idempotentResponse {
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.now()
+	if response, ok := s.responses[scope]; ok && now.Before(response.expires) {
+		copied := *response
+		return &copied
+	}
+
+	if len(s.responses) >= maxIdempotencyKeys {
+		s.evict(now)
+	}
+	s.responses[scope] = &idempotentResponse{fingerprint: fingerprint, expires: now.Add(IdempotencyTTL)}
+	return nil
+}
+
+// finish records the response sent for scope
+func (s *idempotencyStore) finish(scope string, status int, contentType string, body []byte) {
+	s.Lock()
+	defer s.Unlock()
+
+	if response, ok := s.responses[scope]; ok {
+		response.done = true
+		response.status = status
+		response.contentType = contentType
+		response.body = body
+	}
+}
+
+// forget drops scope, so that the request can be retried
+func (s *idempotencyStore) forget(scope string) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.responses, scope)
+}
+
+// evict drops the expired responses, or the oldest one if none has expired. It must be called with the lock held.
+func (s *idempotencyStore) evict(now time.Time) {
+	oldest := ""
+	for scope, response := range s.responses {
+		if !now.Before(response.expires) {
+			delete(s.responses, scope)
+		} else if oldest == "" || response.expires.Before(s.responses[oldest].expires) {
+			oldest = scope
+		}
+	}
+	if len(s.responses) >= maxIdempotencyKeys {
+		delete(s.responses, oldest)
+	}
+}
+
+// idempotent returns a middleware replaying the response of r to requests repeating an idempotency key.
+// Requests without the header run as usual. Failures of the server and streamed responses are not kept,
+// so that they can be retried.
+func (s *idempotencyStore) idempotent(r route) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			msg := fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength)
+			return SendJSON(c, fiber.StatusBadRequest, msg, EmptyResponse)
+		}
+
+		scope := rateLimitClient(c) + " " + r.name() + " " + key
+		fingerprint := sha256.Sum256(append([]byte(c.OriginalURL()+"\n"), c.Body()...))
+		if response := s.begin(scope, fingerprint); response != nil {
+			switch {
+			case response.fingerprint != fingerprint:
+				msg := fmt.Sprintf("%s was already used for a different request", IdempotencyKeyHeader)
+				return SendJSON(c, fiber.StatusUnprocessableEntity, msg, EmptyResponse)
+			case !response.done:
+				msg := fmt.Sprintf("A request with the same %s is in progress", IdempotencyKeyHeader)
+				return SendJSON(c, fiber.StatusConflict, msg, EmptyResponse)
+			}
+			c.Set(IdempotencyReplayedHeader, "true")
+			c.Set(fiber.HeaderContentType, response.contentType)
+			return c.Status(response.status).Send(response.body)
+		}
+
+		// The key is released unless a response is recorded, even if the handler panics
+		recorded := false
+		defer func() {
+			if !recorded {
+				s.forget(scope)
+			}
+		}()
+
+		err := c.Next()
+		status := c.Response().StatusCode()
+		if err != nil || status >= fiber.StatusInternalServerError || c.Response().IsBodyStream() {
+			return err
+		}
+		body := append([]byte(nil), c.Response().Body()...)
+		s.finish(scope, status, string(c.Response().Header.ContentType()), body)
+		recorded = true
+		return nil
+	}
+}
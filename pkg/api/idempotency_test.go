@@ -0,0 +1,131 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New())
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1")))
+
+	postBid := func(key, body string) (int, string) {
+		req := httptest.NewRequest("POST", "/api/v1/bids", strings.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Add(IdempotencyKeyHeader, key)
+		}
+		resp, err := api.server.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, resp.Header.Get(IdempotencyReplayedHeader)
+	}
+	bid := `{"useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","timestamp":1351807721,"amount":30}`
+
+	// WHEN a bid is retried with the same key
+	status, replayed := postBid("first", bid)
+	assert.Equal(fiber.StatusOK, status)
+	assert.Equal("", replayed)
+	status, replayed = postBid("first", bid)
+	assert.Equal(fiber.StatusOK, status)
+	assert.Equal("true", replayed)
+
+	// THEN it is only placed once
+	bids, _ := api.itemsBid.GetBids(itemUUID)
+	assert.Len(bids, 1)
+
+	// WHEN the key is used for a different bid
+	status, _ = postBid("first", strings.Replace(bid, "30", "40", 1))
+	assert.Equal(fiber.StatusUnprocessableEntity, status)
+
+	// WHEN no key or another key is sent, the bids are placed
+	postBid("", strings.Replace(bid, "30", "50", 1))
+	postBid("second", strings.Replace(bid, "30", "60", 1))
+	bids, _ = api.itemsBid.GetBids(itemUUID)
+	assert.Len(bids, 3)
+
+	// THEN rejected requests are replayed as well, they would be rejected again
+	unknown := strings.Replace(bid, "b2f9ee6d", "00000000", 1)
+	status, _ = postBid("third", unknown)
+	assert.Equal(fiber.StatusNotFound, status)
+	status, replayed = postBid("third", unknown)
+	assert.Equal(fiber.StatusNotFound, status)
+	assert.Equal("true", replayed)
+
+	status, _ = postBid(strings.Repeat("k", maxIdempotencyKeyLength+1), bid)
+	assert.Equal(fiber.StatusBadRequest, status)
+}
+
+func TestIdempotencyKeyImports(t *testing.T) {
+	assert := assert.New(t)
+
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(), fiber.New(fiber.Config{StreamRequestBody: true, BodyLimit: 256}))
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1")))
+
+	// WHEN an import over the body limit is sent twice with the same key
+	items := "itemuuid,title\n" + strings.Repeat("cef31b6b-cdeb-4035-8d42-a4f33b2d02fe,Camera\n", 20)
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/api/v1/import/items?format=csv&dry_run=true", strings.NewReader(items))
+		req.Header.Add(IdempotencyKeyHeader, "import")
+		resp, err := api.server.Test(req)
+		if !assert.Nil(err) {
+			return
+		}
+
+		// THEN the streamed body is read whole every time, the response is not kept
+		assert.Equal(fiber.StatusOK, resp.StatusCode)
+		assert.Equal("", resp.Header.Get(IdempotencyReplayedHeader))
+		report := new(ResponseImportReport)
+		assert.Nil(json.NewDecoder(resp.Body).Decode(report))
+		assert.Equal(20, report.Data.Rows)
+	}
+}
+
+func TestIdempotencyStoreEvicts(t *testing.T) {
+	assert := assert.New(t)
+
+	store := newIdempotencyStore()
+	now := time.Unix(1351807721, 0)
+	store.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	var fingerprint [32]byte
+	for i := 0; i < maxIdempotencyKeys+10; i++ {
+		assert.Nil(store.begin(strings.Repeat("k", i+1), fingerprint))
+	}
+	assert.Len(store.responses, maxIdempotencyKeys)
+	assert.Nil(store.responses["k"], "the oldest key is dropped first")
+}
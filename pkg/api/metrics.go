@@ -0,0 +1,183 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "bidtracker"
+
+// Metrics collects the prometheus metrics of the API and of the tracker it serves.
+// It implements bidtracker.Observer to count bids and measure lock contention.
+type Metrics struct {
+	registry      *prometheus.Registry
+	requests      *prometheus.CounterVec
+	latency       *prometheus.HistogramVec
+	bidsAccepted  prometheus.Counter
+	bidsRejected  *prometheus.CounterVec
+	lockWait      prometheus.Histogram
+	subscriptions prometheus.Gauge
+}
+
+// NewMetrics returns metrics registered in their own registry, along with the go runtime and process metrics
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route and status code.",
+		}, []string{"method", "route", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests by route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		bidsAccepted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "bids_accepted_total",
+			Help:      "Number of bids accepted.",
+		}),
+		bidsRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "bids_rejected_total",
+			Help:      "Number of bids rejected by reason.",
+		}, []string{"reason"}),
+		lockWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "lock_wait_seconds",
+			Help:      "Time spent waiting for the lock of the bid tracker.",
+			Buckets:   prometheus.ExponentialBuckets(1e-6, 4, 10),
+		}),
+		subscriptions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "subscriptions_active",
+			Help:      "Number of open subscription connections.",
+		}),
+	}
+	m.registry.MustRegister(
+		m.requests, m.latency, m.bidsAccepted, m.bidsRejected, m.lockWait, m.subscriptions,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// BidAccepted implements bidtracker.Observer
+func (m *Metrics) BidAccepted(bid bidtracker.Bid) {
+	m.bidsAccepted.Inc()
+}
+
+// BidRejected implements bidtracker.Observer
+func (m *Metrics) BidRejected(bid bidtracker.Bid, reason bidtracker.RejectReason) {
+	m.bidsRejected.WithLabelValues(string(reason)).Inc()
+}
+
+// LockWaited implements bidtracker.Observer
+func (m *Metrics) LockWaited(wait time.Duration) {
+	m.lockWait.Observe(wait.Seconds())
+}
+
+// SubscriptionOpened counts a new subscription connection
+func (m *Metrics) SubscriptionOpened() {
+	m.subscriptions.Inc()
+}
+
+// SubscriptionClosed counts a subscription connection going away
+func (m *Metrics) SubscriptionClosed() {
+	m.subscriptions.Dec()
+}
+
+// observe makes the tracker report to m, and exports the number of items and bids it holds
+func (m *Metrics) observe(tracker *bidtracker.BidManagement) error {
+	tracker.SetObserver(m)
+
+	items := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "items",
+		Help:      "Number of items held by the bid tracker.",
+	}, func() float64 {
+		items, _ := tracker.Size()
+		return float64(items)
+	})
+	bids := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "bids",
+		Help:      "Number of bids held by the bid tracker.",
+	}, func() float64 {
+		_, bids := tracker.Size()
+		return float64(bids)
+	})
+	for _, collector := range []prometheus.Collector{items, bids} {
+		if err := m.registry.Register(collector); err != nil {
+			return errors.WithMessage(err, "Failed to register the bid tracker metrics")
+		}
+	}
+	return nil
+}
+
+// instrument counts the requests to r and measures their latency, including authorization and rate limiting
+func (m *Metrics) instrument(r route) fiber.Handler {
+	latency := m.latency.WithLabelValues(r.method, r.path)
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		latency.Observe(time.Since(start).Seconds())
+
+		m.requests.WithLabelValues(r.method, r.path, strconv.Itoa(responseStatus(c, err))).Inc()
+		return err
+	}
+}
+
+// responseStatus is the status code the client gets, once the error handler of fiber handled err
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	if e, ok := err.(*fiber.Error); ok {
+		return e.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// GetHandlerMetrics godoc
+// @Summary Prometheus metrics
+// @Description Expose the metrics of the service in the prometheus text format
+// @Tags Metrics
+// @Produce  plain
+// @Success 200 {string} string
+// @Router /metrics [get]
+// GetHandlerMetrics returns the handler for GET requests to scrape the metrics
+func (m *Metrics) GetHandlerMetrics() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
@@ -0,0 +1,67 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New())
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1"), RegisterWithMetrics(NewMetrics())))
+
+	for _, body := range []string{
+		`{"itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":10}`,
+		`{"itemuuid":"cef31b6b-cdeb-4035-8d42-a4f33b2d02fe","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":10}`,
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/bids", bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		api.server.Test(req)
+	}
+
+	// WHEN
+	resp, _ := api.server.Test(httptest.NewRequest("GET", "/metrics", nil))
+
+	// THEN
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	metrics := string(body)
+	assert.Contains(metrics, `bidtracker_http_requests_total{code="200",method="POST",route="/bids"} 1`)
+	assert.Contains(metrics, `bidtracker_http_request_duration_seconds_count{method="POST",route="/bids"} 2`)
+	assert.Contains(metrics, `bidtracker_bids_accepted_total 1`)
+	assert.Contains(metrics, `bidtracker_bids_rejected_total{reason="unknown_item"} 1`)
+	assert.Contains(metrics, `bidtracker_items 1`)
+	assert.Contains(metrics, `bidtracker_bids 1`)
+	assert.Contains(metrics, `bidtracker_lock_wait_seconds_count`)
+	assert.Contains(metrics, `bidtracker_subscriptions_active 0`)
+}
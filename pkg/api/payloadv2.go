@@ -0,0 +1,303 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofrs/uuid"
+)
+
+// The v2 payloads mirror the v1 ones with camelCase keys. Requests are decoded case insensitively,
+// so both casings are accepted in request bodies.
+
+// BidV2 is a bidtracker.Bid in a ResponseV2
+type BidV2 struct {
+	ItemUUID  uuid.UUID `json:"itemUuid"`
+	UserUUID  uuid.UUID `json:"userUuid"`
+	Timestamp int64     `json:"timestamp"`
+	Amount    float64   `json:"amount"`
+}
+
+// BidResultV2 is a bidtracker.BidResult in a ResponseV2
+type BidResultV2 struct {
+	Bid      BidV2                   `json:"bid"`
+	Accepted bool                    `json:"accepted"`
+	Reason   bidtracker.RejectReason `json:"reason,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+	Leader   bool                    `json:"leader"`
+}
+
+// BidBatchResultV2 is a bidtracker.BidBatchResult in a ResponseV2
+type BidBatchResultV2 struct {
+	Accepted int           `json:"accepted"`
+	Rejected int           `json:"rejected"`
+	Results  []BidResultV2 `json:"results"`
+}
+
+// ItemV2 is a bidtracker.Item in a ResponseV2
+type ItemV2 struct {
+	UUID        uuid.UUID         `json:"itemUuid"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Categories  []string          `json:"categories"`
+	SellerUUID  uuid.UUID         `json:"sellerUuid"`
+	Images      []string          `json:"images"`
+	Attributes  map[string]string `json:"attributes"`
+	EndTime     int64             `json:"endTime"`
+}
+
+// ItemSummaryV2 is a bidtracker.ItemSummary in a ResponseV2
+type ItemSummaryV2 struct {
+	ItemV2
+	Status       bidtracker.ItemStatus `json:"status"`
+	BidCount     int                   `json:"bidCount"`
+	CurrentPrice float64               `json:"currentPrice"`
+}
+
+// ItemFacetsV2 is a bidtracker.ItemFacets in a ResponseV2
+type ItemFacetsV2 struct {
+	Categories  map[string]int                `json:"categories"`
+	Status      map[bidtracker.ItemStatus]int `json:"status"`
+	PriceRanges []bidtracker.PriceRangeFacet  `json:"priceRanges"`
+}
+
+// ItemSearchResultV2 is a bidtracker.ItemSearchResult in a ResponseV2
+type ItemSearchResultV2 struct {
+	Items  []ItemSummaryV2 `json:"items"`
+	Total  int             `json:"total"`
+	Facets ItemFacetsV2    `json:"facets"`
+}
+
+// LeaderboardEntryV2 is a bidtracker.LeaderboardEntry in a ResponseV2
+type LeaderboardEntryV2 struct {
+	Rank     int       `json:"rank"`
+	UserUUID uuid.UUID `json:"userUuid"`
+	BestBid  BidV2     `json:"bestBid"`
+}
+
+// PortfolioEntryV2 is a bidtracker.PortfolioEntry in a ResponseV2
+type PortfolioEntryV2 struct {
+	ItemUUID      uuid.UUID                  `json:"itemUuid"`
+	HighestBid    BidV2                      `json:"highestBid"`
+	BidCount      int                        `json:"bidCount"`
+	LeadingAmount float64                    `json:"leadingAmount"`
+	Status        bidtracker.PortfolioStatus `json:"status"`
+	EndTime       int64                      `json:"endTime"`
+}
+
+// ItemAnalyticsV2 is a bidtracker.ItemAnalytics in a ResponseV2
+type ItemAnalyticsV2 struct {
+	ItemUUID      uuid.UUID                    `json:"itemUuid"`
+	BidCount      int                          `json:"bidCount"`
+	UniqueBidders int                          `json:"uniqueBidders"`
+	FirstBid      int64                        `json:"firstBid"`
+	LastBid       int64                        `json:"lastBid"`
+	BidsPerMinute float64                      `json:"bidsPerMinute"`
+	PriceSeries   []bidtracker.PricePoint      `json:"priceSeries"`
+	Histogram     []bidtracker.HistogramBucket `json:"histogram"`
+}
+
+// SystemAnalyticsV2 is a bidtracker.SystemAnalytics in a ResponseV2
+type SystemAnalyticsV2 struct {
+	ItemCount     int                          `json:"itemCount"`
+	ItemsWithBids int                          `json:"itemsWithBids"`
+	BidCount      int                          `json:"bidCount"`
+	UniqueBidders int                          `json:"uniqueBidders"`
+	FirstBid      int64                        `json:"firstBid"`
+	LastBid       int64                        `json:"lastBid"`
+	BidsPerMinute float64                      `json:"bidsPerMinute"`
+	LeadingVolume float64                      `json:"leadingVolume"`
+	Histogram     []bidtracker.HistogramBucket `json:"histogram"`
+}
+
+// ImportReportV2 is a bidtracker.ImportReport in a ResponseV2
+type ImportReportV2 struct {
+	DryRun   bool                     `json:"dryRun"`
+	Rows     int                      `json:"rows"`
+	Imported int                      `json:"imported"`
+	Failed   int                      `json:"failed"`
+	Errors   []bidtracker.ImportError `json:"errors"`
+}
+
+// SuspicionV2 is a bidtracker.Suspicion in a ResponseV2
+type SuspicionV2 struct {
+	Kind      bidtracker.SuspicionKind `json:"kind"`
+	Users     []uuid.UUID              `json:"users"`
+	ItemUUIDs []uuid.UUID              `json:"itemUuids"`
+	Reason    string                   `json:"reason"`
+}
+
+// APIKeyV2 is an apikey.Key in a ResponseV2
+type APIKeyV2 struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Role      string         `json:"role"`
+	UserUUID  uuid.UUID      `json:"userUuid"`
+	Scopes    []apikey.Scope `json:"scopes"`
+	CreatedAt time.Time      `json:"createdAt"`
+	ExpiresAt time.Time      `json:"expiresAt,omitempty"`
+	RevokedAt time.Time      `json:"revokedAt,omitempty"`
+}
+
+// APIKeyIssuedV2 is an APIKeyIssued in a ResponseV2
+type APIKeyIssuedV2 struct {
+	Token string   `json:"token"`
+	Key   APIKeyV2 `json:"key"`
+}
+
+// BuildInfoV2 is a BuildInfo in a ResponseV2
+type BuildInfoV2 struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
+
+// payloadV2 converts data to its v2 payload, types without multiword keys are returned as is
+func payloadV2(data interface{}) interface{} {
+	switch val := data.(type) {
+	case bidtracker.Bid:
+		return bidV2(val)
+	case *bidtracker.Bid:
+		return bidV2(*val)
+	case []bidtracker.Bid:
+		bids := make([]BidV2, 0, len(val))
+		for _, bid := range val {
+			bids = append(bids, bidV2(bid))
+		}
+		return bids
+	case bidtracker.BidBatchResult:
+		result := BidBatchResultV2{Accepted: val.Accepted, Rejected: val.Rejected, Results: make([]BidResultV2, 0, len(val.Results))}
+		for _, r := range val.Results {
+			result.Results = append(result.Results, BidResultV2{
+				Bid:      bidV2(r.Bid),
+				Accepted: r.Accepted,
+				Reason:   r.Reason,
+				Error:    r.Error,
+				Leader:   r.Leader,
+			})
+		}
+		return result
+	case bidtracker.Item:
+		return itemV2(val)
+	case *bidtracker.Item:
+		return itemV2(*val)
+	case []bidtracker.Item:
+		items := make([]ItemV2, 0, len(val))
+		for _, item := range val {
+			items = append(items, itemV2(item))
+		}
+		return items
+	case bidtracker.ItemSearchResult:
+		result := ItemSearchResultV2{
+			Items: make([]ItemSummaryV2, 0, len(val.Items)),
+			Total: val.Total,
+			Facets: ItemFacetsV2{
+				Categories:  val.Facets.Categories,
+				Status:      val.Facets.Status,
+				PriceRanges: val.Facets.PriceRanges,
+			},
+		}
+		for _, summary := range val.Items {
+			result.Items = append(result.Items, ItemSummaryV2{
+				ItemV2:       itemV2(summary.Item),
+				Status:       summary.Status,
+				BidCount:     summary.BidCount,
+				CurrentPrice: summary.CurrentPrice,
+			})
+		}
+		return result
+	case bidtracker.LeaderboardEntry:
+		return leaderboardEntryV2(val)
+	case []bidtracker.LeaderboardEntry:
+		entries := make([]LeaderboardEntryV2, 0, len(val))
+		for _, entry := range val {
+			entries = append(entries, leaderboardEntryV2(entry))
+		}
+		return entries
+	case []bidtracker.PortfolioEntry:
+		entries := make([]PortfolioEntryV2, 0, len(val))
+		for _, entry := range val {
+			entries = append(entries, PortfolioEntryV2{
+				ItemUUID:      entry.ItemUUID,
+				HighestBid:    bidV2(entry.HighestBid),
+				BidCount:      entry.BidCount,
+				LeadingAmount: entry.LeadingAmount,
+				Status:        entry.Status,
+				EndTime:       entry.EndTime,
+			})
+		}
+		return entries
+	case bidtracker.ItemAnalytics:
+		return ItemAnalyticsV2(val)
+	case bidtracker.SystemAnalytics:
+		return SystemAnalyticsV2(val)
+	case bidtracker.ImportReport:
+		return ImportReportV2(val)
+	case []bidtracker.Suspicion:
+		suspicions := make([]SuspicionV2, 0, len(val))
+		for _, suspicion := range val {
+			suspicions = append(suspicions, SuspicionV2(suspicion))
+		}
+		return suspicions
+	case apikey.Key:
+		return apiKeyV2(val)
+	case []apikey.Key:
+		keys := make([]APIKeyV2, 0, len(val))
+		for _, key := range val {
+			keys = append(keys, apiKeyV2(key))
+		}
+		return keys
+	case APIKeyIssued:
+		return APIKeyIssuedV2{Token: val.Token, Key: apiKeyV2(val.Key)}
+	case BuildInfo:
+		return BuildInfoV2(val)
+	}
+	return data
+}
+
+func bidV2(bid bidtracker.Bid) BidV2 {
+	return BidV2(bid)
+}
+
+func itemV2(item bidtracker.Item) ItemV2 {
+	return ItemV2(item)
+}
+
+func leaderboardEntryV2(entry bidtracker.LeaderboardEntry) LeaderboardEntryV2 {
+	return LeaderboardEntryV2{Rank: entry.Rank, UserUUID: entry.UserUUID, BestBid: bidV2(entry.BestBid)}
+}
+
+func apiKeyV2(key apikey.Key) APIKeyV2 {
+	return APIKeyV2{
+		ID:        key.ID,
+		Name:      key.Name,
+		Role:      key.Role,
+		UserUUID:  key.UserUUID,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+		ExpiresAt: key.ExpiresAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
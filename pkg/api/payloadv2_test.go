@@ -0,0 +1,132 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// lowercaseKeys lists the json keys of t, and of the types it holds, that are all lowercase
+// although their field is named with several words, e.g. itemuuid for ItemUUID
+func lowercaseKeys(t reflect.Type, seen map[reflect.Type]bool) []string {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return lowercaseKeys(t.Elem(), seen)
+	case reflect.Struct:
+	default:
+		return nil
+	}
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		keys = append(keys, lowercaseKeys(field.Type, seen)...)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.Anonymous && name == "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if multiword(field.Name) && strings.ToLower(name) == name {
+			keys = append(keys, t.Name()+"."+name)
+		}
+	}
+	return keys
+}
+
+// multiword reports whether a Go identifier joins several words, e.g. ItemUUID but not UUID
+func multiword(name string) bool {
+	runes := []rune(name)
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPayloadV2CamelCase(t *testing.T) {
+	assert := assert.New(t)
+
+	// payloads holds what every route sends, routes sending none map to nil
+	payloads := map[string]interface{}{
+		"POST " + URLBidItem:           bidtracker.Bid{},
+		"POST " + URLBidBatch:          bidtracker.BidBatchResult{},
+		"GET " + URLBidGetAll:          bidtracker.BidPage{}.Bids,
+		"GET " + URLBidGetWinning:      &bidtracker.Bid{},
+		"GET " + URLBidGetLeaderboard:  []bidtracker.LeaderboardEntry{},
+		"GET " + URLBidGetRank:         bidtracker.LeaderboardEntry{},
+		"GET " + URLItems:              []bidtracker.Item{},
+		"POST " + URLItems:             bidtracker.Item{},
+		"GET " + URLItemSearch:         bidtracker.ItemSearchResult{},
+		"GET " + URLItem:               bidtracker.Item{},
+		"PUT " + URLItem:               bidtracker.Item{},
+		"GET " + URLUserGetAllBids:     bidtracker.BidPage{}.Bids,
+		"GET " + URLUserGetPortfolio:   []bidtracker.PortfolioEntry{},
+		"GET " + URLAnalytics:          bidtracker.SystemAnalytics{},
+		"GET " + URLAnalyticsItem:      bidtracker.ItemAnalytics{},
+		"POST " + URLImportItems:       bidtracker.ImportReport{},
+		"POST " + URLImportBids:        bidtracker.ImportReport{},
+		"GET " + URLExportBids:         nil,
+		"GET " + URLAdminShillReport:   []bidtracker.Suspicion{},
+		"DELETE " + URLAdminShillFlags: nil,
+		"POST " + URLGraphQL:           nil,
+		"GET " + URLAdminAPIKeys:       []apikey.Key{},
+		"POST " + URLAdminAPIKeys:      APIKeyIssued{},
+		"POST " + URLAdminAPIKeyRotate: APIKeyIssued{},
+		"DELETE " + URLAdminAPIKey:     apikey.Key{},
+	}
+
+	// THEN every route has its payload checked, a new route has to be added above
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(), fiber.New())
+	api.apiKeys = apikey.NewStore()
+	for _, r := range api.routes() {
+		assert.Contains(payloads, r.name())
+	}
+
+	// THEN no payload of the v2 envelope keeps a lowercase key of v1
+	for name, payload := range payloads {
+		if payload == nil {
+			continue
+		}
+		v2 := payloadV2(payload)
+		assert.Empty(lowercaseKeys(reflect.TypeOf(v2), map[reflect.Type]bool{}), name)
+	}
+
+	// THEN the check tells the v1 payloads apart
+	assert.Contains(lowercaseKeys(reflect.TypeOf(bidtracker.Bid{}), map[reflect.Type]bool{}), "Bid.itemuuid")
+}
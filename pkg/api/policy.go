@@ -0,0 +1,190 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Role of a client, as carried by its api key
+type Role string
+
+const (
+	// RoleBidder can place bids and look at bids
+	RoleBidder Role = "bidder"
+
+	// RoleSeller can additionally manage its own items
+	RoleSeller Role = "seller"
+
+	// RoleModerator can look at everything and moderate items
+	RoleModerator Role = "moderator"
+
+	// RoleIntegration can place bids for any user, e.g. a partner platform relaying the bids of its users
+	RoleIntegration Role = "integration"
+
+	// RoleAdmin can do everything
+	RoleAdmin Role = "admin"
+)
+
+// Permission is an operation a route requires the caller to be allowed to do
+type Permission string
+
+const (
+	// PermissionAll is a wildcard granting every permission
+	PermissionAll Permission = "*"
+
+	// PermissionBidsCreate allows placing bids
+	PermissionBidsCreate Permission = "bids:create"
+
+	// PermissionBidsCreateAny allows placing bids for any user, not only the one the api key is bound to
+	PermissionBidsCreateAny Permission = "bids:create:any"
+
+	// PermissionBidsRead allows reading bids of items and users
+	PermissionBidsRead Permission = "bids:read"
+
+	// PermissionItemsRead allows browsing the item catalogue
+	PermissionItemsRead Permission = "items:read"
+
+	// PermissionItemsWrite allows creating and updating items
+	PermissionItemsWrite Permission = "items:write"
+
+	// PermissionItemsModerate allows moderating items and bids of other users
+	PermissionItemsModerate Permission = "items:moderate"
+
+	// PermissionAPIKeysManage allows issuing, rotating and revoking api keys
+	PermissionAPIKeysManage Permission = "apikeys:manage"
+
+	// PermissionDataImport allows bulk importing items and historical bids
+	PermissionDataImport Permission = "data:import"
+
+	// PermissionDataExport allows bulk exporting every bid
+	PermissionDataExport Permission = "data:export"
+)
+
+// permissionScopes maps every permission to the api key scope it needs on top of the role
+var permissionScopes = map[Permission]apikey.Scope{
+	PermissionBidsCreate:    apikey.ScopeBidsWrite,
+	PermissionBidsCreateAny: apikey.ScopeBidsWrite,
+	PermissionBidsRead:      apikey.ScopeBidsRead,
+	PermissionItemsRead:     apikey.ScopeBidsRead,
+	PermissionItemsWrite:    apikey.ScopeItemsWrite,
+	PermissionItemsModerate: apikey.ScopeAdmin,
+	PermissionAPIKeysManage: apikey.ScopeAdmin,
+	PermissionDataImport:    apikey.ScopeAdmin,
+	PermissionDataExport:    apikey.ScopeBidsRead,
+}
+
+// Policy maps each role to the permissions it is granted
+type Policy struct {
+	Roles map[Role][]Permission `yaml:"roles" json:"roles"`
+}
+
+// DefaultPolicy returns the policy used when none has been configured
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Roles: map[Role][]Permission{
+			RoleBidder:      {PermissionBidsCreate, PermissionBidsRead, PermissionItemsRead},
+			RoleSeller:      {PermissionBidsCreate, PermissionBidsRead, PermissionItemsRead, PermissionItemsWrite},
+			RoleModerator:   {PermissionBidsRead, PermissionItemsRead, PermissionItemsModerate, PermissionDataExport},
+			RoleIntegration: {PermissionBidsCreate, PermissionBidsCreateAny, PermissionBidsRead, PermissionItemsRead},
+			RoleAdmin:       {PermissionAll},
+		},
+	}
+}
+
+// LoadPolicyFile reads a policy from a yaml (or json) file looking like
+//
+//	roles:
+//	  bidder: [bids:create, bids:read]
+//	  admin: ["*"]
+func LoadPolicyFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to read the policy file")
+	}
+
+	policy := new(Policy)
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, errors.WithMessagef(err, "Failed to parse the policy file %s", path)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, errors.WithMessagef(err, "Invalid policy file %s", path)
+	}
+	return policy, nil
+}
+
+// Validate checks that the policy only refers to known permissions
+func (p *Policy) Validate() error {
+	if len(p.Roles) == 0 {
+		return fmt.Errorf("Policy does not define any role")
+	}
+	for role, permissions := range p.Roles {
+		for _, permission := range permissions {
+			if _, ok := permissionScopes[permission]; !ok && permission != PermissionAll {
+				return fmt.Errorf("Role %s refers to unknown permission %q", role, permission)
+			}
+		}
+	}
+	return nil
+}
+
+// Permits reports whether the role of key is granted the permission, and whether its scopes cover it
+func (p *Policy) Permits(key apikey.Key, permission Permission) bool {
+	return p.Allows(Role(key.Role), permission) && key.HasScope(permissionScopes[permission])
+}
+
+// ActsFor reports whether key may act for the user, e.g. bid as the user or manage the items the user sells:
+// either key is bound to the user, or its role may moderate items
+func (p *Policy) ActsFor(key apikey.Key, useruuid uuid.UUID) bool {
+	if p.Allows(Role(key.Role), PermissionItemsModerate) {
+		return true
+	}
+	return key.UserUUID != uuid.Nil && key.UserUUID == useruuid
+}
+
+// BidsFor reports whether key may place bids for the user: either it acts for the user,
+// or it is permitted to bid for any user
+func (p *Policy) BidsFor(key apikey.Key, useruuid uuid.UUID) bool {
+	return p.ActsFor(key, useruuid) || p.Permits(key, PermissionBidsCreateAny)
+}
+
+// HasRole reports whether the policy defines the role
+func (p *Policy) HasRole(role Role) bool {
+	_, ok := p.Roles[role]
+	return ok
+}
+
+// Allows reports whether the role is granted the permission
+func (p *Policy) Allows(role Role, permission Permission) bool {
+	for _, granted := range p.Roles[role] {
+		if granted == permission || granted == PermissionAll {
+			return true
+		}
+	}
+	return false
+}
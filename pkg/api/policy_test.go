@@ -0,0 +1,179 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := DefaultPolicy()
+	assert.Nil(policy.Validate())
+	assert.True(policy.Allows(RoleBidder, PermissionBidsCreate))
+	assert.False(policy.Allows(RoleBidder, PermissionItemsWrite))
+	assert.True(policy.Allows(RoleSeller, PermissionItemsWrite))
+	assert.False(policy.Allows(RoleModerator, PermissionBidsCreate))
+	assert.True(policy.Allows(RoleAdmin, PermissionAPIKeysManage))
+	assert.False(policy.Allows(Role("unknown"), PermissionBidsRead))
+}
+
+func TestLoadPolicyFile(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "policy.yaml")
+	assert.Nil(os.WriteFile(valid, []byte("roles:\n  bidder: [bids:read]\n  admin: [\"*\"]\n"), 0o600))
+
+	policy, err := LoadPolicyFile(valid)
+	assert.Nil(err)
+	assert.True(policy.Allows(RoleBidder, PermissionBidsRead))
+	assert.False(policy.Allows(RoleBidder, PermissionBidsCreate))
+	assert.False(policy.HasRole(RoleSeller))
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	assert.Nil(os.WriteFile(invalid, []byte("roles:\n  bidder: [bids:steal]\n"), 0o600))
+	_, err = LoadPolicyFile(invalid)
+	assert.NotNil(err)
+
+	_, err = LoadPolicyFile(filepath.Join(dir, "missing.yaml"))
+	assert.NotNil(err)
+}
+
+func TestPolicyDeniesWithForbidden(t *testing.T) {
+	assert := assert.New(t)
+	api, _ := newAPIWithKeys(t)
+
+	// A moderator key with every scope is still not allowed to bid
+	token, _, err := api.apiKeys.Create(apikey.Spec{Name: "moderator", Role: string(RoleModerator), Scopes: []apikey.Scope{apikey.ScopeAdmin}})
+	assert.Nil(err)
+
+	jsonData := `{"useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba", "itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a", "timestamp":1351807721, "amount":30}`
+	req := httptest.NewRequest("POST", "/api/v1/bids", bytes.NewBufferString(jsonData))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add(APIKeyHeader, token)
+	resp, _ := api.server.Test(req)
+	assert.Equal(fiber.StatusForbidden, resp.StatusCode)
+
+	response := new(Response)
+	assert.Nil(json.NewDecoder(resp.Body).Decode(response))
+	assert.Equal(fiber.StatusForbidden, response.Status)
+	assert.Equal("Permission denied, bids:create is required", response.Message)
+
+	req = httptest.NewRequest("GET", "/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a", nil)
+	req.Header.Add(APIKeyHeader, token)
+	resp, _ = api.server.Test(req)
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+}
+
+func TestPolicyBidsForOwnUser(t *testing.T) {
+	assert := assert.New(t)
+	api, adminToken := newAPIWithKeys(t)
+
+	alice := "ae8f7716-867b-4479-b455-c5769e7475ba"
+	bidderToken, _, err := api.apiKeys.Create(apikey.Spec{
+		Name:     "alice",
+		Role:     string(RoleBidder),
+		UserUUID: uuid.Must(uuid.FromString(alice)),
+		Scopes:   []apikey.Scope{apikey.ScopeBidsRead, apikey.ScopeBidsWrite},
+	})
+	assert.Nil(err)
+
+	post := func(url, token, body string) int {
+		req := httptest.NewRequest("POST", url, bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add(APIKeyHeader, token)
+		resp, _ := api.server.Test(req)
+		return resp.StatusCode
+	}
+	bid := func(user string, amount int) string {
+		return fmt.Sprintf(`{"itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","useruuid":"%s","amount":%d}`, user, amount)
+	}
+	bob := "f475091b-a8f1-4679-83bd-483b616e5260"
+
+	// WHEN a bidder bids for the user of its key, or a moderator for anyone
+	assert.Equal(fiber.StatusOK, post("/api/v1/bids", bidderToken, bid(alice, 10)))
+	assert.Equal(fiber.StatusOK, post("/api/v1/bids", adminToken, bid(bob, 20)))
+
+	// THEN the bidder can not bid for another user, alone, in a batch or through GraphQL
+	assert.Equal(fiber.StatusForbidden, post("/api/v1/bids", bidderToken, bid(bob, 30)))
+	assert.Equal(fiber.StatusForbidden, post("/api/v1/bids:batch", bidderToken, "["+bid(alice, 30)+","+bid(bob, 40)+"]"))
+	placeBid := `mutation($user: ID!) { placeBid(itemUuid: "b2f9ee6d-79fe-4b14-9c19-35a69a89219a", userUuid: $user, amount: 50) { amount } }`
+	_, response := postGraphQL(t, api, bidderToken, placeBid, map[string]interface{}{"user": bob})
+	if assert.Len(response.Errors, 1) {
+		assert.Contains(response.Errors[0].Message, "Permission denied")
+	}
+
+	bids, _ := api.itemsBid.GetBids(uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a")))
+	assert.Len(bids, 2)
+}
+
+func TestPolicyIntegrationBidsForAnyUser(t *testing.T) {
+	assert := assert.New(t)
+	api, _ := newAPIWithKeys(t)
+
+	scopes := []apikey.Scope{apikey.ScopeBidsRead, apikey.ScopeBidsWrite}
+	integrationToken, _, err := api.apiKeys.Create(apikey.Spec{Name: "partner", Role: string(RoleIntegration), Scopes: scopes})
+	assert.Nil(err)
+	bidderToken, _, err := api.apiKeys.Create(apikey.Spec{Name: "unbound", Role: string(RoleBidder), Scopes: scopes})
+	assert.Nil(err)
+
+	post := func(url, token, body string) int {
+		req := httptest.NewRequest("POST", url, bytes.NewBufferString(body))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add(APIKeyHeader, token)
+		resp, _ := api.server.Test(req)
+		return resp.StatusCode
+	}
+	bid := func(user string, amount int) string {
+		return fmt.Sprintf(`{"itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","useruuid":"%s","amount":%d}`, user, amount)
+	}
+	alice := "ae8f7716-867b-4479-b455-c5769e7475ba"
+	bob := "f475091b-a8f1-4679-83bd-483b616e5260"
+
+	// WHEN an integration key bound to no user bids for users, alone or in a batch
+	assert.Equal(fiber.StatusOK, post("/api/v1/bids", integrationToken, bid(alice, 10)))
+	assert.Equal(fiber.StatusOK, post("/api/v1/bids:batch", integrationToken, "["+bid(bob, 20)+","+bid(alice, 30)+"]"))
+
+	// THEN an unbound bidder key still can not
+	assert.Equal(fiber.StatusForbidden, post("/api/v1/bids", bidderToken, bid(alice, 40)))
+
+	// WHEN the integration key lacks the bids:write scope, it can not bid at all
+	readOnly, _, err := api.apiKeys.Create(apikey.Spec{Name: "partner", Role: string(RoleIntegration), Scopes: []apikey.Scope{apikey.ScopeBidsRead}})
+	assert.Nil(err)
+	assert.Equal(fiber.StatusForbidden, post("/api/v1/bids", readOnly, bid(alice, 50)))
+
+	bids, _ := api.itemsBid.GetBids(uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a")))
+	assert.Len(bids, 3)
+}
@@ -0,0 +1,134 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"strings"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MIMEApplicationProblemJSON is the content type of problem details, clients accepting it get
+	// their errors as problem details
+	MIMEApplicationProblemJSON = "application/problem+json"
+
+	// ProblemTypePrefix followed by the code of a problem makes up its type
+	ProblemTypePrefix = "urn:bid-tracker:problem:"
+
+	localsProblemDetails = "problemdetails"
+)
+
+// Problem is an error response in the RFC 7807 problem details format
+type Problem struct {
+	// Type is ProblemTypePrefix followed by Code
+	Type string `json:"type"`
+	// Code is a stable name of the problem for clients to branch on, e.g. item_not_found
+	Code   string `json:"code"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Instance is the X-Request-ID of the request
+	Instance string `json:"instance,omitempty"`
+	// Errors lists the invalid fields of the request
+	Errors []FieldError `json:"errors,omitempty"`
+	// Data is what was done before failing, e.g. the report of an import or the results of a batch
+	Data interface{} `json:"data,omitempty"`
+}
+
+// FieldError tells why a query parameter, path parameter or json field of the request is invalid
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// problemDetails makes every error response of the request a problem, whatever the client accepts
+func problemDetails(c *fiber.Ctx) error {
+	c.Locals(localsProblemDetails, true)
+	return c.Next()
+}
+
+// wantsProblem reports whether the errors of the request are sent as problem details
+func wantsProblem(c *fiber.Ctx) bool {
+	if always, _ := c.Locals(localsProblemDetails).(bool); always {
+		return true
+	}
+	return strings.Contains(c.Get(fiber.HeaderAccept), MIMEApplicationProblemJSON)
+}
+
+// newProblem describes a failed request, data is dropped if it is EmptyResponse
+func newProblem(c *fiber.Ctx, kind errorKind, detail string, data interface{}) Problem {
+	problem := Problem{
+		Type:     ProblemTypePrefix + kind.code,
+		Code:     kind.code,
+		Title:    kind.title(),
+		Status:   kind.status,
+		Detail:   detail,
+		Instance: requestIDOf(c),
+	}
+	if empty, ok := data.(map[string]interface{}); !ok || len(empty) > 0 {
+		problem.Data = data
+	}
+	return problem
+}
+
+func sendProblem(c *fiber.Ctx, problem Problem) error {
+	if err := c.Status(problem.Status).JSON(problem); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, MIMEApplicationProblemJSON)
+	return nil
+}
+
+// SendError sends err with the status mapped to its kind. Clients accepting problem details and the v2 routes
+// get its code and, for invalid arguments, the invalid field.
+func SendError(c *fiber.Ctx, err error) error {
+	return sendError(c, err, EmptyResponse)
+}
+
+// sendError sends err along with data, e.g. what was done before failing
+func sendError(c *fiber.Ctx, err error, data interface{}) error {
+	kind := errorKindOf(err)
+	if !wantsProblem(c) && !wantsEnvelopeV2(c) {
+		return SendJSON(c, kind.status, err.Error(), data)
+	}
+
+	c.Locals(localsErrorMessage, err.Error())
+	var fields []FieldError
+	var invalid *bidtracker.InvalidArgumentError
+	if errors.As(err, &invalid) && invalid.Field != "" {
+		fields = []FieldError{{Field: invalid.Field, Detail: invalid.Err.Error()}}
+	}
+	return sendFailure(c, kind, err.Error(), fields, data)
+}
+
+// sendFailure sends a problem to the clients wanting one, else the error of the v2 envelope
+func sendFailure(c *fiber.Ctx, kind errorKind, detail string, fields []FieldError, data interface{}) error {
+	if !wantsProblem(c) {
+		return sendJSONV2(c, kind.status, data, &ErrorV2{Code: kind.code, Message: detail, Fields: fields})
+	}
+	problem := newProblem(c, kind, detail, data)
+	problem.Errors = fields
+	return sendProblem(c, problem)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemDetails(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New())
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1")))
+
+	get := func(path, accept string) (*http.Response, Problem) {
+		req := httptest.NewRequest("GET", path, nil)
+		if accept != "" {
+			req.Header.Add(fiber.HeaderAccept, accept)
+		}
+		resp, _ := api.server.Test(req)
+		var problem Problem
+		assert.Nil(json.NewDecoder(resp.Body).Decode(&problem))
+		return resp, problem
+	}
+
+	// WHEN the client does not accept problem details, the envelope is sent
+	resp, _ := get("/api/v1/bids/cef31b6b-cdeb-4035-8d42-a4f33b2d02fe/winning", "")
+	assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+	assert.Equal(fiber.MIMEApplicationJSON, resp.Header.Get(fiber.HeaderContentType))
+
+	// WHEN the client accepts them, the problem has the code of the error and the request id
+	resp, problem := get("/api/v1/bids/cef31b6b-cdeb-4035-8d42-a4f33b2d02fe/winning", "application/json, "+MIMEApplicationProblemJSON)
+	assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+	assert.Equal(MIMEApplicationProblemJSON, resp.Header.Get(fiber.HeaderContentType))
+	assert.Equal(ProblemTypePrefix+"item_not_found", problem.Type)
+	assert.Equal("item_not_found", problem.Code)
+	assert.Equal(bidtracker.ErrItemNotFound.Error(), problem.Title)
+	assert.Equal(fiber.StatusNotFound, problem.Status)
+	assert.Contains(problem.Detail, "Failed to fetch the current winning bid")
+	assert.Equal(resp.Header.Get(RequestIDHeader), problem.Instance)
+	assert.Empty(problem.Errors)
+
+	// THEN invalid parameters are listed, whether the tracker or the handler found them
+	resp, problem = get("/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a?limit=5000", MIMEApplicationProblemJSON)
+	assert.Equal(fiber.StatusBadRequest, resp.StatusCode)
+	assert.Equal("invalid_argument", problem.Code)
+	assert.Equal([]FieldError{{Field: "limit", Detail: "Limit must be between 1 and 1000"}}, problem.Errors)
+
+	_, problem = get("/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a?order=up", MIMEApplicationProblemJSON)
+	assert.Equal("invalid_argument", problem.Code)
+	assert.Equal("order", problem.Errors[0].Field)
+
+	_, problem = get("/api/v1/bids/not-a-uuid/leaderboard", MIMEApplicationProblemJSON)
+	assert.Equal("invalid_argument", problem.Code)
+	assert.Equal("itemuuid", problem.Errors[0].Field)
+
+	// THEN what was done before failing is kept
+	body := `[{"itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":10},
+		{"itemuuid":"cef31b6b-cdeb-4035-8d42-a4f33b2d02fe","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":20}]`
+	req := httptest.NewRequest("POST", "/api/v1/bids:batch?atomic=true", bytes.NewBufferString(body))
+	req.Header.Add(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Add(fiber.HeaderAccept, MIMEApplicationProblemJSON)
+	resp, _ = api.server.Test(req)
+	assert.Equal(fiber.StatusUnprocessableEntity, resp.StatusCode)
+	var batch struct {
+		Code string
+		Data bidtracker.BidBatchResult
+	}
+	assert.Nil(json.NewDecoder(resp.Body).Decode(&batch))
+	assert.Equal("unprocessable_entity", batch.Code)
+	assert.Equal(2, batch.Data.Rejected)
+}
+
+func TestProblemDetailsAlways(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New())
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1"), RegisterWithAPIKeys(apikey.NewStore()), RegisterWithProblemDetails()))
+
+	// WHEN problem details are configured, errors of the middlewares are sent as such too
+	resp, _ := api.server.Test(httptest.NewRequest("GET", "/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a", nil))
+	assert.Equal(fiber.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(MIMEApplicationProblemJSON, resp.Header.Get(fiber.HeaderContentType))
+	var problem Problem
+	assert.Nil(json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal("unauthorized", problem.Code)
+	assert.Equal("Unauthorized", problem.Title)
+	assert.Equal("Missing api key", problem.Detail)
+}
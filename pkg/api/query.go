@@ -0,0 +1,192 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// parseBidQuery reads the pagination, sorting and filtering query parameters shared by the bid listings
+func parseBidQuery(c *fiber.Ctx) (bidtracker.BidQuery, error) {
+	query := bidtracker.BidQuery{
+		Cursor: c.Query("cursor"),
+		SortBy: bidtracker.BidSortField(c.Query("sort")),
+	}
+
+	var err error
+	switch order := c.Query("order"); order {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, invalidParam("order", errors.Errorf("Unknown order %q, expected asc or desc", order))
+	}
+
+	if query.Limit, err = queryInt(c, "limit"); err != nil {
+		return query, err
+	}
+	if query.From, err = queryInt64(c, "from"); err != nil {
+		return query, err
+	}
+	if query.To, err = queryInt64(c, "to"); err != nil {
+		return query, err
+	}
+	if query.MinAmount, err = queryFloat(c, "min_amount"); err != nil {
+		return query, err
+	}
+	if query.MaxAmount, err = queryFloat(c, "max_amount"); err != nil {
+		return query, err
+	}
+	if query.UserUUID, err = queryUUID(c, "useruuid"); err != nil {
+		return query, err
+	}
+	if query.ItemUUID, err = queryUUID(c, "itemuuid"); err != nil {
+		return query, err
+	}
+	return query, query.Validate()
+}
+
+// parseItemSearch reads the text, facet, sorting and paging query parameters of the item search
+func parseItemSearch(c *fiber.Ctx) (bidtracker.ItemSearch, error) {
+	search := bidtracker.ItemSearch{
+		Text:   c.Query("q"),
+		Status: bidtracker.ItemStatus(c.Query("status")),
+		SortBy: bidtracker.ItemSort(c.Query("sort")),
+	}
+	if category := c.Query("category"); category != "" {
+		search.Categories = strings.Split(category, ",")
+	}
+
+	var err error
+	if search.Limit, err = queryInt(c, "limit"); err != nil {
+		return search, err
+	}
+	if search.Offset, err = queryInt(c, "offset"); err != nil {
+		return search, err
+	}
+	if search.MinPrice, err = queryFloat(c, "min_price"); err != nil {
+		return search, err
+	}
+	if search.MaxPrice, err = queryFloat(c, "max_price"); err != nil {
+		return search, err
+	}
+	return search, search.Validate()
+}
+
+// parseAnalyticsQuery reads the series interval (in seconds) and the number of histogram buckets
+func parseAnalyticsQuery(c *fiber.Ctx) (bidtracker.AnalyticsQuery, error) {
+	var query bidtracker.AnalyticsQuery
+
+	interval, err := queryInt64(c, "interval")
+	if err != nil {
+		return query, err
+	}
+	query.Interval = time.Duration(interval) * time.Second
+	if query.HistogramBuckets, err = queryInt(c, "buckets"); err != nil {
+		return query, err
+	}
+	return query, query.Validate()
+}
+
+// parseImportQuery reads the format of the body, json lines by default, and whether to only validate it
+func parseImportQuery(c *fiber.Ctx) (format string, dryRun bool, err error) {
+	format = c.Query("format", bidtracker.FormatJSONL)
+	if format != bidtracker.FormatJSONL && format != bidtracker.FormatCSV {
+		return format, false, invalidParam("format", errors.Errorf("format must be %s or %s", bidtracker.FormatJSONL, bidtracker.FormatCSV))
+	}
+	dryRun, err = queryBool(c, "dry_run")
+	return format, dryRun, err
+}
+
+func queryBool(c *fiber.Ctx, name string) (bool, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, invalidParam(name, errors.Errorf("%s must be a boolean", name))
+	}
+	return value, nil
+}
+
+func queryInt(c *fiber.Ctx, name string) (int, error) {
+	value, err := queryInt64(c, name)
+	return int(value), err
+}
+
+func queryInt64(c *fiber.Ctx, name string) (int64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, invalidParam(name, errors.Errorf("%s must be an integer", name))
+	}
+	return value, nil
+}
+
+func queryFloat(c *fiber.Ctx, name string) (float64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, invalidParam(name, errors.Errorf("%s must be a number", name))
+	}
+	return value, nil
+}
+
+func queryUUID(c *fiber.Ctx, name string) (uuid.UUID, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return uuid.Nil, nil
+	}
+	value, err := uuid.FromString(raw)
+	if err != nil {
+		return uuid.Nil, invalidParam(name, errors.WithMessagef(err, "%s can not be parsed successfully", name))
+	}
+	return value, nil
+}
+
+// paramUUID reads the uuid in the path parameter name
+func paramUUID(c *fiber.Ctx, name string) (uuid.UUID, error) {
+	value, err := uuid.FromString(c.Params(name))
+	if err != nil {
+		return uuid.Nil, invalidParam(name, errors.WithMessagef(err, "%s can not be parsed successfully", name))
+	}
+	return value, nil
+}
+
+// invalidParam marks err as an invalid value of the named query parameter, path parameter or json field
+func invalidParam(name string, err error) error {
+	return &bidtracker.InvalidArgumentError{Field: name, Err: err}
+}
@@ -0,0 +1,334 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// RateLimit is a token bucket budget allowing Requests per Period,
+// with bursts of up to Burst requests. A zero RateLimit means unlimited.
+type RateLimit struct {
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+	// Burst is the capacity of the bucket, defaults to Requests
+	Burst int `yaml:"burst"`
+}
+
+// Validate checks the budget for negative values and a missing period
+func (rl RateLimit) Validate() error {
+	if rl.Requests < 0 || rl.Burst < 0 || rl.Period < 0 {
+		return errors.New("requests, period and burst can not be negative")
+	}
+	if rl.Requests > 0 && rl.Period == 0 {
+		return errors.New("period is required when requests are limited")
+	}
+	return nil
+}
+
+// RateLimits configures the budgets of every client.
+// Clients are identified by the user their api key is bound to, then by api key, then by IP.
+type RateLimits struct {
+	// Read is shared by all GET routes
+	Read RateLimit `yaml:"read"`
+	// Write is shared by all the other routes
+	Write RateLimit `yaml:"write"`
+	// Routes gives individual routes a budget of their own, keyed by method and path
+	// without any prefix, e.g. "POST /bids"
+	Routes map[string]RateLimit `yaml:"routes"`
+	// AuthFailures is the budget of failed authentications of every IP, shared by all routes
+	AuthFailures RateLimit `yaml:"authfailures"`
+}
+
+// Validate checks every budget
+func (limits RateLimits) Validate() error {
+	if err := limits.Read.Validate(); err != nil {
+		return errors.WithMessage(err, "read")
+	}
+	if err := limits.Write.Validate(); err != nil {
+		return errors.WithMessage(err, "write")
+	}
+	if err := limits.AuthFailures.Validate(); err != nil {
+		return errors.WithMessage(err, "authfailures")
+	}
+	for name, limit := range limits.Routes {
+		if err := limit.Validate(); err != nil {
+			return errors.WithMessage(err, name)
+		}
+	}
+	return nil
+}
+
+// DefaultRateLimits returns the budgets used by the server unless configured otherwise
+func DefaultRateLimits() RateLimits {
+	return RateLimits{
+		Read:         RateLimit{Requests: 100, Period: time.Second, Burst: 200},
+		Write:        RateLimit{Requests: 20, Period: time.Second, Burst: 40},
+		AuthFailures: RateLimit{Requests: 10, Period: time.Minute, Burst: 20},
+	}
+}
+
+func (rl RateLimit) unlimited() bool {
+	return rl.Requests <= 0 || rl.Period <= 0
+}
+
+func (rl RateLimit) capacity() float64 {
+	if rl.Burst > 0 {
+		return float64(rl.Burst)
+	}
+	return float64(rl.Requests)
+}
+
+// refillRate returns tokens per second
+func (rl RateLimit) refillRate() float64 {
+	return float64(rl.Requests) / rl.Period.Seconds()
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter keeps one token bucket per client for a single budget
+type rateLimiter struct {
+	sync.Mutex
+	limit   RateLimit
+	buckets map[string]*bucket
+	now     func() time.Time
+	calls   int
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	return &rateLimiter{
+		limit:   limit,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// take consumes a token from the bucket of the client. It returns the tokens left,
+// the time until the bucket is full again and, if no token was available, the time to wait.
+func (rl *rateLimiter) take(client string) (remaining int, reset, retryAfter time.Duration) {
+	rl.Lock()
+	defer rl.Unlock()
+
+	now := rl.now()
+	capacity := rl.limit.capacity()
+	rate := rl.limit.refillRate()
+
+	rl.calls++
+	if rl.calls%1024 == 0 {
+		rl.evictFull(now, capacity, rate)
+	}
+
+	b, ok := rl.buckets[client]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		rl.buckets[client] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		retryAfter = seconds((1 - b.tokens) / rate)
+	} else {
+		b.tokens--
+	}
+	return int(b.tokens), seconds((capacity - b.tokens) / rate), retryAfter
+}
+
+// wait returns the time until the bucket of the client has a token again, without consuming it
+func (rl *rateLimiter) wait(client string) time.Duration {
+	rl.Lock()
+	defer rl.Unlock()
+
+	b, ok := rl.buckets[client]
+	if !ok {
+		return 0
+	}
+	tokens := math.Min(rl.limit.capacity(), b.tokens+rl.now().Sub(b.last).Seconds()*rl.limit.refillRate())
+	if tokens >= 1 {
+		return 0
+	}
+	return seconds((1 - tokens) / rl.limit.refillRate())
+}
+
+// evictFull forgets the buckets which have refilled completely, they are identical to new ones
+func (rl *rateLimiter) evictFull(now time.Time, capacity, rate float64) {
+	for client, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= capacity {
+			delete(rl.buckets, client)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// rateLimitClient identifies the caller, preferring the authenticated user over the api key over the IP
+func rateLimitClient(c *fiber.Ctx) string {
+	if key, ok := c.Locals(localsAPIKey).(apikey.Key); ok {
+		if key.UserUUID != uuid.Nil {
+			return "user:" + key.UserUUID.String()
+		}
+		return "key:" + key.ID
+	}
+	return "ip:" + c.IP()
+}
+
+// ceilSeconds formats a duration as whole seconds for the rate limit headers
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// rateLimit returns a middleware enforcing the limiter, or nil if the route is unlimited
+func rateLimit(limiter *rateLimiter) fiber.Handler {
+	if limiter == nil {
+		return nil
+	}
+	limit := strconv.Itoa(int(limiter.limit.capacity()))
+
+	return func(c *fiber.Ctx) error {
+		remaining, reset, retryAfter := limiter.take(rateLimitClient(c))
+
+		c.Set("RateLimit-Limit", limit)
+		c.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Set("RateLimit-Reset", ceilSeconds(reset))
+
+		if retryAfter > 0 {
+			c.Set(fiber.HeaderRetryAfter, ceilSeconds(retryAfter))
+			return SendJSON(c, fiber.StatusTooManyRequests, "Rate limit exceeded", EmptyResponse)
+		}
+		return c.Next()
+	}
+}
+
+// limitAuthFailures returns a middleware answering 429 to the IPs that used up their budget of failed
+// authentications, or nil if failures are unlimited. It runs before authorize, so that api keys can not
+// be guessed at the pace of the budgets of authenticated clients.
+func limitAuthFailures(limiter *rateLimiter) fiber.Handler {
+	if limiter == nil {
+		return nil
+	}
+
+	return func(c *fiber.Ctx) error {
+		client := "ip:" + c.IP()
+		if retryAfter := limiter.wait(client); retryAfter > 0 {
+			c.Set(fiber.HeaderRetryAfter, ceilSeconds(retryAfter))
+			return SendJSON(c, fiber.StatusTooManyRequests, "Too many failed authentications", EmptyResponse)
+		}
+
+		err := c.Next()
+		if c.Response().StatusCode() == fiber.StatusUnauthorized {
+			limiter.take(client)
+		}
+		return err
+	}
+}
+
+// RateLimiters holds the token buckets of RateLimits. The REST routes and the grpc api given
+// the same RateLimiters share every budget, a client can not double it by using both.
+type RateLimiters struct {
+	limits       RateLimits
+	read         *rateLimiter
+	write        *rateLimiter
+	routes       map[string]*rateLimiter
+	authFailures *rateLimiter
+}
+
+// NewRateLimiters creates the buckets of limits, unlimited budgets get none
+func NewRateLimiters(limits RateLimits) *RateLimiters {
+	newLimiter := func(limit RateLimit) *rateLimiter {
+		if limit.unlimited() {
+			return nil
+		}
+		return newRateLimiter(limit)
+	}
+
+	routes := make(map[string]*rateLimiter, len(limits.Routes))
+	for name, limit := range limits.Routes {
+		routes[name] = newLimiter(limit)
+	}
+	return &RateLimiters{
+		limits:       limits,
+		read:         newLimiter(limits.Read),
+		write:        newLimiter(limits.Write),
+		routes:       routes,
+		authFailures: newLimiter(limits.AuthFailures),
+	}
+}
+
+// route returns the limiter of the route named like "POST /bids", nil if it is unlimited.
+// Routes without a budget of their own share the read or the write budget.
+func (l *RateLimiters) route(name string) *rateLimiter {
+	if limiter, ok := l.routes[name]; ok {
+		return limiter
+	}
+	if strings.HasPrefix(name, fiber.MethodGet+" ") {
+		return l.read
+	}
+	return l.write
+}
+
+// RateLimiter enforces a budget on every client outside of the REST routes, e.g. in the grpc api
+type RateLimiter struct {
+	limiter *rateLimiter
+}
+
+// Route returns the limiter of the route named like "POST /bids", or nil if it is unlimited
+func (l *RateLimiters) Route(name string) *RateLimiter {
+	limiter := l.route(name)
+	if limiter == nil {
+		return nil
+	}
+	return &RateLimiter{limiter}
+}
+
+// AuthFailures returns the limiter of the failed authentications, or nil if they are unlimited
+func (l *RateLimiters) AuthFailures() *RateLimiter {
+	if l.authFailures == nil {
+		return nil
+	}
+	return &RateLimiter{l.authFailures}
+}
+
+// Take consumes a token from the bucket of the client, it returns the time to wait if none was available
+func (rl *RateLimiter) Take(client string) time.Duration {
+	_, _, retryAfter := rl.limiter.take(client)
+	return retryAfter
+}
+
+// Wait returns the time until the bucket of the client has a token again, without consuming it
+func (rl *RateLimiter) Wait(client string) time.Duration {
+	return rl.limiter.wait(client)
+}
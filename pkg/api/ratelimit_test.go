@@ -0,0 +1,161 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterRefill(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(RateLimit{Requests: 1, Period: time.Second, Burst: 2})
+	limiter.now = func() time.Time { return now }
+
+	remaining, _, retryAfter := limiter.take("ip:1")
+	assert.Equal(1, remaining)
+	assert.Zero(retryAfter)
+
+	remaining, reset, retryAfter := limiter.take("ip:1")
+	assert.Equal(0, remaining)
+	assert.Equal(2*time.Second, reset)
+	assert.Zero(retryAfter)
+
+	_, _, retryAfter = limiter.take("ip:1")
+	assert.Equal(time.Second, retryAfter)
+
+	// Other clients have their own bucket
+	_, _, retryAfter = limiter.take("ip:2")
+	assert.Zero(retryAfter)
+
+	now = now.Add(time.Second)
+	_, _, retryAfter = limiter.take("ip:1")
+	assert.Zero(retryAfter)
+}
+
+func TestRateLimitedRoutes(t *testing.T) {
+	assert := assert.New(t)
+
+	biddableItems := []uuid.UUID{
+		uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a")),
+	}
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(biddableItems...), fiber.New())
+	err := RegisterRoutes(api,
+		RegisterWithAPIVersion("/api/v1"),
+		RegisterWithRateLimits(RateLimits{
+			Read:  RateLimit{Requests: 1, Period: time.Minute, Burst: 1},
+			Write: RateLimit{Requests: 1, Period: time.Minute, Burst: 1},
+			Routes: map[string]RateLimit{
+				"GET " + URLBidGetWinning: {},
+			},
+		}),
+	)
+	assert.Nil(err)
+
+	jsonData := `{"useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba", "itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a", "timestamp":1351807721, "amount":30}`
+	post := func() *http.Response {
+		req := httptest.NewRequest("POST", "/api/v1/bids", bytes.NewBufferString(jsonData))
+		req.Header.Add("Content-Type", "application/json")
+		resp, _ := api.server.Test(req)
+		return resp
+	}
+
+	// WHEN the write budget is used up
+	resp := post()
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	assert.Equal("1", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal("0", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal("60", resp.Header.Get("RateLimit-Reset"))
+
+	resp = post()
+	assert.Equal(fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal("60", resp.Header.Get(fiber.HeaderRetryAfter))
+
+	// THEN reads still have their own budget
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a", nil))
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	resp, _ = api.server.Test(httptest.NewRequest("GET", "/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a", nil))
+	assert.Equal(fiber.StatusTooManyRequests, resp.StatusCode)
+
+	// THEN the route configured as unlimited is not limited at all
+	for i := 0; i < 3; i++ {
+		resp, _ = api.server.Test(httptest.NewRequest("GET", "/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a/winning", nil))
+		assert.Equal(fiber.StatusOK, resp.StatusCode)
+		assert.Empty(resp.Header.Get("RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitUnknownRoute(t *testing.T) {
+	assert := assert.New(t)
+
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(), fiber.New())
+	err := RegisterRoutes(api,
+		RegisterWithAPIVersion("/api/v1"),
+		RegisterWithRateLimits(RateLimits{Routes: map[string]RateLimit{"GET /nope": {}}}),
+	)
+	assert.NotNil(err)
+}
+
+func TestAuthFailuresLimited(t *testing.T) {
+	assert := assert.New(t)
+
+	store := apikey.NewStore()
+	token, _, err := store.Create(apikey.Spec{Name: "reader", Role: string(RoleBidder), Scopes: []apikey.Scope{apikey.ScopeBidsRead}})
+	assert.Nil(err)
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(), fiber.New())
+	assert.Nil(RegisterRoutes(api,
+		RegisterWithAPIVersion("/api/v1"),
+		RegisterWithAPIKeys(store),
+		RegisterWithRateLimits(RateLimits{AuthFailures: RateLimit{Requests: 1, Period: time.Minute, Burst: 2}}),
+	))
+
+	get := func(key string) *http.Response {
+		req := httptest.NewRequest("GET", "/api/v1/items", nil)
+		req.Header.Add(APIKeyHeader, key)
+		resp, _ := api.server.Test(req)
+		return resp
+	}
+
+	// WHEN a valid key is used, nothing is counted
+	for i := 0; i < 3; i++ {
+		assert.Equal(fiber.StatusOK, get(token).StatusCode)
+	}
+
+	// WHEN the budget of failures is used up, even a valid key is refused
+	assert.Equal(fiber.StatusUnauthorized, get("guess.one").StatusCode)
+	assert.Equal(fiber.StatusUnauthorized, get("guess.two").StatusCode)
+	resp := get("guess.three")
+	assert.Equal(fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal("60", resp.Header.Get(fiber.HeaderRetryAfter))
+	assert.Equal(fiber.StatusTooManyRequests, get(token).StatusCode)
+}
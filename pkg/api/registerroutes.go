@@ -24,9 +24,14 @@ package api
 import (
 	"net/url"
 	"path"
+	"strings"
 
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/gofiber/fiber/v2"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // RegisterRoutesOption will be a set of routes registry options
@@ -35,14 +40,57 @@ type RegisterRoutesOption struct {
 }
 
 type routesOptions struct {
-	apiVersion  string
+	apiVersion string
+	// apiVersion2 serves the routes a second time with the v2 envelope, e.g. "/api/v2"
+	apiVersion2 string
 	proxyPrefix string
+	apiKeys     *apikey.Store
+	apiKeysFile string
+	policy      *Policy
+	rateLimits  *RateLimiters
+	metrics     *Metrics
+	tracing     trace.TracerProvider
+	logger      *zerolog.Logger
+	health      *Health
+	buildInfo   *BuildInfo
+	// problemDetails sends every error as problem details, not only to the clients accepting them
+	problemDetails bool
+}
+
+// route describes a single endpoint and the permission it requires
+type route struct {
+	method     string
+	path       string
+	permission Permission
+	handler    fiber.Handler
+}
+
+// name identifies the route in configuration, e.g. "POST /bids"
+func (r route) name() string {
+	return r.method + " " + r.path
+}
+
+// streamsBody reports whether the handler of r reads the request body as it is received, e.g. for bulk imports
+func (r route) streamsBody() bool {
+	return r.path == URLImportItems || r.path == URLImportBids
 }
 
 func prepareRoutes(baseURL, suffix string) string {
 	return path.Join(baseURL, suffix)
 }
 
+// routePattern escapes the colons of p that do not start a parameter, e.g. in "/bids:batch", for fiber
+func routePattern(p string) string {
+	var pattern strings.Builder
+	for i, r := range p {
+		if r == ':' && i > 0 && p[i-1] != '/' {
+			pattern.WriteByte('\\')
+		}
+		pattern.WriteRune(r)
+	}
+	return pattern.String()
+}
+
 // RegisterWithAPIVersion returns a RegisterRoutesOption that configures prefix of the API
 // This must look like "/api/v1"
 func RegisterWithAPIVersion(apiVersion string) RegisterRoutesOption {
@@ -51,6 +99,14 @@ func RegisterWithAPIVersion(apiVersion string) RegisterRoutesOption {
 	}}
 }
 
+// RegisterWithAPIVersion2 returns a RegisterRoutesOption that serves every route a second time under
+// apiVersion, e.g. "/api/v2", answering with ResponseV2. The routes of RegisterWithAPIVersion are unchanged.
+func RegisterWithAPIVersion2(apiVersion string) RegisterRoutesOption {
+	return RegisterRoutesOption{func(ro *routesOptions) {
+		ro.apiVersion2 = apiVersion
+	}}
+}
+
 // RegisterWithAPIProxyPrefix returns a RegisterRoutesOption that configures proxy-prefix for the API
 // This must look like "/production/prefix/stuff"
 func RegisterWithAPIProxyPrefix(proxyPrefix string) RegisterRoutesOption {
@@ -59,6 +115,129 @@ func RegisterWithAPIProxyPrefix(proxyPrefix string) RegisterRoutesOption {
 	}}
 }
 
+// RegisterWithAPIKeys returns a RegisterRoutesOption that enforces api key authentication
+// on every route using the given store, and registers the admin endpoints to manage keys.
+func RegisterWithAPIKeys(store *apikey.Store) RegisterRoutesOption {
+	return RegisterRoutesOption{func(ro *routesOptions) {
+		ro.apiKeys = store
+	}}
+}
+
+// RegisterWithAPIKeysFile returns a RegisterRoutesOption that saves the api keys to path
+// every time one is issued, rotated or revoked, so that a crash does not bring a revoked key back.
+func RegisterWithAPIKeysFile(path string) RegisterRoutesOption {
+	return RegisterRoutesOption{func(ro *routesOptions) {
+		ro.apiKeysFile = path
+	}}
+}
+
+// RegisterWithPolicy returns a RegisterRoutesOption that configures the role based access policy.
+// DefaultPolicy is used if this option is not provided.
+func RegisterWithPolicy(policy *Policy) RegisterRoutesOption {
+	return RegisterRoutesOption{func(ro *routesOptions) {
+		ro.policy = policy
+	}}
+}
+
+// RegisterWithRateLimits returns a RegisterRoutesOption that configures per client rate limiting.
+// Routes are unlimited if this option is not provided.
+func RegisterWithRateLimits(limits RateLimits) RegisterRoutesOption {
+	return RegisterWithRateLimiters(NewRateLimiters(limits))
+}
+
+// RegisterWithRateLimiters returns a RegisterRoutesOption that rate limits the routes with the buckets
+// of limiters, e.g. to share them with the grpc api
+func RegisterWithRateLimiters(limiters *RateLimiters) RegisterRoutesOption {
+	return RegisterRoutesOption{func(ro *routesOptions) {
+		ro.rateLimits = limiters
+	}}
+}
+
+// RegisterWithMetrics returns a RegisterRoutesOption that instruments every route, reports the tracker
+// activity to metrics and serves them on URLMetrics, without authentication.
+func RegisterWithMetrics(metrics *Metrics) RegisterRoutesOption {
+	return RegisterRoutesOption{func(ro *routesOptions) {
+		ro.metrics = metrics
+	}}
+}
+
+// RegisterWithTracing returns a RegisterRoutesOption that starts a span for every request, continuing
+// W3C trace context sent by the caller, and makes the tracker create child spans with the same provider.
+func RegisterWithTracing(provider trace.TracerProvider) RegisterRoutesOption {
+	return RegisterRoutesOption{func(ro *routesOptions) {
+		ro.tracing = provider
+	}}
+}
+
+// RegisterWithAccessLog returns a RegisterRoutesOption that writes one line per request with logger.
+// Every request gets an X-Request-ID, whether or not this option is provided.
+func RegisterWithAccessLog(logger zerolog.Logger) RegisterRoutesOption {
+	return RegisterRoutesOption{func(ro *routesOptions) {
+		ro.logger = &logger
+	}}
+}
+
+// RegisterWithHealth returns a RegisterRoutesOption that serves the liveness and readiness probes
+// of health on URLHealthz and URLReadyz, without authentication. Shutdown marks health as draining.
+func RegisterWithHealth(health *Health) RegisterRoutesOption {
+	return RegisterRoutesOption{func(ro *routesOptions) {
+		ro.health = health
+	}}
+}
+
+// RegisterWithBuildInfo returns a RegisterRoutesOption that serves info on URLVersion, without authentication
+func RegisterWithBuildInfo(info BuildInfo) RegisterRoutesOption {
+	return RegisterRoutesOption{func(ro *routesOptions) {
+		ro.buildInfo = &info
+	}}
+}
+
+// RegisterWithProblemDetails returns a RegisterRoutesOption that sends every error response in the
+// RFC 7807 problem details format. Without it, only the clients accepting application/problem+json get them.
+func RegisterWithProblemDetails() RegisterRoutesOption {
+	return RegisterRoutesOption{func(ro *routesOptions) {
+		ro.problemDetails = true
+	}}
+}
+
+// routes lists every endpoint of the application together with the permission it requires
+func (api *API) routes() []route {
+	routes := []route{
+		{fiber.MethodPost, URLBidItem, PermissionBidsCreate, api.PostHandlerBidNew},
+		{fiber.MethodPost, URLBidBatch, PermissionBidsCreate, api.PostHandlerBidBatch},
+		{fiber.MethodGet, URLBidGetAll, PermissionBidsRead, api.GetHandlerBids},
+		{fiber.MethodGet, URLBidGetWinning, PermissionBidsRead, api.GetHandlerCurrentWinningBid},
+		{fiber.MethodGet, URLBidGetLeaderboard, PermissionBidsRead, api.GetHandlerLeaderboard},
+		{fiber.MethodGet, URLBidGetRank, PermissionBidsRead, api.GetHandlerUserRank},
+		{fiber.MethodGet, URLItems, PermissionItemsRead, api.GetHandlerItems},
+		{fiber.MethodPost, URLItems, PermissionItemsWrite, api.PostHandlerItemNew},
+		{fiber.MethodGet, URLItemSearch, PermissionItemsRead, api.GetHandlerItemSearch},
+		{fiber.MethodGet, URLItem, PermissionItemsRead, api.GetHandlerItem},
+		{fiber.MethodPut, URLItem, PermissionItemsWrite, api.PutHandlerItem},
+		{fiber.MethodGet, URLUserGetAllBids, PermissionBidsRead, api.GetHandlerUserBidGetAll},
+		{fiber.MethodGet, URLUserGetPortfolio, PermissionBidsRead, api.GetHandlerUserPortfolio},
+		{fiber.MethodGet, URLAnalytics, PermissionBidsRead, api.GetHandlerSystemAnalytics},
+		{fiber.MethodGet, URLAnalyticsItem, PermissionBidsRead, api.GetHandlerItemAnalytics},
+		{fiber.MethodPost, URLImportItems, PermissionDataImport, api.PostHandlerImportItems},
+		{fiber.MethodPost, URLImportBids, PermissionDataImport, api.PostHandlerImportBids},
+		{fiber.MethodGet, URLExportBids, PermissionDataExport, api.GetHandlerExportBids},
+		{fiber.MethodGet, URLAdminShillReport, PermissionItemsModerate, api.GetHandlerShillReport},
+		{fiber.MethodDelete, URLAdminShillFlags, PermissionItemsModerate, api.DeleteHandlerShillFlags},
+		// Fields needing more than reading bids check their own permission
+		{fiber.MethodPost, URLGraphQL, PermissionBidsRead, api.PostHandlerGraphQL},
+	}
+
+	if api.apiKeys != nil {
+		routes = append(routes,
+			route{fiber.MethodGet, URLAdminAPIKeys, PermissionAPIKeysManage, api.GetHandlerAPIKeys},
+			route{fiber.MethodPost, URLAdminAPIKeys, PermissionAPIKeysManage, api.PostHandlerAPIKeyNew},
+			route{fiber.MethodPost, URLAdminAPIKeyRotate, PermissionAPIKeysManage, api.PostHandlerAPIKeyRotate},
+			route{fiber.MethodDelete, URLAdminAPIKey, PermissionAPIKeysManage, api.DeleteHandlerAPIKey},
+		)
+	}
+	return routes
+}
+
 // RegisterRoutes registers all the routes available in this application
 func RegisterRoutes(api *API, options ...RegisterRoutesOption) error {
 	ro := &routesOptions{}
@@ -93,10 +272,123 @@ func RegisterRoutes(api *API, options ...RegisterRoutesOption) error {
 
 	log.Info().Msgf("Now registering %s", finalURL)
 
-	api.server.Post(prepareRoutes(finalURL, URLBidItem), api.PostHandlerBidNew)
-	api.server.Get(prepareRoutes(finalURL, URLBidGetAll), api.GetHandlerBids)
-	api.server.Get(prepareRoutes(finalURL, URLBidGetWinning), api.GetHandlerCurrentWinningBid)
-	api.server.Get(prepareRoutes(finalURL, URLUserGetAllBids), api.GetHandlerUserBidGetAll)
+	baseURLs := []string{finalURL}
+	if ro.apiVersion2 != "" {
+		apiVersion2, err := url.Parse(ro.apiVersion2)
+		if err != nil {
+			return errors.WithMessage(err, "Failed to register API version 2")
+		}
+		v2URL := path.Join(ro.proxyPrefix, apiVersion2.String())
+		if path.Join("/", v2URL) == path.Join("/", finalURL) {
+			return errors.Errorf("Failed to register API version 2 on the routes of API version %s", ro.apiVersion)
+		}
+		log.Info().Msgf("Now registering %s", v2URL)
+		baseURLs = append(baseURLs, v2URL)
+	}
+
+	api.apiKeys = ro.apiKeys
+	api.apiKeysFile = ro.apiKeysFile
+	api.policy = ro.policy
+	if api.policy == nil {
+		api.policy = DefaultPolicy()
+	}
+	if err := api.policy.Validate(); err != nil {
+		return errors.WithMessage(err, "Failed to register access policy")
+	}
+
+	if ro.logger != nil {
+		api.server.Use(requestID(*ro.logger), accessLog)
+	} else {
+		api.server.Use(requestID(log.Logger))
+	}
+	if ro.problemDetails {
+		api.server.Use(problemDetails)
+	}
+
+	schema, err := api.graphQLSchema()
+	if err != nil {
+		return errors.WithMessage(err, "Failed to register the GraphQL schema")
+	}
+	api.graphQL = &schema
+
+	routes := api.routes()
+	names := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		names[r.name()] = true
+	}
+	limiters := ro.rateLimits
+	if limiters == nil {
+		limiters = NewRateLimiters(RateLimits{})
+	}
+	for name := range limiters.limits.Routes {
+		if !names[name] {
+			return errors.Errorf("Failed to register rate limit for unknown route %s", name)
+		}
+	}
+
+	if ro.health != nil {
+		api.health = ro.health
+		api.server.Get(prepareRoutes(ro.proxyPrefix, URLHealthz), ro.health.GetHandlerHealthz)
+		api.server.Get(prepareRoutes(ro.proxyPrefix, URLReadyz), ro.health.GetHandlerReadyz)
+	}
+	if ro.buildInfo != nil {
+		api.server.Get(prepareRoutes(ro.proxyPrefix, URLVersion), GetHandlerVersion(*ro.buildInfo))
+	}
+
+	if ro.metrics != nil {
+		api.metrics = ro.metrics
+		if err := ro.metrics.observe(api.itemsBid); err != nil {
+			return err
+		}
+		api.server.Get(prepareRoutes(ro.proxyPrefix, URLMetrics), ro.metrics.GetHandlerMetrics())
+	}
+
+	if ro.tracing != nil {
+		api.itemsBid.SetTracerProvider(ro.tracing)
+	}
+
+	var authFailures fiber.Handler
+	if api.apiKeys != nil {
+		authFailures = limitAuthFailures(limiters.authFailures)
+	}
+	idempotency := newIdempotencyStore()
+	for _, r := range routes {
+		for i, baseURL := range baseURLs {
+			var handlers []fiber.Handler
+			// The first handler, so that even the failures of authentication use the v2 envelope
+			if i > 0 {
+				handlers = append(handlers, envelopeV2)
+			}
+			handlers = append(handlers, api.routeHandlers(r, ro, limiters, authFailures, idempotency)...)
+			api.server.Add(r.method, routePattern(prepareRoutes(baseURL, r.path)), handlers...)
+		}
+	}
 
 	return nil
 }
+
+// routeHandlers chains the middlewares enabled by ro before the handler of r
+func (api *API) routeHandlers(r route, ro *routesOptions, limiters *RateLimiters, authFailures fiber.Handler, idempotency *idempotencyStore) []fiber.Handler {
+	var handlers []fiber.Handler
+	if ro.tracing != nil {
+		handlers = append(handlers, traceRoute(ro.tracing, r))
+	}
+	if ro.metrics != nil {
+		handlers = append(handlers, ro.metrics.instrument(r))
+	}
+	if !r.streamsBody() {
+		handlers = append(handlers, limitBody)
+	}
+	if authFailures != nil {
+		handlers = append(handlers, authFailures)
+	}
+	handlers = append(handlers, api.authorize(r.permission))
+	if limiter := rateLimit(limiters.route(r.name())); limiter != nil {
+		handlers = append(handlers, limiter)
+	}
+	// Fingerprinting a streamed body would buffer it whole
+	if r.method == fiber.MethodPost && !r.streamsBody() {
+		handlers = append(handlers, idempotency.idempotent(r))
+	}
+	return append(handlers, r.handler)
+}
@@ -31,20 +31,122 @@ type Response struct {
 	Status  int
 	Message string
 	Data    interface{}
+	// RequestID is the X-Request-ID of the request
+	RequestID string `json:",omitempty"`
 }
 
 // ResponseBid is the response sent out in case of get handler
 type ResponseBid struct {
-	Status  int
-	Message string
-	Data    bidtracker.Bid
+	Status    int
+	Message   string
+	Data      bidtracker.Bid
+	RequestID string `json:",omitempty"`
+}
+
+// ResponseBidBatch is the response sent out in case of batch bid handler
+type ResponseBidBatch struct {
+	Status    int
+	Message   string
+	Data      bidtracker.BidBatchResult
+	RequestID string `json:",omitempty"`
 }
 
 // ResponseGetBids is the response sent out in case of get bids handler
 type ResponseGetBids struct {
-	Status  int
-	Message string
-	Data    []bidtracker.Bid
+	Status    int
+	Message   string
+	Data      []bidtracker.Bid
+	RequestID string `json:",omitempty"`
+	// NextCursor is passed as the cursor query parameter to fetch the next page
+	NextCursor string `json:",omitempty"`
+}
+
+// ResponsePortfolio is the response sent out in case of get portfolio handler
+type ResponsePortfolio struct {
+	Status    int
+	Message   string
+	Data      []bidtracker.PortfolioEntry
+	RequestID string `json:",omitempty"`
+}
+
+// ResponseLeaderboard is the response sent out in case of get leaderboard handler
+type ResponseLeaderboard struct {
+	Status    int
+	Message   string
+	Data      []bidtracker.LeaderboardEntry
+	RequestID string `json:",omitempty"`
+}
+
+// ResponseRank is the response sent out in case of get rank handler
+type ResponseRank struct {
+	Status    int
+	Message   string
+	Data      bidtracker.LeaderboardEntry
+	RequestID string `json:",omitempty"`
+}
+
+// ResponseItem is the response sent out in case of get item handler
+type ResponseItem struct {
+	Status    int
+	Message   string
+	Data      bidtracker.Item
+	RequestID string `json:",omitempty"`
+}
+
+// ResponseItems is the response sent out in case of list items handler
+type ResponseItems struct {
+	Status    int
+	Message   string
+	Data      []bidtracker.Item
+	RequestID string `json:",omitempty"`
+}
+
+// ResponseItemSearch is the response sent out in case of item search handler
+type ResponseItemSearch struct {
+	Status    int
+	Message   string
+	Data      bidtracker.ItemSearchResult
+	RequestID string `json:",omitempty"`
+}
+
+// ResponseItemAnalytics is the response sent out in case of item analytics handler
+type ResponseItemAnalytics struct {
+	Status    int
+	Message   string
+	Data      bidtracker.ItemAnalytics
+	RequestID string `json:",omitempty"`
+}
+
+// ResponseSystemAnalytics is the response sent out in case of system analytics handler
+type ResponseSystemAnalytics struct {
+	Status    int
+	Message   string
+	Data      bidtracker.SystemAnalytics
+	RequestID string `json:",omitempty"`
+}
+
+// ResponseImportReport is the response sent out in case of import handlers
+type ResponseImportReport struct {
+	Status    int
+	Message   string
+	Data      bidtracker.ImportReport
+	RequestID string `json:",omitempty"`
+}
+
+// ResponseReadiness is the response sent out in case of readiness handler
+type ResponseReadiness struct {
+	Status    int
+	Message   string
+	Data      Readiness
+	RequestID string `json:",omitempty"`
+}
+
+// ResponseBuildInfo is the response sent out in case of version handler
+type ResponseBuildInfo struct {
+	Status    int
+	Message   string
+	Data      BuildInfo
+	RequestID string `json:",omitempty"`
 }
 
 // EmptyResponse represents an empty response
@@ -54,33 +156,140 @@ var EmptyResponse = make(map[string]interface{})
 func SendJSON(c *fiber.Ctx, statusCode int, message string, data interface{}) error {
 
 	var resp interface{}
+	requestID := requestIDOf(c)
+	if statusCode >= fiber.StatusBadRequest {
+		// Keep the reason of the failure for the access log
+		c.Locals(localsErrorMessage, message)
+		if wantsProblem(c) || wantsEnvelopeV2(c) {
+			kind := errorKind{status: statusCode, code: problemCode(statusCode)}
+			return sendFailure(c, kind, message, nil, data)
+		}
+	} else if wantsEnvelopeV2(c) {
+		return sendJSONV2(c, statusCode, data, nil)
+	}
 
 	switch val := data.(type) {
 
 	case bidtracker.Bid:
 		resp = ResponseBid{
-			Status:  statusCode,
-			Message: message,
-			Data:    val,
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
 		}
 
 	case *bidtracker.Bid:
 		resp = ResponseBid{
-			Status:  statusCode,
-			Message: message,
-			Data:    *val,
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      *val,
+		}
+	case bidtracker.BidBatchResult:
+		resp = ResponseBidBatch{
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
 		}
 	case []bidtracker.Bid:
 		resp = ResponseGetBids{
-			Status:  statusCode,
-			Message: message,
-			Data:    val,
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
+		}
+	case bidtracker.BidPage:
+		resp = ResponseGetBids{
+			Status:     statusCode,
+			Message:    message,
+			RequestID:  requestID,
+			Data:       val.Bids,
+			NextCursor: val.NextCursor,
+		}
+	case []bidtracker.PortfolioEntry:
+		resp = ResponsePortfolio{
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
+		}
+	case []bidtracker.LeaderboardEntry:
+		resp = ResponseLeaderboard{
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
+		}
+	case bidtracker.LeaderboardEntry:
+		resp = ResponseRank{
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
+		}
+	case bidtracker.Item:
+		resp = ResponseItem{
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
+		}
+	case []bidtracker.Item:
+		resp = ResponseItems{
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
+		}
+	case bidtracker.ItemSearchResult:
+		resp = ResponseItemSearch{
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
+		}
+	case bidtracker.ItemAnalytics:
+		resp = ResponseItemAnalytics{
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
+		}
+	case bidtracker.SystemAnalytics:
+		resp = ResponseSystemAnalytics{
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
+		}
+	case bidtracker.ImportReport:
+		resp = ResponseImportReport{
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
+		}
+	case Readiness:
+		resp = ResponseReadiness{
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
+		}
+	case BuildInfo:
+		resp = ResponseBuildInfo{
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      val,
 		}
 	default:
 		resp = Response{
-			Status:  statusCode,
-			Message: message,
-			Data:    data,
+			Status:    statusCode,
+			Message:   message,
+			RequestID: requestID,
+			Data:      data,
 		}
 	}
 
@@ -0,0 +1,94 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+)
+
+const localsEnvelopeV2 = "envelopev2"
+
+// ResponseV2 is the envelope of the routes registered with RegisterWithAPIVersion2. Unlike Response, the
+// HTTP status is not repeated, failures carry an Error instead of a message and the request is described by Meta.
+type ResponseV2 struct {
+	// Data is the result of the request. Failures keep what was done before failing, e.g. the results of a batch.
+	Data  interface{} `json:"data,omitempty"`
+	Error *ErrorV2    `json:"error,omitempty"`
+	Meta  MetaV2      `json:"meta"`
+}
+
+// MetaV2 describes the request answered by a ResponseV2
+type MetaV2 struct {
+	// RequestID is the X-Request-ID of the request
+	RequestID string `json:"requestId,omitempty"`
+	// NextCursor is passed as the cursor query parameter to fetch the next page
+	NextCursor string    `json:"nextCursor,omitempty"`
+	ServerTime time.Time `json:"serverTime"`
+}
+
+// ErrorV2 is why a request failed, Code is the same as the code of its problem details
+type ErrorV2 struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Fields lists the invalid fields of the request
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// envelopeV2 makes the responses of the request use ResponseV2
+func envelopeV2(c *fiber.Ctx) error {
+	c.Locals(localsEnvelopeV2, true)
+	return c.Next()
+}
+
+// wantsEnvelopeV2 reports whether the request was received on a v2 route
+func wantsEnvelopeV2(c *fiber.Ctx) bool {
+	v2, _ := c.Locals(localsEnvelopeV2).(bool)
+	return v2
+}
+
+// sendJSONV2 sends the v2 payload of data in a ResponseV2, the cursor of a page goes to its Meta and
+// EmptyResponse is dropped
+func sendJSONV2(c *fiber.Ctx, statusCode int, data interface{}, failure *ErrorV2) error {
+	resp := ResponseV2{
+		Error: failure,
+		Meta: MetaV2{
+			RequestID:  requestIDOf(c),
+			ServerTime: time.Now().UTC(),
+		},
+	}
+
+	switch val := data.(type) {
+	case bidtracker.BidPage:
+		resp.Data = payloadV2(val.Bids)
+		resp.Meta.NextCursor = val.NextCursor
+	case map[string]interface{}:
+		if len(val) > 0 {
+			resp.Data = val
+		}
+	default:
+		resp.Data = payloadV2(data)
+	}
+	return c.Status(statusCode).JSON(resp)
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseV2(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New())
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1"), RegisterWithAPIVersion2("/api/v2")))
+
+	send := func(method, path, body, accept string) (*http.Response, map[string]json.RawMessage) {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Add(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		if accept != "" {
+			req.Header.Add(fiber.HeaderAccept, accept)
+		}
+		resp, _ := api.server.Test(req)
+		var fields map[string]json.RawMessage
+		assert.Nil(json.NewDecoder(resp.Body).Decode(&fields))
+		return resp, fields
+	}
+
+	for _, amount := range []string{"10", "20"} {
+		resp, fields := send("POST", "/api/v2/bids", `{"itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":`+amount+`}`, "")
+		assert.Equal(fiber.StatusOK, resp.StatusCode)
+		assert.NotContains(fields, "Status", "the status is not repeated")
+		assert.NotContains(fields, "error")
+
+		var bid BidV2
+		assert.Nil(json.Unmarshal(fields["data"], &bid))
+		assert.Equal(itemUUID, bid.ItemUUID)
+		assert.Contains(string(fields["data"]), `"itemUuid"`, "the payload is camel cased")
+		assert.NotContains(string(fields["data"]), `"itemuuid"`)
+
+		var meta MetaV2
+		assert.Nil(json.Unmarshal(fields["meta"], &meta))
+		assert.Equal(resp.Header.Get(RequestIDHeader), meta.RequestID)
+		assert.False(meta.ServerTime.IsZero())
+	}
+
+	// WHEN a page is listed, its cursor is part of the meta
+	resp, fields := send("GET", "/api/v2/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a?limit=1", "", "")
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	var bids []BidV2
+	assert.Nil(json.Unmarshal(fields["data"], &bids))
+	assert.Len(bids, 1)
+	assert.Contains(string(fields["data"]), `"userUuid"`)
+	var meta MetaV2
+	assert.Nil(json.Unmarshal(fields["meta"], &meta))
+	assert.NotEmpty(meta.NextCursor)
+
+	// THEN the v1 envelope of the same request is unchanged
+	resp, fields = send("GET", "/api/v1/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a?limit=1", "", "")
+	assert.Equal(fiber.StatusOK, resp.StatusCode)
+	for _, field := range []string{"Status", "Message", "Data", "NextCursor"} {
+		assert.Contains(fields, field)
+	}
+	assert.NotContains(fields, "meta")
+	assert.Contains(string(fields["Data"]), `"itemuuid"`)
+
+	// WHEN the request fails, the error has the code of the problem and no data is sent
+	resp, fields = send("GET", "/api/v2/bids/cef31b6b-cdeb-4035-8d42-a4f33b2d02fe/winning", "", "")
+	assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+	assert.Equal(fiber.MIMEApplicationJSON, resp.Header.Get(fiber.HeaderContentType))
+	assert.NotContains(fields, "data")
+	var failure ErrorV2
+	assert.Nil(json.Unmarshal(fields["error"], &failure))
+	assert.Equal("item_not_found", failure.Code)
+	assert.Contains(failure.Message, "Failed to fetch the current winning bid")
+
+	_, fields = send("GET", "/api/v2/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a?limit=5000", "", "")
+	assert.Nil(json.Unmarshal(fields["error"], &failure))
+	assert.Equal("invalid_argument", failure.Code)
+	assert.Equal([]FieldError{{Field: "limit", Detail: "Limit must be between 1 and 1000"}}, failure.Fields)
+
+	// WHEN the amount is not positive, the bid is invalid rather than too low
+	resp, fields = send("POST", "/api/v2/bids", `{"itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":0}`, "")
+	assert.Equal(fiber.StatusBadRequest, resp.StatusCode)
+	assert.Nil(json.Unmarshal(fields["error"], &failure))
+	assert.Equal("invalid_argument", failure.Code)
+	assert.Equal([]FieldError{{Field: "amount", Detail: "Bid amount must be positive"}}, failure.Fields)
+
+	// WHEN the amount does not beat the winning bid, it is too low
+	resp, fields = send("POST", "/api/v2/bids", `{"itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":20}`, "")
+	assert.Equal(fiber.StatusUnprocessableEntity, resp.StatusCode)
+	failure = ErrorV2{}
+	assert.Nil(json.Unmarshal(fields["error"], &failure))
+	assert.Equal("bid_too_low", failure.Code)
+	assert.Empty(failure.Fields)
+
+	// THEN what was done before failing is kept
+	body := `[{"itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":30},
+		{"itemuuid":"cef31b6b-cdeb-4035-8d42-a4f33b2d02fe","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":40}]`
+	resp, fields = send("POST", "/api/v2/bids:batch?atomic=true", body, "")
+	assert.Equal(fiber.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Nil(json.Unmarshal(fields["error"], &failure))
+	assert.Equal("unprocessable_entity", failure.Code)
+	var batch BidBatchResultV2
+	assert.Nil(json.Unmarshal(fields["data"], &batch))
+	assert.Equal(2, batch.Rejected)
+	assert.Contains(string(fields["data"]), `"itemUuid"`)
+	assert.NotContains(string(fields["data"]), `"itemuuid"`)
+
+	// WHEN an item is fetched, its payload is camel cased too
+	_, fields = send("GET", "/api/v2/items/b2f9ee6d-79fe-4b14-9c19-35a69a89219a", "", "")
+	var item map[string]json.RawMessage
+	assert.Nil(json.Unmarshal(fields["data"], &item))
+	for _, key := range []string{"itemUuid", "sellerUuid", "endTime"} {
+		assert.Contains(item, key)
+	}
+
+	// WHEN the client accepts problem details, it gets a problem on the v2 routes too
+	resp, _ = send("GET", "/api/v2/bids/cef31b6b-cdeb-4035-8d42-a4f33b2d02fe/winning", "", MIMEApplicationProblemJSON)
+	assert.Equal(MIMEApplicationProblemJSON, resp.Header.Get(fiber.HeaderContentType))
+}
+
+func TestResponseV2Middlewares(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New())
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1"), RegisterWithAPIVersion2("/api/v2"), RegisterWithAPIKeys(apikey.NewStore())))
+
+	// WHEN authentication fails on a v2 route, the error uses the v2 envelope
+	resp, _ := api.server.Test(httptest.NewRequest("GET", "/api/v2/bids/b2f9ee6d-79fe-4b14-9c19-35a69a89219a", nil))
+	assert.Equal(fiber.StatusUnauthorized, resp.StatusCode)
+	var envelope ResponseV2
+	assert.Nil(json.NewDecoder(resp.Body).Decode(&envelope))
+	if assert.NotNil(envelope.Error) {
+		assert.Equal("unauthorized", envelope.Error.Code)
+		assert.Equal("Missing api key", envelope.Error.Message)
+	}
+
+	// THEN the v2 routes can not replace the v1 routes
+	api = NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New())
+	assert.NotNil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v2"), RegisterWithAPIVersion2("/api/v2")))
+}
@@ -0,0 +1,86 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	started := make(chan struct{})
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(), fiber.New(fiber.Config{DisableStartupMessage: true}))
+	api.server.Get("/slow", func(c *fiber.Ctx) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		return c.SendString("done")
+	})
+
+	var order []string
+	api.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "first")
+		return nil
+	})
+	api.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "second")
+		return nil
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(err) {
+		return
+	}
+	go api.server.Listener(listener)
+
+	// WHEN a shutdown starts while a request is in flight
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(api.Shutdown(ctx))
+
+	// THEN the request completes, and the hooks run afterwards in reverse order
+	assert.Equal(fiber.StatusOK, <-status)
+	assert.Equal([]string{"second", "first"}, order)
+
+	_, err = http.Get("http://" + listener.Addr().String() + "/slow")
+	assert.NotNil(err, "New connections are refused")
+}
@@ -0,0 +1,84 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/ansrivas/bid-tracker/pkg/api"
+
+// propagator reads and writes W3C trace context and baggage headers
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// fiberCarrier adapts the request headers to a propagation.TextMapCarrier
+type fiberCarrier struct {
+	c *fiber.Ctx
+}
+
+func (fc fiberCarrier) Get(key string) string {
+	return fc.c.Get(key)
+}
+
+func (fc fiberCarrier) Set(key, value string) {
+	fc.c.Request().Header.Set(key, value)
+}
+
+func (fc fiberCarrier) Keys() []string {
+	var keys []string
+	fc.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// traceRoute starts a server span for every request to r, continuing the trace of the caller if any.
+// The span is stored in the user context of the request so that handlers can create children.
+func traceRoute(provider trace.TracerProvider, r route) fiber.Handler {
+	tracer := provider.Tracer(tracerName)
+	name := r.name()
+	return func(c *fiber.Ctx) error {
+		ctx := propagator.Extract(c.UserContext(), fiberCarrier{c})
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(r.method),
+				semconv.HTTPRoute(r.path),
+				semconv.URLPath(c.Path()),
+			),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		code := responseStatus(c, err)
+		span.SetAttributes(semconv.HTTPStatusCode(code))
+		if code >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, fiber.ErrInternalServerError.Message)
+		}
+		return err
+	}
+}
@@ -0,0 +1,75 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package api
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/ansrivas/bid-tracker/pkg/tracing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	assert := assert.New(t)
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewTracerProvider(exporter, tracing.Config{})
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	api := NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New())
+	assert.Nil(RegisterRoutes(api, RegisterWithAPIVersion("/api/v1"), RegisterWithTracing(provider)))
+
+	// WHEN a caller continues its trace with a bid on an unknown item
+	body := `{"itemuuid":"cef31b6b-cdeb-4035-8d42-a4f33b2d02fe","useruuid":"ae8f7716-867b-4479-b455-c5769e7475ba","amount":10}`
+	req := httptest.NewRequest("POST", "/api/v1/bids", bytes.NewBufferString(body))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, _ := api.server.Test(req)
+	assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+
+	// THEN the handler and the tracker spans belong to the trace of the caller
+	spans := exporter.GetSpans().Snapshots()
+	byName := make(map[string]int)
+	for i, span := range spans {
+		byName[span.Name()] = i
+		assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	}
+	if !assert.Len(spans, 4) {
+		return
+	}
+	server := spans[byName["POST /bids"]]
+	insert := spans[byName["BidManagement.InsertBid"]]
+	assert.Equal("00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.True(server.Parent().IsRemote())
+	assert.Equal(server.SpanContext().SpanID(), insert.Parent().SpanID())
+	assert.Equal(codes.Error, insert.Status().Code)
+	for _, name := range []string{"BidManagement.Lock", "BidManagement.validateBid"} {
+		assert.Equal(insert.SpanContext().SpanID(), spans[byName[name]].Parent().SpanID(), name)
+	}
+}
@@ -25,12 +25,78 @@ var (
 	// URLBidItem to POST bid for a given itemuuid
 	URLBidItem = "/bids"
 
+	// URLBidBatch to POST many bids at once, the colon is part of the path
+	URLBidBatch = "/bids:batch"
+
 	// URLBidGetAll to GET all the bids for this given itemuuid
 	URLBidGetAll = "/bids/:itemuuid"
 
 	// URLBidGetWinning to GET winning bids on this itemuuid
 	URLBidGetWinning = "/bids/:itemuuid/winning"
 
+	// URLBidGetLeaderboard to GET the users with the best bids on this itemuuid
+	URLBidGetLeaderboard = "/bids/:itemuuid/leaderboard"
+
+	// URLBidGetRank to GET the rank of a user on this itemuuid
+	URLBidGetRank = "/bids/:itemuuid/rank/:useruuid"
+
+	// URLItems to GET the item catalogue or POST a new item
+	URLItems = "/items"
+
+	// URLItemSearch to GET a full text search over the item catalogue with facets
+	URLItemSearch = "/items/search"
+
+	// URLItem to GET or PUT the metadata of this itemuuid
+	URLItem = "/items/:itemuuid"
+
 	// URLUserGetAllBids to GET all the bids for this user
 	URLUserGetAllBids = "/users/:useruuid/bids"
+
+	// URLUserGetPortfolio to GET the standing of this user on every item they bid on
+	URLUserGetPortfolio = "/users/:useruuid/portfolio"
+
+	// URLAnalytics to GET the analytics across all items
+	URLAnalytics = "/analytics"
+
+	// URLAnalyticsItem to GET the analytics of this itemuuid
+	URLAnalyticsItem = "/analytics/items/:itemuuid"
+
+	// URLImportItems to POST items in bulk as csv or json lines
+	URLImportItems = "/import/items"
+
+	// URLImportBids to POST historical bids in bulk as csv or json lines
+	URLImportBids = "/import/bids"
+
+	// URLExportBids to GET every bid as csv or json lines
+	URLExportBids = "/export/bids"
+
+	// URLGraphQL to POST GraphQL queries, mutations and subscriptions
+	URLGraphQL = "/graphql"
+
+	// URLMetrics to GET the prometheus metrics, it is served next to the API version instead of under it
+	URLMetrics = "/metrics"
+
+	// URLHealthz to GET the liveness of the process, served next to the API version
+	URLHealthz = "/healthz"
+
+	// URLReadyz to GET the readiness of the server, served next to the API version
+	URLReadyz = "/readyz"
+
+	// URLVersion to GET the build information, served next to the API version
+	URLVersion = "/version"
+
+	// URLAdminShillReport to GET the users flagged for shill bidding or collusion
+	URLAdminShillReport = "/admin/reports/shill"
+
+	// URLAdminShillFlags to DELETE (clear) the shill bidding flags of this useruuid
+	URLAdminShillFlags = "/admin/reports/shill/:useruuid"
+
+	// URLAdminAPIKeys to GET all api keys or POST a new one
+	URLAdminAPIKeys = "/admin/apikeys"
+
+	// URLAdminAPIKey to DELETE (revoke) an api key
+	URLAdminAPIKey = "/admin/apikeys/:keyid"
+
+	// URLAdminAPIKeyRotate to POST a rotation of an api key
+	URLAdminAPIKeyRotate = "/admin/apikeys/:keyid/rotate"
 )
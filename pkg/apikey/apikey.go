@@ -0,0 +1,329 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package apikey stores hashed api keys for machine clients.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Scope is a permission granted to an api key
+type Scope string
+
+const (
+	// ScopeBidsRead allows reading bids
+	ScopeBidsRead Scope = "bids:read"
+
+	// ScopeBidsWrite allows placing new bids
+	ScopeBidsWrite Scope = "bids:write"
+
+	// ScopeItemsWrite allows managing catalogue items
+	ScopeItemsWrite Scope = "items:write"
+
+	// ScopeAdmin allows everything, including managing api keys
+	ScopeAdmin Scope = "admin"
+)
+
+var (
+	// ErrInvalidKey is returned when a token is malformed, unknown or does not match
+	ErrInvalidKey = errors.New("invalid api key")
+
+	// ErrExpiredKey is returned when a token is past its expiry
+	ErrExpiredKey = errors.New("api key has expired")
+
+	// ErrRevokedKey is returned when a token has been revoked
+	ErrRevokedKey = errors.New("api key has been revoked")
+
+	// ErrKeyNotFound is returned when no key exists with the requested id
+	ErrKeyNotFound = errors.New("api key not found")
+)
+
+// ParseScope validates a scope string
+func ParseScope(s string) (Scope, error) {
+	switch scope := Scope(s); scope {
+	case ScopeBidsRead, ScopeBidsWrite, ScopeItemsWrite, ScopeAdmin:
+		return scope, nil
+	}
+	return "", fmt.Errorf("Unknown api key scope %q", s)
+}
+
+// Key is the server side representation of an api key.
+// The secret part of the token is never stored, only its hash.
+// Scopes restrict what the key can be used for, while Role names the
+// access policy role of the client holding it.
+type Key struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	UserUUID  uuid.UUID `json:"useruuid"`
+	Scopes    []Scope   `json:"scopes"`
+	CreatedAt time.Time `json:"createdat"`
+	ExpiresAt time.Time `json:"expiresat,omitempty"`
+	RevokedAt time.Time `json:"revokedat,omitempty"`
+
+	hash [sha256.Size]byte
+
+	// previousHash stays valid until previousValidUntil after a rotation
+	previousHash       [sha256.Size]byte
+	previousValidUntil time.Time
+}
+
+// HasScope reports whether the key grants the given scope. ScopeAdmin grants every scope.
+func (k Key) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Spec describes a key to be issued
+type Spec struct {
+	Name string
+	Role string
+	// UserUUID optionally binds the key to a user, uuid.Nil means a plain machine client
+	UserUUID uuid.UUID
+	Scopes   []Scope
+	// TTL is the lifetime of the key, zero means it never expires
+	TTL time.Duration
+}
+
+// Store keeps api keys in memory, indexed by their id
+type Store struct {
+	sync.Mutex
+	keys map[string]*Key
+	now  func() time.Time
+
+	// saving serializes Save, so that an older snapshot never replaces a newer one
+	saving sync.Mutex
+}
+
+// NewStore creates a new, empty api key store
+func NewStore() *Store {
+	return &Store{
+		keys: make(map[string]*Key),
+		now:  time.Now,
+	}
+}
+
+// Create issues a new api key. The returned token is the only time the secret is visible.
+func (s *Store) Create(spec Spec) (string, Key, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return "", Key{}, err
+	}
+	secret, err := randomSecret()
+	if err != nil {
+		return "", Key{}, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	key := s.insert(id, spec, secret)
+	return formatToken(id, secret), *key, nil
+}
+
+// Import registers an externally generated token, for instance a bootstrap admin key
+// handed over via the environment. The token must look like "<id>.<secret>".
+func (s *Store) Import(token string, spec Spec) (Key, error) {
+	id, secret, ok := parseToken(token)
+	if !ok || len(secret) < 16 {
+		return Key{}, fmt.Errorf("Api key must look like <id>.<secret> with a secret of at least 16 characters")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.keys[id]; exists {
+		return Key{}, fmt.Errorf("Api key with id %s already exists", id)
+	}
+	return *s.insert(id, spec, secret), nil
+}
+
+func (s *Store) insert(id string, spec Spec, secret string) *Key {
+	now := s.now().UTC()
+	key := &Key{
+		ID:        id,
+		Name:      spec.Name,
+		Role:      spec.Role,
+		UserUUID:  spec.UserUUID,
+		Scopes:    append([]Scope(nil), spec.Scopes...),
+		CreatedAt: now,
+		hash:      sha256.Sum256([]byte(secret)),
+	}
+	if spec.TTL > 0 {
+		key.ExpiresAt = now.Add(spec.TTL)
+	}
+	s.keys[id] = key
+	return key
+}
+
+// Authenticate validates a token and returns the matching key
+func (s *Store) Authenticate(token string) (Key, error) {
+	id, secret, ok := parseToken(token)
+	if !ok {
+		return Key{}, ErrInvalidKey
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrInvalidKey
+	}
+
+	now := s.now()
+	hash := sha256.Sum256([]byte(secret))
+	valid := subtle.ConstantTimeCompare(hash[:], key.hash[:]) == 1
+	if !valid && now.Before(key.previousValidUntil) {
+		valid = subtle.ConstantTimeCompare(hash[:], key.previousHash[:]) == 1
+	}
+	if !valid {
+		return Key{}, ErrInvalidKey
+	}
+
+	if !key.RevokedAt.IsZero() {
+		return Key{}, ErrRevokedKey
+	}
+	if !key.ExpiresAt.IsZero() && !now.Before(key.ExpiresAt) {
+		return Key{}, ErrExpiredKey
+	}
+	return *key, nil
+}
+
+// Rotate replaces the secret of an existing key and returns the new token.
+// The old secret keeps working for the given grace period, or stops immediately if grace is zero.
+// A positive ttl resets the expiry relative to now.
+func (s *Store) Rotate(id string, grace, ttl time.Duration) (string, Key, error) {
+	secret, err := randomSecret()
+	if err != nil {
+		return "", Key{}, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return "", Key{}, ErrKeyNotFound
+	}
+	if !key.RevokedAt.IsZero() {
+		return "", Key{}, ErrRevokedKey
+	}
+
+	now := s.now().UTC()
+	key.previousHash = key.hash
+	key.previousValidUntil = now.Add(grace)
+	key.hash = sha256.Sum256([]byte(secret))
+	if ttl > 0 {
+		key.ExpiresAt = now.Add(ttl)
+	}
+	return formatToken(id, secret), *key, nil
+}
+
+// Revoke permanently disables a key
+func (s *Store) Revoke(id string) (Key, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+	if key.RevokedAt.IsZero() {
+		key.RevokedAt = s.now().UTC()
+	}
+	return *key, nil
+}
+
+// Get returns the key with the given id
+func (s *Store) Get(id string) (Key, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+	return *key, nil
+}
+
+// List returns all the keys known to the store, including revoked ones, oldest first
+func (s *Store) List() []Key {
+	s.Lock()
+	defer s.Unlock()
+
+	keys := make([]Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+func formatToken(id, secret string) string {
+	return id + "." + secret
+}
+
+func parseToken(token string) (id, secret string, ok bool) {
+	id, secret, ok = strings.Cut(token, ".")
+	if !ok || id == "" || secret == "" {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("Failed to generate api key id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func randomSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("Failed to generate api key secret: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
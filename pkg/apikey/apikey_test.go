@@ -0,0 +1,135 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package apikey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewStore()
+	token, key, err := store.Create(Spec{Name: "partner", Role: "bidder", Scopes: []Scope{ScopeBidsRead}})
+	assert.Nil(err)
+	assert.True(key.HasScope(ScopeBidsRead))
+	assert.False(key.HasScope(ScopeBidsWrite))
+
+	got, err := store.Authenticate(token)
+	assert.Nil(err)
+	assert.Equal(key.ID, got.ID)
+
+	_, err = store.Authenticate(key.ID + ".wrong-secret")
+	assert.Equal(ErrInvalidKey, err)
+
+	_, err = store.Authenticate("garbage")
+	assert.Equal(ErrInvalidKey, err)
+}
+
+func TestAdminScopeGrantsEverything(t *testing.T) {
+	assert := assert.New(t)
+
+	key := Key{Scopes: []Scope{ScopeAdmin}}
+	assert.True(key.HasScope(ScopeBidsRead))
+	assert.True(key.HasScope(ScopeBidsWrite))
+}
+
+func TestExpiry(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewStore()
+	store.now = func() time.Time { return now }
+
+	token, _, err := store.Create(Spec{Name: "short-lived", Role: "bidder", Scopes: []Scope{ScopeBidsRead}, TTL: time.Minute})
+	assert.Nil(err)
+
+	_, err = store.Authenticate(token)
+	assert.Nil(err)
+
+	now = now.Add(time.Minute)
+	_, err = store.Authenticate(token)
+	assert.Equal(ErrExpiredKey, err)
+}
+
+func TestRotateWithGrace(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewStore()
+	store.now = func() time.Time { return now }
+
+	oldToken, key, err := store.Create(Spec{Name: "rotating", Role: "bidder", Scopes: []Scope{ScopeBidsWrite}})
+	assert.Nil(err)
+
+	newToken, _, err := store.Rotate(key.ID, time.Hour, 0)
+	assert.Nil(err)
+	assert.NotEqual(oldToken, newToken)
+
+	_, err = store.Authenticate(oldToken)
+	assert.Nil(err, "Old token must keep working during the grace period")
+	_, err = store.Authenticate(newToken)
+	assert.Nil(err)
+
+	now = now.Add(time.Hour)
+	_, err = store.Authenticate(oldToken)
+	assert.Equal(ErrInvalidKey, err)
+	_, err = store.Authenticate(newToken)
+	assert.Nil(err)
+
+	_, _, err = store.Rotate("unknown", 0, 0)
+	assert.Equal(ErrKeyNotFound, err)
+}
+
+func TestRevoke(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewStore()
+	token, key, err := store.Create(Spec{Name: "revoked", Role: "bidder", Scopes: []Scope{ScopeBidsRead}})
+	assert.Nil(err)
+
+	_, err = store.Revoke(key.ID)
+	assert.Nil(err)
+
+	_, err = store.Authenticate(token)
+	assert.Equal(ErrRevokedKey, err)
+
+	_, _, err = store.Rotate(key.ID, 0, 0)
+	assert.Equal(ErrRevokedKey, err)
+}
+
+func TestImport(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewStore()
+	_, err := store.Import("admin.0123456789abcdef", Spec{Name: "bootstrap", Role: "admin", Scopes: []Scope{ScopeAdmin}})
+	assert.Nil(err)
+
+	key, err := store.Authenticate("admin.0123456789abcdef")
+	assert.Nil(err)
+	assert.Equal("bootstrap", key.Name)
+	assert.Equal("admin", key.Role)
+
+	_, err = store.Import("id.short", Spec{Name: "short", Role: "admin", Scopes: []Scope{ScopeAdmin}})
+	assert.NotNil(err)
+}
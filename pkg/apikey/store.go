@@ -0,0 +1,137 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package apikey
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// record is the persisted form of a key. Secrets are never written, only their hashes.
+type record struct {
+	Key
+	Hash               string    `json:"hash"`
+	PreviousHash       string    `json:"previoushash,omitempty"`
+	PreviousValidUntil time.Time `json:"previousvaliduntil,omitempty"`
+}
+
+// Save writes every key of the store to path, along with its rotation and revocation state.
+// The file is replaced atomically.
+func (s *Store) Save(path string) error {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+
+	s.Lock()
+	records := make([]record, 0, len(s.keys))
+	for _, key := range s.keys {
+		r := record{Key: *key, Hash: hex.EncodeToString(key.hash[:])}
+		if !key.previousValidUntil.IsZero() {
+			r.PreviousHash = hex.EncodeToString(key.previousHash[:])
+			r.PreviousValidUntil = key.previousValidUntil
+		}
+		records = append(records, r)
+	}
+	s.Unlock()
+
+	content, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("Failed to encode the api keys: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("Failed to write the api keys: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to write the api keys: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to write the api keys: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to write the api keys: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("Failed to write the api keys: %w", err)
+	}
+	return nil
+}
+
+// Load reads the keys written by Save. They replace the keys of the store with the same id,
+// so that a rotated or revoked bootstrap key stays that way across restarts.
+// Nothing is loaded if any key of the file is malformed.
+func (s *Store) Load(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Failed to read the api keys: %w", err)
+	}
+	var records []record
+	if err := json.Unmarshal(content, &records); err != nil {
+		return fmt.Errorf("Failed to parse the api keys %s: %w", path, err)
+	}
+
+	keys := make([]*Key, 0, len(records))
+	for _, r := range records {
+		key := r.Key
+		if key.ID == "" {
+			return fmt.Errorf("Failed to parse the api keys %s: a key has no id", path)
+		}
+		if err := decodeHash(r.Hash, &key.hash); err != nil {
+			return fmt.Errorf("Failed to parse the hash of api key %s: %w", key.ID, err)
+		}
+		if r.PreviousHash != "" {
+			if err := decodeHash(r.PreviousHash, &key.previousHash); err != nil {
+				return fmt.Errorf("Failed to parse the previous hash of api key %s: %w", key.ID, err)
+			}
+			key.previousValidUntil = r.PreviousValidUntil
+		}
+		keys = append(keys, &key)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	for _, key := range keys {
+		s.keys[key.ID] = key
+	}
+	return nil
+}
+
+func decodeHash(s string, hash *[sha256.Size]byte) error {
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(decoded) != len(hash) {
+		return fmt.Errorf("expected %d bytes, got %d", len(hash), len(decoded))
+	}
+	copy(hash[:], decoded)
+	return nil
+}
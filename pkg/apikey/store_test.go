@@ -0,0 +1,119 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package apikey
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewStore()
+	store.now = func() time.Time { return now }
+
+	user := uuid.Must(uuid.NewV4())
+	bidderToken, bidder, err := store.Create(Spec{Name: "bidder", Role: "bidder", UserUUID: user, Scopes: []Scope{ScopeBidsWrite}})
+	assert.Nil(err)
+	oldToken, rotated, err := store.Create(Spec{Name: "rotated", Role: "bidder", Scopes: []Scope{ScopeBidsRead}, TTL: time.Hour})
+	assert.Nil(err)
+	newToken, _, err := store.Rotate(rotated.ID, time.Minute, 0)
+	assert.Nil(err)
+	revokedToken, revoked, err := store.Create(Spec{Name: "revoked", Role: "bidder"})
+	assert.Nil(err)
+	_, err = store.Revoke(revoked.ID)
+	assert.Nil(err)
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	assert.Nil(store.Save(path))
+
+	content, err := os.ReadFile(path)
+	assert.Nil(err)
+	for _, token := range []string{bidderToken, oldToken, newToken, revokedToken} {
+		_, secret, _ := strings.Cut(token, ".")
+		assert.NotContains(string(content), secret)
+	}
+
+	loaded := NewStore()
+	loaded.now = func() time.Time { return now }
+	assert.Nil(loaded.Load(path))
+	assert.Equal(store.List(), loaded.List())
+
+	got, err := loaded.Authenticate(bidderToken)
+	assert.Nil(err)
+	assert.Equal(user, got.UserUUID)
+	assert.Equal(bidder.Scopes, got.Scopes)
+
+	_, err = loaded.Authenticate(oldToken)
+	assert.Nil(err)
+	_, err = loaded.Authenticate(newToken)
+	assert.Nil(err)
+	_, err = loaded.Authenticate(revokedToken)
+	assert.Equal(ErrRevokedKey, err)
+
+	now = now.Add(2 * time.Minute)
+	_, err = loaded.Authenticate(oldToken)
+	assert.Equal(ErrInvalidKey, err)
+	_, err = loaded.Authenticate(newToken)
+	assert.Nil(err)
+}
+
+func TestLoadReplacesImportedKey(t *testing.T) {
+	assert := assert.New(t)
+
+	token := "bootstrap.0123456789abcdef0123"
+	store := NewStore()
+	_, err := store.Import(token, Spec{Name: "admin", Scopes: []Scope{ScopeAdmin}})
+	assert.Nil(err)
+	_, err = store.Revoke("bootstrap")
+	assert.Nil(err)
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	assert.Nil(store.Save(path))
+
+	restarted := NewStore()
+	_, err = restarted.Import(token, Spec{Name: "admin", Scopes: []Scope{ScopeAdmin}})
+	assert.Nil(err)
+	assert.Nil(restarted.Load(path))
+	_, err = restarted.Authenticate(token)
+	assert.Equal(ErrRevokedKey, err)
+}
+
+func TestLoadMalformed(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	assert.Nil(os.WriteFile(path, []byte(`[{"id":"a","hash":"00"}]`), 0o600))
+
+	store := NewStore()
+	assert.NotNil(store.Load(path))
+	assert.Empty(store.List())
+
+	assert.ErrorIs(store.Load(filepath.Join(t.TempDir(), "missing.json")), os.ErrNotExist)
+}
@@ -0,0 +1,275 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// DefaultAnalyticsInterval is the width of a price series bucket when none is asked for
+	DefaultAnalyticsInterval = time.Minute
+
+	// DefaultHistogramBuckets is the number of amount histogram buckets when none is asked for
+	DefaultHistogramBuckets = 10
+
+	// MaxHistogramBuckets is the largest number of amount histogram buckets
+	MaxHistogramBuckets = 100
+)
+
+// AnalyticsQuery shapes the series and histograms of the analytics. Zero values fall back to the defaults.
+type AnalyticsQuery struct {
+	Interval         time.Duration
+	HistogramBuckets int
+}
+
+// Validate checks the query for values out of range
+func (q AnalyticsQuery) Validate() error {
+	if q.Interval != 0 && q.Interval < time.Second {
+		return invalidf("interval", "Interval must be at least one second")
+	}
+	if q.HistogramBuckets < 0 || q.HistogramBuckets > MaxHistogramBuckets {
+		return invalidf("buckets", "Histogram buckets must be between 1 and %d", MaxHistogramBuckets)
+	}
+	return nil
+}
+
+func (q AnalyticsQuery) withDefaults() AnalyticsQuery {
+	if q.Interval == 0 {
+		q.Interval = DefaultAnalyticsInterval
+	}
+	if q.HistogramBuckets == 0 {
+		q.HistogramBuckets = DefaultHistogramBuckets
+	}
+	return q
+}
+
+// PricePoint summarizes the bids of one interval, starting at Start (unix seconds).
+// Intervals without bids are left out of a series.
+type PricePoint struct {
+	Start int64   `json:"start"`
+	Open  float64 `json:"open"`
+	High  float64 `json:"high"`
+	Low   float64 `json:"low"`
+	Close float64 `json:"close"`
+	Count int     `json:"count"`
+}
+
+// HistogramBucket counts the bids with an amount in [Min, Max), the last bucket includes Max
+type HistogramBucket struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// ItemAnalytics describes the bidding activity on an item
+type ItemAnalytics struct {
+	ItemUUID      uuid.UUID         `json:"itemuuid"`
+	BidCount      int               `json:"bidcount"`
+	UniqueBidders int               `json:"uniquebidders"`
+	FirstBid      int64             `json:"firstbid"`
+	LastBid       int64             `json:"lastbid"`
+	BidsPerMinute float64           `json:"bidsperminute"`
+	PriceSeries   []PricePoint      `json:"priceseries"`
+	Histogram     []HistogramBucket `json:"histogram"`
+}
+
+// SystemAnalytics describes the bidding activity across all items
+type SystemAnalytics struct {
+	ItemCount     int     `json:"itemcount"`
+	ItemsWithBids int     `json:"itemswithbids"`
+	BidCount      int     `json:"bidcount"`
+	UniqueBidders int     `json:"uniquebidders"`
+	FirstBid      int64   `json:"firstbid"`
+	LastBid       int64   `json:"lastbid"`
+	BidsPerMinute float64 `json:"bidsperminute"`
+	// LeadingVolume sums the current winning amount of every item
+	LeadingVolume float64           `json:"leadingvolume"`
+	Histogram     []HistogramBucket `json:"histogram"`
+}
+
+// ItemAnalytics computes the analytics of an item from its bids
+func (ibm *BidManagement) ItemAnalytics(itemuuid uuid.UUID, query AnalyticsQuery) (ItemAnalytics, error) {
+	if err := query.Validate(); err != nil {
+		return ItemAnalytics{}, err
+	}
+	query = query.withDefaults()
+
+	ibm.Lock()
+	itemMetaInfo, ok := ibm.itemsMap[itemuuid]
+	ibm.Unlock()
+	if !ok {
+		return ItemAnalytics{}, fmt.Errorf("%w. %s", ErrItemNotFound, itemuuid)
+	}
+	// Bids is append only, the elements seen here do not change after the lock is released
+	bids := itemMetaInfo.Bids
+
+	analytics := ItemAnalytics{
+		ItemUUID:    itemuuid,
+		BidCount:    len(bids),
+		PriceSeries: priceSeries(bids, query.Interval),
+		Histogram:   histogram([][]Bid{bids}, query.HistogramBuckets),
+	}
+	bidders := make(map[uuid.UUID]struct{})
+	for _, bid := range bids {
+		bidders[bid.UserUUID] = struct{}{}
+	}
+	analytics.UniqueBidders = len(bidders)
+	analytics.FirstBid, analytics.LastBid = timeSpan([][]Bid{bids})
+	analytics.BidsPerMinute = bidsPerMinute(len(bids), analytics.FirstBid, analytics.LastBid)
+	return analytics, nil
+}
+
+// SystemAnalytics computes the analytics across the bids on every item
+func (ibm *BidManagement) SystemAnalytics(query AnalyticsQuery) (SystemAnalytics, error) {
+	if err := query.Validate(); err != nil {
+		return SystemAnalytics{}, err
+	}
+	query = query.withDefaults()
+
+	ibm.Lock()
+	analytics := SystemAnalytics{
+		ItemCount:     len(ibm.itemsMap),
+		UniqueBidders: len(ibm.userBidMap),
+	}
+	allBids := make([][]Bid, 0, len(ibm.itemsMap))
+	for _, itemMetaInfo := range ibm.itemsMap {
+		if len(itemMetaInfo.Bids) == 0 {
+			continue
+		}
+		analytics.ItemsWithBids++
+		analytics.BidCount += len(itemMetaInfo.Bids)
+		analytics.LeadingVolume += itemMetaInfo.currentWinndingBid.Amount
+		allBids = append(allBids, itemMetaInfo.Bids)
+	}
+	ibm.Unlock()
+
+	analytics.FirstBid, analytics.LastBid = timeSpan(allBids)
+	analytics.BidsPerMinute = bidsPerMinute(analytics.BidCount, analytics.FirstBid, analytics.LastBid)
+	analytics.Histogram = histogram(allBids, query.HistogramBuckets)
+	return analytics, nil
+}
+
+func timeSpan(bidLists [][]Bid) (first, last int64) {
+	seen := false
+	for _, bids := range bidLists {
+		for _, bid := range bids {
+			if !seen || bid.Timestamp < first {
+				first = bid.Timestamp
+			}
+			if !seen || bid.Timestamp > last {
+				last = bid.Timestamp
+			}
+			seen = true
+		}
+	}
+	return first, last
+}
+
+// bidsPerMinute averages over the span between the first and last bid, but never less than a minute
+func bidsPerMinute(count int, first, last int64) float64 {
+	if count == 0 {
+		return 0
+	}
+	minutes := math.Max(float64(last-first)/60, 1)
+	return float64(count) / minutes
+}
+
+// priceSeries buckets the bids by interval, in the order they were placed within a bucket
+func priceSeries(bids []Bid, interval time.Duration) []PricePoint {
+	width := int64(interval / time.Second)
+	points := make(map[int64]*PricePoint)
+	var starts []int64
+	for _, bid := range bids {
+		start := bid.Timestamp - mod(bid.Timestamp, width)
+		point, ok := points[start]
+		if !ok {
+			point = &PricePoint{Start: start, Open: bid.Amount, High: bid.Amount, Low: bid.Amount}
+			points[start] = point
+			starts = append(starts, start)
+		}
+		point.High = math.Max(point.High, bid.Amount)
+		point.Low = math.Min(point.Low, bid.Amount)
+		point.Close = bid.Amount
+		point.Count++
+	}
+
+	series := make([]PricePoint, 0, len(starts))
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	for _, start := range starts {
+		series = append(series, *points[start])
+	}
+	return series
+}
+
+// histogram splits the range of the bid amounts into buckets of equal width
+func histogram(bidLists [][]Bid, buckets int) []HistogramBucket {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, bids := range bidLists {
+		for _, bid := range bids {
+			min = math.Min(min, bid.Amount)
+			max = math.Max(max, bid.Amount)
+		}
+	}
+	if math.IsInf(min, 1) {
+		return []HistogramBucket{}
+	}
+	if min == max {
+		buckets = 1
+	}
+
+	width := (max - min) / float64(buckets)
+	histogram := make([]HistogramBucket, buckets)
+	for i := range histogram {
+		histogram[i].Min = min + float64(i)*width
+		histogram[i].Max = min + float64(i+1)*width
+	}
+	histogram[buckets-1].Max = max
+
+	for _, bids := range bidLists {
+		for _, bid := range bids {
+			i := buckets - 1
+			if width > 0 {
+				i = int((bid.Amount - min) / width)
+			}
+			if i >= buckets {
+				i = buckets - 1
+			}
+			histogram[i].Count++
+		}
+	}
+	return histogram
+}
+
+// mod is always positive, so that bids before the epoch land in the right bucket
+func mod(a, b int64) int64 {
+	m := a % b
+	if m < 0 {
+		m += b
+	}
+	return m
+}
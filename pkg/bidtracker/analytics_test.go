@@ -0,0 +1,103 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemAnalytics(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	items := NewBidManagement(itemUUID)
+	alice, bob := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+
+	for _, bid := range []Bid{
+		{UserUUID: alice, Timestamp: 60, Amount: 10},
+		{UserUUID: bob, Timestamp: 70, Amount: 20},
+		{UserUUID: alice, Timestamp: 110, Amount: 25},
+		{UserUUID: bob, Timestamp: 300, Amount: 100},
+	} {
+		bid.ItemUUID = itemUUID
+		assert.Nil(items.InsertBid(&bid))
+	}
+
+	analytics, err := items.ItemAnalytics(itemUUID, AnalyticsQuery{HistogramBuckets: 3})
+	assert.Nil(err)
+	assert.Equal(4, analytics.BidCount)
+	assert.Equal(2, analytics.UniqueBidders)
+	assert.Equal(int64(60), analytics.FirstBid)
+	assert.Equal(int64(300), analytics.LastBid)
+	assert.Equal(1.0, analytics.BidsPerMinute)
+	assert.Equal([]PricePoint{
+		{Start: 60, Open: 10, High: 25, Low: 10, Close: 25, Count: 3},
+		{Start: 300, Open: 100, High: 100, Low: 100, Close: 100, Count: 1},
+	}, analytics.PriceSeries)
+	assert.Equal([]HistogramBucket{
+		{Min: 10, Max: 40, Count: 3},
+		{Min: 40, Max: 70, Count: 0},
+		{Min: 70, Max: 100, Count: 1},
+	}, analytics.Histogram)
+
+	analytics, err = items.ItemAnalytics(itemUUID, AnalyticsQuery{Interval: 5 * time.Minute})
+	assert.Nil(err)
+	assert.Equal(2, len(analytics.PriceSeries))
+	assert.Equal(int64(0), analytics.PriceSeries[0].Start)
+	assert.Equal(10, len(analytics.Histogram))
+
+	_, err = items.ItemAnalytics(uuid.Must(uuid.NewV4()), AnalyticsQuery{})
+	assert.NotNil(err)
+	_, err = items.ItemAnalytics(itemUUID, AnalyticsQuery{HistogramBuckets: MaxHistogramBuckets + 1})
+	assert.NotNil(err)
+}
+
+func TestSystemAnalytics(t *testing.T) {
+	assert := assert.New(t)
+
+	camera := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	lens := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+	items := NewBidManagement(camera, lens, uuid.Must(uuid.NewV4()))
+
+	analytics, err := items.SystemAnalytics(AnalyticsQuery{})
+	assert.Nil(err)
+	assert.Equal(3, analytics.ItemCount)
+	assert.Equal(0, analytics.BidCount)
+	assert.Empty(analytics.Histogram)
+
+	alice, bob := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: camera, UserUUID: alice, Timestamp: 0, Amount: 50}))
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: camera, UserUUID: bob, Timestamp: 60, Amount: 70}))
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: lens, UserUUID: alice, Timestamp: 240, Amount: 30}))
+
+	analytics, err = items.SystemAnalytics(AnalyticsQuery{HistogramBuckets: 2})
+	assert.Nil(err)
+	assert.Equal(2, analytics.ItemsWithBids)
+	assert.Equal(3, analytics.BidCount)
+	assert.Equal(2, analytics.UniqueBidders)
+	assert.Equal(100.0, analytics.LeadingVolume)
+	assert.Equal(0.75, analytics.BidsPerMinute)
+	assert.Equal([]HistogramBucket{{Min: 30, Max: 50, Count: 1}, {Min: 50, Max: 70, Count: 2}}, analytics.Histogram)
+}
@@ -0,0 +1,146 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BidResult is the outcome of a bid of a batch
+type BidResult struct {
+	Bid      Bid          `json:"bid"`
+	Accepted bool         `json:"accepted"`
+	Reason   RejectReason `json:"reason,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	// Leader is set if the bid became the current winning bid of its item
+	Leader bool `json:"leader"`
+}
+
+// BidBatchResult is the outcome of InsertBids, with a result per bid in the order they were sent
+type BidBatchResult struct {
+	Accepted int         `json:"accepted"`
+	Rejected int         `json:"rejected"`
+	Results  []BidResult `json:"results"`
+}
+
+// InsertBids inserts the bids in order while holding the lock once for the whole batch.
+// With atomic set either every bid is accepted or none is, the bids are then all validated
+// against the state before the batch. Otherwise every valid bid is accepted.
+func (ibm *BidManagement) InsertBids(bids []Bid, atomic bool) BidBatchResult {
+	return ibm.InsertBidsContext(context.Background(), bids, atomic)
+}
+
+// InsertBidsContext is InsertBids tracing the wait for the lock and the validation of each bid
+// as children of the span in ctx.
+func (ibm *BidManagement) InsertBidsContext(ctx context.Context, bids []Bid, atomic bool) BidBatchResult {
+	tracer := ibm.tracer()
+	ctx, span := tracer.Start(ctx, "BidManagement.InsertBids", trace.WithAttributes(
+		attribute.Int("bids.count", len(bids)),
+		attribute.Bool("bids.atomic", atomic),
+	))
+
+	_, lockSpan := tracer.Start(ctx, "BidManagement.Lock")
+	ibm.Lock()
+	lockSpan.End()
+	defer ibm.Unlock()
+
+	result := BidBatchResult{Results: make([]BidResult, len(bids))}
+	if atomic {
+		ibm.insertBidsAtomic(ctx, bids, result.Results)
+	} else {
+		for i := range bids {
+			result.Results[i] = ibm.insertBatchBid(ctx, bids[i])
+		}
+	}
+
+	for _, bidResult := range result.Results {
+		if bidResult.Accepted {
+			result.Accepted++
+		} else {
+			result.Rejected++
+		}
+	}
+	span.SetAttributes(attribute.Int("bids.accepted", result.Accepted))
+	span.End()
+	return result
+}
+
+// insertBatchBid validates and applies a single bid of a batch, it must be called with the lock held
+func (ibm *BidManagement) insertBatchBid(ctx context.Context, bid Bid) BidResult {
+	itemMetaInfo, err := ibm.validateBid(ctx, &bid)
+	if err != nil {
+		return rejectedResult(bid, err)
+	}
+	return ibm.applyBatchBid(itemMetaInfo, bid)
+}
+
+// insertBidsAtomic applies every bid or none of them, it must be called with the lock held
+func (ibm *BidManagement) insertBidsAtomic(ctx context.Context, bids []Bid, results []BidResult) {
+	failed := false
+	// Every bid must also be above the earlier bids of the batch on its item, which are not applied yet
+	highest := make(map[uuid.UUID]float64)
+	for i := range bids {
+		bid := &bids[i]
+		_, err := ibm.validateBid(ctx, bid)
+		if amount, ok := highest[bid.ItemUUID]; err == nil && ok && bid.Amount <= amount {
+			err = ibm.reject(bid, RejectBidTooLow, bidTooLow(bid.ItemUUID, amount))
+		}
+		if err != nil {
+			results[i] = rejectedResult(*bid, err)
+			failed = true
+			continue
+		}
+		highest[bid.ItemUUID] = bid.Amount
+	}
+
+	for i := range bids {
+		switch {
+		case failed && results[i].Reason == "":
+			results[i] = rejectedResult(bids[i], ibm.reject(&bids[i], RejectBatchAborted, ErrBatchAborted))
+		case !failed:
+			// Earlier bids of the batch may have changed the item, it is looked up again
+			results[i] = ibm.applyBatchBid(ibm.itemsMap[bids[i].ItemUUID], bids[i])
+		}
+	}
+}
+
+// applyBatchBid records a valid bid, it must be called with the lock held
+func (ibm *BidManagement) applyBatchBid(itemMetaInfo ItemBidState, bid Bid) BidResult {
+	leader := ibm.applyBid(itemMetaInfo, &bid)
+	if ibm.observer != nil {
+		ibm.observer.BidAccepted(bid)
+	}
+	ibm.notifyWatches(bid, leader)
+	return BidResult{Bid: bid, Accepted: true, Leader: leader}
+}
+
+func rejectedResult(bid Bid, err error) BidResult {
+	result := BidResult{Bid: bid, Error: err.Error()}
+	if rejected, ok := err.(*BidRejectedError); ok {
+		result.Reason = rejected.Reason
+	}
+	return result
+}
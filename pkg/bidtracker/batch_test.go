@@ -0,0 +1,104 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertBidsBestEffort(t *testing.T) {
+	assert := assert.New(t)
+
+	open := uuid.Must(uuid.NewV4())
+	closed := uuid.Must(uuid.NewV4())
+	items := NewBidManagement(open, closed)
+	assert.Nil(items.SetAuctionEnd(closed, 1))
+	user := uuid.Must(uuid.NewV4())
+
+	result := items.InsertBids([]Bid{
+		{ItemUUID: open, UserUUID: user, Amount: 10},
+		{ItemUUID: uuid.Must(uuid.NewV4()), UserUUID: user, Amount: 50},
+		{ItemUUID: open, UserUUID: user, Amount: 5},
+		{ItemUUID: open, UserUUID: user, Amount: 20},
+		{ItemUUID: closed, UserUUID: user, Amount: 30},
+	}, false)
+
+	assert.Equal(2, result.Accepted)
+	assert.Equal(3, result.Rejected)
+	var accepted, leaders []bool
+	var reasons []RejectReason
+	for _, bidResult := range result.Results {
+		accepted = append(accepted, bidResult.Accepted)
+		leaders = append(leaders, bidResult.Leader)
+		reasons = append(reasons, bidResult.Reason)
+	}
+	assert.Equal([]bool{true, false, false, true, false}, accepted)
+	assert.Equal([]bool{true, false, false, true, false}, leaders)
+	assert.Equal([]RejectReason{"", RejectUnknownItem, RejectBidTooLow, "", RejectAuctionClosed}, reasons)
+	assert.NotEmpty(result.Results[1].Error)
+
+	winning, err := items.CurrentWinningBid(open)
+	assert.Nil(err)
+	assert.Equal(20.0, winning.Amount)
+	bids, _ := items.GetBidsByUser(user)
+	assert.Len(bids, 2)
+}
+
+func TestInsertBidsAtomic(t *testing.T) {
+	assert := assert.New(t)
+
+	itemuuid := uuid.Must(uuid.NewV4())
+	items := NewBidManagement(itemuuid)
+	user := uuid.Must(uuid.NewV4())
+
+	result := items.InsertBids([]Bid{
+		{ItemUUID: itemuuid, UserUUID: user, Amount: 10},
+		{ItemUUID: uuid.Must(uuid.NewV4()), UserUUID: user, Amount: 50},
+	}, true)
+	assert.Equal(0, result.Accepted)
+	assert.Equal(RejectBatchAborted, result.Results[0].Reason)
+	assert.Equal(RejectUnknownItem, result.Results[1].Reason)
+	bids, _ := items.GetBids(itemuuid)
+	assert.Empty(bids)
+
+	// Bids must be above the earlier bids of the batch as well
+	result = items.InsertBids([]Bid{
+		{ItemUUID: itemuuid, UserUUID: user, Amount: 10},
+		{ItemUUID: itemuuid, UserUUID: user, Amount: 15},
+		{ItemUUID: itemuuid, UserUUID: user, Amount: 12},
+	}, true)
+	assert.Equal(0, result.Accepted)
+	assert.Equal(RejectBidTooLow, result.Results[2].Reason)
+	assert.Contains(result.Results[2].Error, ErrBidTooLow.Error())
+
+	result = items.InsertBids([]Bid{
+		{ItemUUID: itemuuid, UserUUID: user, Amount: 10},
+		{ItemUUID: itemuuid, UserUUID: user, Amount: 15},
+	}, true)
+	assert.Equal(2, result.Accepted)
+	assert.True(result.Results[1].Leader)
+	winning, err := items.CurrentWinningBid(itemuuid)
+	assert.Nil(err)
+	assert.Equal(15.0, winning.Amount)
+}
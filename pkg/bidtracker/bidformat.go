@@ -0,0 +1,242 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// FormatJSONL is one json object per line
+	FormatJSONL = "jsonl"
+
+	// FormatCSV is comma separated values with a header row
+	FormatCSV = "csv"
+)
+
+// bidCSVHeader are the columns of bids in csv
+var bidCSVHeader = []string{"itemuuid", "useruuid", "timestamp", "amount"}
+
+// BidWriter streams bids in one of the export formats
+type BidWriter interface {
+	Write(bid Bid) error
+	// Flush writes any buffered data, it must be called once done
+	Flush() error
+}
+
+// NewBidWriter returns a BidWriter writing format to w
+func NewBidWriter(w io.Writer, format string) (BidWriter, error) {
+	switch format {
+	case FormatJSONL:
+		buffered := bufio.NewWriter(w)
+		return &jsonlBidWriter{buffered: buffered, encoder: json.NewEncoder(buffered)}, nil
+	case FormatCSV:
+		return &csvBidWriter{writer: csv.NewWriter(w)}, nil
+	}
+	return nil, invalidf("format", "Unknown format %q, expected %s or %s", format, FormatJSONL, FormatCSV)
+}
+
+type jsonlBidWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+func (w *jsonlBidWriter) Write(bid Bid) error {
+	return w.encoder.Encode(bid)
+}
+
+func (w *jsonlBidWriter) Flush() error {
+	return w.buffered.Flush()
+}
+
+type csvBidWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (w *csvBidWriter) Write(bid Bid) error {
+	if !w.headerWritten {
+		if err := w.writer.Write(bidCSVHeader); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+	return w.writer.Write([]string{
+		bid.ItemUUID.String(),
+		bid.UserUUID.String(),
+		strconv.FormatInt(bid.Timestamp, 10),
+		strconv.FormatFloat(bid.Amount, 'f', -1, 64),
+	})
+}
+
+func (w *csvBidWriter) Flush() error {
+	if !w.headerWritten {
+		if err := w.writer.Write(bidCSVHeader); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// RowError is returned for a malformed row, the rows after it can still be read
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("Line %d is not valid. %s", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// BidReader streams bids from one of the export formats
+type BidReader interface {
+	// Read returns the next bid, or io.EOF once there are no more.
+	// A malformed row returns a *RowError, any other error stops the reader.
+	Read() (Bid, error)
+	// Line is the line of the last bid read, starting at 1
+	Line() int
+}
+
+// NewBidReader returns a BidReader reading format from r
+func NewBidReader(r io.Reader, format string) (BidReader, error) {
+	switch format {
+	case FormatJSONL:
+		return &jsonlBidReader{scanner: bufio.NewScanner(r)}, nil
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.ReuseRecord = true
+		return &csvBidReader{reader: reader}, nil
+	}
+	return nil, invalidf("format", "Unknown format %q, expected %s or %s", format, FormatJSONL, FormatCSV)
+}
+
+type jsonlBidReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *jsonlBidReader) Read() (Bid, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := strings.TrimSpace(r.scanner.Text())
+		if text == "" {
+			continue
+		}
+		var bid Bid
+		if err := json.Unmarshal([]byte(text), &bid); err != nil {
+			return bid, &RowError{Line: r.line, Err: err}
+		}
+		return bid, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Bid{}, err
+	}
+	return Bid{}, io.EOF
+}
+
+func (r *jsonlBidReader) Line() int {
+	return r.line
+}
+
+type csvBidReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	line    int
+	// headerErr is returned by every Read once the header turned out to be invalid
+	headerErr error
+}
+
+func (r *csvBidReader) Read() (Bid, error) {
+	if r.headerErr != nil {
+		return Bid{}, r.headerErr
+	}
+	if r.columns == nil {
+		header, err := r.reader.Read()
+		if err != nil {
+			return Bid{}, err
+		}
+		r.line++
+		columns := make(map[string]int, len(header))
+		for i, name := range header {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		for _, name := range bidCSVHeader {
+			if _, ok := columns[name]; !ok {
+				r.headerErr = invalidf("", "Column %s is missing from the csv header", name)
+				return Bid{}, r.headerErr
+			}
+		}
+		r.columns = columns
+	}
+
+	record, err := r.reader.Read()
+	if err != nil {
+		if parseErr, ok := err.(*csv.ParseError); ok {
+			r.line = parseErr.Line
+			return Bid{}, &RowError{Line: r.line, Err: parseErr.Err}
+		}
+		return Bid{}, err
+	}
+	r.line, _ = r.reader.FieldPos(0)
+
+	field := func(name string) string {
+		if i := r.columns[name]; i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var bid Bid
+	if bid.ItemUUID, err = uuid.FromString(field("itemuuid")); err != nil {
+		return bid, &RowError{Line: r.line, Err: fmt.Errorf("Invalid itemuuid. %s", err)}
+	}
+	if bid.UserUUID, err = uuid.FromString(field("useruuid")); err != nil {
+		return bid, &RowError{Line: r.line, Err: fmt.Errorf("Invalid useruuid. %s", err)}
+	}
+	if bid.Timestamp, err = strconv.ParseInt(field("timestamp"), 10, 64); err != nil {
+		return bid, &RowError{Line: r.line, Err: fmt.Errorf("Invalid timestamp. %s", err)}
+	}
+	if bid.Amount, err = strconv.ParseFloat(field("amount"), 64); err != nil {
+		return bid, &RowError{Line: r.line, Err: fmt.Errorf("Invalid amount. %s", err)}
+	}
+	return bid, nil
+}
+
+func (r *csvBidReader) Line() int {
+	return r.line
+}
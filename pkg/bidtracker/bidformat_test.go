@@ -0,0 +1,112 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func readBids(reader BidReader) (bids []Bid, errs []error) {
+	for {
+		bid, err := reader.Read()
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			errs = append(errs, err)
+			continue
+		}
+		if err != nil {
+			return bids, errs
+		}
+		bids = append(bids, bid)
+	}
+}
+
+func TestBidFormatsRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	bids := []Bid{
+		{ItemUUID: uuid.Must(uuid.NewV4()), UserUUID: uuid.Must(uuid.NewV4()), Timestamp: 10, Amount: 1.25},
+		{ItemUUID: uuid.Must(uuid.NewV4()), UserUUID: uuid.Must(uuid.NewV4()), Timestamp: 20, Amount: 300},
+	}
+	for _, format := range []string{FormatJSONL, FormatCSV} {
+		var buffer bytes.Buffer
+		writer, err := NewBidWriter(&buffer, format)
+		assert.Nil(err)
+		for _, bid := range bids {
+			assert.Nil(writer.Write(bid))
+		}
+		assert.Nil(writer.Flush())
+
+		reader, err := NewBidReader(&buffer, format)
+		assert.Nil(err)
+		read, errs := readBids(reader)
+		assert.Empty(errs, format)
+		assert.Equal(bids, read, format)
+	}
+
+	_, err := NewBidWriter(io.Discard, "xml")
+	assert.NotNil(err)
+	_, err = NewBidReader(strings.NewReader(""), "xml")
+	assert.NotNil(err)
+}
+
+func TestBidReaderErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	item := uuid.Must(uuid.NewV4())
+	user := uuid.Must(uuid.NewV4())
+
+	// Columns may come in any order, malformed rows are reported with their line
+	reader, err := NewBidReader(strings.NewReader("amount,timestamp,useruuid,itemuuid\n"+
+		"5,1,"+user.String()+","+item.String()+"\n"+
+		"five,2,"+user.String()+","+item.String()+"\n"), FormatCSV)
+	assert.Nil(err)
+	bids, errs := readBids(reader)
+	assert.Equal([]Bid{{ItemUUID: item, UserUUID: user, Timestamp: 1, Amount: 5}}, bids)
+	if assert.Len(errs, 1) {
+		assert.Contains(errs[0].Error(), "Line 3")
+	}
+
+	reader, err = NewBidReader(strings.NewReader("itemuuid,amount\n"), FormatCSV)
+	assert.Nil(err)
+	_, err = reader.Read()
+	assert.NotNil(err)
+	assert.NotEqual(io.EOF, err)
+	var rowErr *RowError
+	assert.False(errors.As(err, &rowErr), "an invalid header stops the reader")
+	_, second := reader.Read()
+	assert.Equal(err, second)
+
+	reader, err = NewBidReader(strings.NewReader("{}\n\nnot json\n"), FormatJSONL)
+	assert.Nil(err)
+	bids, errs = readBids(reader)
+	assert.Len(bids, 1)
+	if assert.Len(errs, 1) {
+		assert.Contains(errs[0].Error(), "Line 3")
+	}
+}
@@ -22,10 +22,15 @@
 package bidtracker
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ItemBidState represents the current state of an item
@@ -33,6 +38,16 @@ type ItemBidState struct {
 	ItemID             uuid.UUID
 	Bids               []Bid
 	currentWinndingBid *Bid
+
+	// Item holds the catalogue metadata, only its UUID is set for items created by NewBidManagement
+	Item Item
+
+	leaderboard *leaderboard
+}
+
+// closed reports whether the auction of the item is over
+func (state ItemBidState) closed(now time.Time) bool {
+	return state.Item.EndTime != 0 && now.Unix() >= state.Item.EndTime
 }
 
 // UserBids represents the state of bids for a user
@@ -45,6 +60,18 @@ type BidManagement struct {
 	sync.Mutex
 	itemsMap   map[uuid.UUID]ItemBidState
 	userBidMap map[uuid.UUID]UserBids
+
+	shillDetector *ShillDetector
+	// shillStats are kept up to date by applyBid once a blocking detector needs them, nil until then
+	shillStats *shillStats
+	// shillCleared holds the users a moderator cleared of shill bidding, they are neither blocked nor reported
+	shillCleared map[uuid.UUID]bool
+	searchIndex  *searchIndex
+	observer     Observer
+	watches      map[uuid.UUID]map[*Watch]struct{}
+	// tracerProvider holds a tracerProviderHolder once SetTracerProvider is called
+	tracerProvider atomic.Value
+	now            func() time.Time
 }
 
 // NewBidManagement creates a new instance of BidManagement struct
@@ -54,13 +81,18 @@ func NewBidManagement(allowedItemUUIDs ...uuid.UUID) *BidManagement {
 	for i := 0; i < len(allowedItemUUIDs); i++ {
 		itemID := allowedItemUUIDs[i]
 		itemsMap[itemID] = ItemBidState{
-			ItemID: itemID,
-			Bids:   []Bid{},
+			ItemID:      itemID,
+			Bids:        []Bid{},
+			Item:        Item{UUID: itemID},
+			leaderboard: newLeaderboard(),
 		}
 	}
 	return &BidManagement{
-		itemsMap:   itemsMap,
-		userBidMap: useBidMap,
+		itemsMap:     itemsMap,
+		userBidMap:   useBidMap,
+		shillCleared: make(map[uuid.UUID]bool),
+		searchIndex:  newSearchIndex(),
+		now:          time.Now,
 	}
 }
 
@@ -71,33 +103,60 @@ func (ibm *BidManagement) CurrentWinningBid(itemuuid uuid.UUID) (*Bid, error) {
 
 	itemMetaInfo, ok := ibm.itemsMap[itemuuid]
 	if !ok {
-		return nil, fmt.Errorf("Requested item is not available for bidding. %s", itemuuid)
+		return nil, fmt.Errorf("%w. %s", ErrItemNotFound, itemuuid)
 	}
 
 	if itemMetaInfo.currentWinndingBid != nil {
 		return itemMetaInfo.currentWinndingBid, nil
 	}
 
-	return nil, fmt.Errorf("No currentbid found for requested uuid %s", itemuuid)
+	return nil, fmt.Errorf("%w for requested uuid %s", ErrNoBids, itemuuid)
 }
 
 // InsertBid a new bid for the provided item.
 func (ibm *BidManagement) InsertBid(bid *Bid) error {
+	return ibm.InsertBidContext(context.Background(), bid)
+}
+
+// InsertBidContext inserts a new bid for the provided item, tracing the wait for the lock
+// and the validation of the bid as children of the span in ctx.
+func (ibm *BidManagement) InsertBidContext(ctx context.Context, bid *Bid) (err error) {
+	tracer := ibm.tracer()
+	ctx, span := tracer.Start(ctx, "BidManagement.InsertBid", trace.WithAttributes(
+		attribute.String("bid.itemuuid", bid.ItemUUID.String()),
+		attribute.String("bid.useruuid", bid.UserUUID.String()),
+		attribute.Float64("bid.amount", bid.Amount),
+	))
+	defer func() { endSpan(span, err) }()
+
+	_, lockSpan := tracer.Start(ctx, "BidManagement.Lock")
 	ibm.Lock()
+	lockSpan.End()
 	defer ibm.Unlock()
 
-	itemMetaInfo, ok := ibm.itemsMap[bid.ItemUUID]
-	if !ok {
-		return fmt.Errorf("Requested item is not available for bidding. %s", bid.ItemUUID)
+	itemMetaInfo, err := ibm.validateBid(ctx, bid)
+	if err != nil {
+		return err
 	}
 
-	// Update the current winning bid
-	if itemMetaInfo.currentWinndingBid == nil {
-		itemMetaInfo.currentWinndingBid = bid
-	} else {
-		if itemMetaInfo.currentWinndingBid.Amount < bid.Amount {
-			itemMetaInfo.currentWinndingBid = bid
-		}
+	leader := ibm.applyBid(itemMetaInfo, bid)
+
+	if ibm.observer != nil {
+		ibm.observer.BidAccepted(*bid)
+	}
+	ibm.notifyWatches(*bid, leader)
+	return nil
+}
+
+// applyBid records a valid bid and reports whether it became the current winning bid,
+// it must be called with the lock held
+func (ibm *BidManagement) applyBid(itemMetaInfo ItemBidState, bid *Bid) (leader bool) {
+	previousLeader := itemMetaInfo.currentWinndingBid
+	// Update the current winning bid, with a copy so that callers reusing bid can not change it
+	if itemMetaInfo.currentWinndingBid == nil || itemMetaInfo.currentWinndingBid.Amount < bid.Amount {
+		winning := *bid
+		itemMetaInfo.currentWinndingBid = &winning
+		leader = true
 	}
 
 	// Update the user-section
@@ -115,9 +174,45 @@ func (ibm *BidManagement) InsertBid(bid *Bid) error {
 
 	}
 
+	itemMetaInfo.leaderboard.update(*bid, len(itemMetaInfo.Bids))
 	itemMetaInfo.Bids = append(itemMetaInfo.Bids, *bid)
 	ibm.itemsMap[bid.ItemUUID] = itemMetaInfo
-	return nil
+	if ibm.shillStats != nil {
+		ibm.shillStats.add(sellerOf(itemMetaInfo), *bid, previousLeader, leader)
+	}
+	return leader
+}
+
+// validateBid checks that the bid can be placed, it must be called with the lock held
+func (ibm *BidManagement) validateBid(ctx context.Context, bid *Bid) (itemMetaInfo ItemBidState, err error) {
+	_, span := ibm.tracer().Start(ctx, "BidManagement.validateBid")
+	defer func() { endSpan(span, err) }()
+
+	if bid.Amount <= 0 {
+		return itemMetaInfo, invalidf("amount", "Bid amount must be positive")
+	}
+
+	itemMetaInfo, ok := ibm.itemsMap[bid.ItemUUID]
+	if !ok {
+		return itemMetaInfo, ibm.reject(bid, RejectUnknownItem, fmt.Errorf("%w. %s", ErrItemNotFound, bid.ItemUUID))
+	}
+
+	if itemMetaInfo.closed(ibm.now()) {
+		return itemMetaInfo, ibm.reject(bid, RejectAuctionClosed, fmt.Errorf("%w. %s", ErrAuctionClosed, bid.ItemUUID))
+	}
+
+	if winning := itemMetaInfo.currentWinndingBid; winning != nil && bid.Amount <= winning.Amount {
+		return itemMetaInfo, ibm.reject(bid, RejectBidTooLow, bidTooLow(bid.ItemUUID, winning.Amount))
+	}
+
+	if err := ibm.checkShillBid(bid); err != nil {
+		return itemMetaInfo, ibm.reject(bid, RejectShillBid, err)
+	}
+	return itemMetaInfo, nil
+}
+
+func bidTooLow(itemuuid uuid.UUID, winning float64) error {
+	return fmt.Errorf("%w, it must be above the winning bid of %v. %s", ErrBidTooLow, winning, itemuuid)
 }
 
 // GetBids get bids for a given item
@@ -127,7 +222,7 @@ func (ibm *BidManagement) GetBids(itemuuid uuid.UUID) ([]Bid, error) {
 
 	itemMetaInfo, ok := ibm.itemsMap[itemuuid]
 	if !ok {
-		return nil, fmt.Errorf("Requested item is not available for bidding. %s", itemuuid)
+		return nil, fmt.Errorf("%w. %s", ErrItemNotFound, itemuuid)
 	}
 	return itemMetaInfo.Bids, nil
 }
@@ -139,8 +234,30 @@ func (ibm *BidManagement) GetBidsByUser(useruuid uuid.UUID) ([]Bid, error) {
 
 	userBidInfo, ok := ibm.userBidMap[useruuid]
 	if !ok {
-		return nil, fmt.Errorf("No user found with uuid%v", useruuid)
+		return nil, fmt.Errorf("%w with uuid %s", ErrUserNotFound, useruuid)
 	}
 
 	return userBidInfo.Bids, nil
 }
+
+// SetClock replaces the clock that decides whether auctions are closed, e.g. to replay old bids
+func (ibm *BidManagement) SetClock(now func() time.Time) {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	ibm.now = now
+}
+
+// SetAuctionEnd sets the unix timestamp at which bidding on the item closes, 0 keeps it open forever
+func (ibm *BidManagement) SetAuctionEnd(itemuuid uuid.UUID, endTime int64) error {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	itemMetaInfo, ok := ibm.itemsMap[itemuuid]
+	if !ok {
+		return fmt.Errorf("%w. %s", ErrItemNotFound, itemuuid)
+	}
+	itemMetaInfo.Item.EndTime = endTime
+	ibm.itemsMap[itemuuid] = itemMetaInfo
+	return nil
+}
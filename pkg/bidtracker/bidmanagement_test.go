@@ -62,6 +62,7 @@ func TestInsert(t *testing.T) {
 	assert.Nil(err, fmt.Sprintf("Failed to insert new bid"))
 	assert.Equal(1, len(items.itemsMap[itemUUID2].Bids))
 
+	bid2.Amount = 40.0
 	err = items.InsertBid(&bid2)
 	assert.Nil(err, fmt.Sprintf("Failed to insert new bid"))
 	assert.Equal(2, len(items.itemsMap[itemUUID2].Bids))
@@ -108,6 +109,7 @@ func TestGetBids(t *testing.T) {
 	assert.Nil(err, fmt.Sprintf("Failed to fetch all the bids for itemUUID2"))
 	assert.Equal(1, len(bids))
 
+	bid2.Amount = 40.0
 	err = items.InsertBid(&bid2)
 	assert.Nil(err, fmt.Sprintf("Failed to insert new bid"))
 	bids, err = items.GetBids(itemUUID2)
@@ -32,4 +32,6 @@ type BidTracker interface {
 	CurrentWinningBid(itemID uuid.UUID) (Bid, error)
 	GetBids(itemID uuid.UUID) ([]Bid, error)
 	GetBidsByUser(userID uuid.UUID) ([]Bid, error)
+	ListBids(itemID uuid.UUID, query BidQuery) (BidPage, error)
+	ListBidsByUser(userID uuid.UUID, query BidQuery) (BidPage, error)
 }
@@ -0,0 +1,92 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// The errors returned by BidManagement wrap one of these, so callers can tell them apart with errors.Is
+var (
+	// ErrItemNotFound is returned for items the tracker does not hold
+	ErrItemNotFound = errors.New("Requested item is not available for bidding")
+
+	// ErrItemExists is returned when adding an item the tracker already holds
+	ErrItemExists = errors.New("Item already exists")
+
+	// ErrUserNotFound is returned for users who never placed a bid
+	ErrUserNotFound = errors.New("No user found")
+
+	// ErrNoBids is returned when an item, or a user on an item, has no bid yet
+	ErrNoBids = errors.New("No bid found")
+
+	// ErrAuctionClosed is returned for bids placed after the end of the auction
+	ErrAuctionClosed = errors.New("Auction for the requested item is closed")
+
+	// ErrBidTooLow is returned for bids whose amount is not above the current winning bid
+	ErrBidTooLow = errors.New("Bid amount is too low")
+
+	// ErrAuctionEndLocked is returned when the end of an auction that has bids or has closed is changed
+	ErrAuctionEndLocked = errors.New("The end of the auction can not change once it has bids or has closed")
+
+	// ErrShillBid is returned for bids of a user flagged by a blocking shill detector
+	ErrShillBid = errors.New("Bid rejected for shill bidding")
+
+	// ErrBatchAborted is returned for valid bids of an all-or-nothing batch another bid of which was rejected
+	ErrBatchAborted = errors.New("Bid not applied, another bid of the batch was rejected")
+
+	// ErrInvalidArgument is matched by the errors of queries, items and imports holding invalid values
+	ErrInvalidArgument = errors.New("Invalid argument")
+)
+
+// InvalidArgumentError keeps the message of Err while matching ErrInvalidArgument
+type InvalidArgumentError struct {
+	// Field names the invalid query parameter or json field, empty if the error is not about a single one
+	Field string
+	Err   error
+}
+
+func (e *InvalidArgumentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *InvalidArgumentError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrInvalidArgument
+func (e *InvalidArgumentError) Is(target error) bool {
+	return target == ErrInvalidArgument
+}
+
+// invalid marks err as an invalid value of field
+func invalid(field string, err error) error {
+	return &InvalidArgumentError{Field: field, Err: err}
+}
+
+// invalidf formats an invalid value of field
+func invalidf(field string, format string, args ...interface{}) error {
+	return invalid(field, fmt.Errorf(format, args...))
+}
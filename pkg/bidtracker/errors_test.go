@@ -0,0 +1,93 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	unknownUUID := uuid.Must(uuid.FromString("cef31b6b-cdeb-4035-8d42-a4f33b2d02fe"))
+	userUUID := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+	items := NewBidManagement(itemUUID)
+
+	// WHEN an item has no bid yet
+	_, err := items.CurrentWinningBid(itemUUID)
+	assert.True(errors.Is(err, ErrNoBids))
+	_, err = items.UserRank(itemUUID, userUUID)
+	assert.True(errors.Is(err, ErrNoBids))
+	_, err = items.GetBidsByUser(userUUID)
+	assert.True(errors.Is(err, ErrUserNotFound))
+
+	// WHEN the item is unknown, the rejection still tells the cause
+	err = items.InsertBid(&Bid{ItemUUID: unknownUUID, UserUUID: userUUID, Amount: 10})
+	var rejected *BidRejectedError
+	assert.True(errors.As(err, &rejected))
+	assert.Equal(RejectUnknownItem, rejected.Reason)
+	assert.True(errors.Is(err, ErrItemNotFound))
+	assert.Equal("Requested item is not available for bidding. "+unknownUUID.String(), err.Error())
+
+	// WHEN the amount is not positive, it is invalid
+	err = items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: userUUID, Amount: 0})
+	assert.True(errors.Is(err, ErrInvalidArgument))
+	assert.False(errors.Is(err, ErrBidTooLow))
+	var invalid *InvalidArgumentError
+	assert.True(errors.As(err, &invalid))
+	assert.Equal("amount", invalid.Field)
+
+	// WHEN the amount is not above the winning bid
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: userUUID, Amount: 10}))
+	err = items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: userUUID, Amount: 10})
+	assert.True(errors.As(err, &rejected))
+	assert.Equal(RejectBidTooLow, rejected.Reason)
+	assert.True(errors.Is(err, ErrBidTooLow))
+	assert.False(errors.Is(err, ErrInvalidArgument))
+	assert.Equal("Bid amount is too low, it must be above the winning bid of 10. "+itemUUID.String(), err.Error())
+
+	// WHEN the auction is closed
+	assert.Nil(items.SetAuctionEnd(itemUUID, 1))
+	err = items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: userUUID, Amount: 20})
+	assert.True(errors.Is(err, ErrAuctionClosed))
+	assert.False(errors.Is(err, ErrItemNotFound))
+
+	// WHEN the item exists already
+	err = items.AddItem(Item{UUID: itemUUID, Title: "Vintage camera"})
+	assert.True(errors.Is(err, ErrItemExists))
+
+	// WHEN a query is out of range, the message is kept
+	_, err = items.ListBids(itemUUID, BidQuery{Limit: MaxBidPageLimit + 1})
+	assert.True(errors.Is(err, ErrInvalidArgument))
+	assert.Contains(err.Error(), "Limit must be between 1 and")
+	if assert.True(errors.As(err, &invalid)) {
+		assert.Equal("limit", invalid.Field)
+	}
+	_, err = items.Leaderboard(itemUUID, -1)
+	assert.True(errors.Is(err, ErrInvalidArgument))
+	_, err = items.SearchItems(ItemSearch{Status: "sold"})
+	assert.True(errors.Is(err, ErrInvalidArgument))
+}
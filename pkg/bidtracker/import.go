@@ -0,0 +1,350 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// MaxImportErrors is the number of failed rows an ImportReport lists, the others are only counted
+const MaxImportErrors = 100
+
+// csvListSeparator separates the values of the categories, images and attributes columns of items in csv
+const csvListSeparator = "|"
+
+// ImportError is a row that could not be imported
+type ImportError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// ImportReport summarises an import, a dry run reports what would have been imported
+type ImportReport struct {
+	DryRun   bool `json:"dryrun"`
+	Rows     int  `json:"rows"`
+	Imported int  `json:"imported"`
+	Failed   int  `json:"failed"`
+	// Errors lists the first MaxImportErrors failed rows
+	Errors []ImportError `json:"errors"`
+}
+
+func (report *ImportReport) fail(line int, err error) {
+	report.Failed++
+	if len(report.Errors) < MaxImportErrors {
+		report.Errors = append(report.Errors, ImportError{Line: line, Message: err.Error()})
+	}
+}
+
+// ItemReader streams items in one of the import formats
+type ItemReader interface {
+	// Read returns the next item, or io.EOF once there are no more.
+	// A malformed row returns a *RowError, any other error stops the reader.
+	Read() (Item, error)
+	// Line is the line of the last item read, starting at 1
+	Line() int
+}
+
+// NewItemReader returns an ItemReader reading format from r.
+// The csv header must have the itemuuid and title columns, it may have description, selleruuid, endtime,
+// and categories, images and attributes (as key=value) with the values separated by |.
+func NewItemReader(r io.Reader, format string) (ItemReader, error) {
+	switch format {
+	case FormatJSONL:
+		return &jsonlItemReader{scanner: bufio.NewScanner(r)}, nil
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		return &csvItemReader{reader: reader}, nil
+	}
+	return nil, invalidf("format", "Unknown format %q, expected %s or %s", format, FormatJSONL, FormatCSV)
+}
+
+type jsonlItemReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *jsonlItemReader) Read() (Item, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := strings.TrimSpace(r.scanner.Text())
+		if text == "" {
+			continue
+		}
+		var item Item
+		if err := json.Unmarshal([]byte(text), &item); err != nil {
+			return item, &RowError{Line: r.line, Err: err}
+		}
+		return item, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Item{}, err
+	}
+	return Item{}, io.EOF
+}
+
+func (r *jsonlItemReader) Line() int {
+	return r.line
+}
+
+type csvItemReader struct {
+	reader    *csv.Reader
+	columns   map[string]int
+	line      int
+	headerErr error
+}
+
+func (r *csvItemReader) Read() (Item, error) {
+	if r.headerErr != nil {
+		return Item{}, r.headerErr
+	}
+	if r.columns == nil {
+		header, err := r.reader.Read()
+		if err != nil {
+			return Item{}, err
+		}
+		r.line++
+		columns := make(map[string]int, len(header))
+		for i, name := range header {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		for _, name := range []string{"itemuuid", "title"} {
+			if _, ok := columns[name]; !ok {
+				r.headerErr = invalidf("", "Column %s is missing from the csv header", name)
+				return Item{}, r.headerErr
+			}
+		}
+		r.columns = columns
+	}
+
+	record, err := r.reader.Read()
+	if err != nil {
+		if parseErr, ok := err.(*csv.ParseError); ok {
+			r.line = parseErr.Line
+			return Item{}, &RowError{Line: r.line, Err: parseErr.Err}
+		}
+		return Item{}, err
+	}
+	r.line, _ = r.reader.FieldPos(0)
+
+	field := func(name string) string {
+		if i, ok := r.columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	list := func(name string) []string {
+		var values []string
+		for _, value := range strings.Split(field(name), csvListSeparator) {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+
+	item := Item{
+		Title:       field("title"),
+		Description: field("description"),
+		Categories:  list("categories"),
+		Images:      list("images"),
+	}
+	if item.UUID, err = uuid.FromString(field("itemuuid")); err != nil {
+		return item, &RowError{Line: r.line, Err: fmt.Errorf("Invalid itemuuid. %s", err)}
+	}
+	if seller := field("selleruuid"); seller != "" {
+		if item.SellerUUID, err = uuid.FromString(seller); err != nil {
+			return item, &RowError{Line: r.line, Err: fmt.Errorf("Invalid selleruuid. %s", err)}
+		}
+	}
+	if endTime := field("endtime"); endTime != "" {
+		if item.EndTime, err = strconv.ParseInt(endTime, 10, 64); err != nil {
+			return item, &RowError{Line: r.line, Err: fmt.Errorf("Invalid endtime. %s", err)}
+		}
+	}
+	for _, attribute := range list("attributes") {
+		key, value, ok := strings.Cut(attribute, "=")
+		if !ok {
+			return item, &RowError{Line: r.line, Err: fmt.Errorf("Invalid attribute %q, expected key=value", attribute)}
+		}
+		if item.Attributes == nil {
+			item.Attributes = map[string]string{}
+		}
+		item.Attributes[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return item, nil
+}
+
+func (r *csvItemReader) Line() int {
+	return r.line
+}
+
+// ImportItems adds the items read from reader one at a time, rows that are malformed, invalid
+// or already present are reported and skipped. The error is only set if reading had to stop.
+func (ibm *BidManagement) ImportItems(reader ItemReader, dryRun bool) (ImportReport, error) {
+	report := ImportReport{DryRun: dryRun, Errors: []ImportError{}}
+	seen := map[uuid.UUID]bool{}
+	for {
+		item, err := reader.Read()
+		if err == io.EOF {
+			return report, nil
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			report.Rows++
+			report.fail(rowErr.Line, rowErr.Err)
+			continue
+		}
+		if err != nil {
+			return report, invalid("", errors.WithMessage(err, "Failed to read the items"))
+		}
+
+		report.Rows++
+		if dryRun {
+			err = ibm.checkNewItem(item, seen)
+		} else {
+			err = ibm.AddItem(item)
+		}
+		if err != nil {
+			report.fail(reader.Line(), err)
+			continue
+		}
+		seen[item.UUID] = true
+		report.Imported++
+	}
+}
+
+// checkNewItem returns the error AddItem would return for item, seen holds the items added before in a dry run
+func (ibm *BidManagement) checkNewItem(item Item, seen map[uuid.UUID]bool) error {
+	if err := item.Validate(); err != nil {
+		return err
+	}
+
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	if _, ok := ibm.itemsMap[item.UUID]; ok || seen[item.UUID] {
+		return fmt.Errorf("%w. %s", ErrItemExists, item.UUID)
+	}
+	return nil
+}
+
+// ImportBids records historical bids read from reader one at a time. Unlike InsertBid, the end of the auction
+// is checked against the timestamp of the bid, shill bidding is not looked for and bids do not have to beat
+// the winning bid. Rows that are malformed or invalid are reported and skipped. The error is only set if
+// reading had to stop.
+func (ibm *BidManagement) ImportBids(reader BidReader, dryRun bool) (ImportReport, error) {
+	report := ImportReport{DryRun: dryRun, Errors: []ImportError{}}
+	for {
+		bid, err := reader.Read()
+		if err == io.EOF {
+			return report, nil
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			report.Rows++
+			report.fail(rowErr.Line, rowErr.Err)
+			continue
+		}
+		if err != nil {
+			return report, invalid("", errors.WithMessage(err, "Failed to read the bids"))
+		}
+
+		report.Rows++
+		if err := ibm.importBid(bid, dryRun); err != nil {
+			report.fail(reader.Line(), err)
+			continue
+		}
+		report.Imported++
+	}
+}
+
+func (ibm *BidManagement) importBid(bid Bid, dryRun bool) error {
+	if bid.UserUUID == uuid.Nil {
+		return invalidf("useruuid", "Bid useruuid is required")
+	}
+	if bid.Amount <= 0 {
+		return invalidf("amount", "Bid amount must be positive")
+	}
+
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	itemMetaInfo, ok := ibm.itemsMap[bid.ItemUUID]
+	if !ok {
+		return fmt.Errorf("%w. %s", ErrItemNotFound, bid.ItemUUID)
+	}
+	if itemMetaInfo.Item.EndTime != 0 && bid.Timestamp >= itemMetaInfo.Item.EndTime {
+		return fmt.Errorf("%w, the bid was placed after its end. %s", ErrAuctionClosed, bid.ItemUUID)
+	}
+	if !dryRun {
+		ibm.notifyWatches(bid, ibm.applyBid(itemMetaInfo, &bid))
+	}
+	return nil
+}
+
+// ExportBids writes the bids of every item, or only of itemuuid if it is set, ordered by itemuuid and then in
+// the order they were placed. The lock is only held to look up each item, not while writing.
+func (ibm *BidManagement) ExportBids(writer BidWriter, itemuuid uuid.UUID) (int, error) {
+	ibm.Lock()
+	var itemuuids []uuid.UUID
+	if itemuuid != uuid.Nil {
+		if _, ok := ibm.itemsMap[itemuuid]; !ok {
+			ibm.Unlock()
+			return 0, fmt.Errorf("%w. %s", ErrItemNotFound, itemuuid)
+		}
+		itemuuids = append(itemuuids, itemuuid)
+	} else {
+		itemuuids = make([]uuid.UUID, 0, len(ibm.itemsMap))
+		for id := range ibm.itemsMap {
+			itemuuids = append(itemuuids, id)
+		}
+	}
+	ibm.Unlock()
+	sort.Slice(itemuuids, func(i, j int) bool { return itemuuids[i].String() < itemuuids[j].String() })
+
+	count := 0
+	for _, id := range itemuuids {
+		// Bids are only ever appended, the slice seen under the lock stays valid after releasing it
+		ibm.Lock()
+		bids := ibm.itemsMap[id].Bids
+		ibm.Unlock()
+
+		for _, bid := range bids {
+			if err := writer.Write(bid); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportItems(t *testing.T) {
+	assert := assert.New(t)
+
+	existing := uuid.Must(uuid.NewV4())
+	items := NewBidManagement(existing)
+	camera := uuid.Must(uuid.NewV4())
+	csvItems := "itemuuid,title,categories,attributes,endtime\n" +
+		camera.String() + ",Camera,photo|vintage,brand=Leica|year=1950,100\n" +
+		camera.String() + ",Camera again,,,\n" +
+		existing.String() + ",Existing,,,\n" +
+		uuid.Must(uuid.NewV4()).String() + ",,,,\n" +
+		"not-a-uuid,Broken,,,\n"
+
+	reader, err := NewItemReader(strings.NewReader(csvItems), FormatCSV)
+	assert.Nil(err)
+	report, err := items.ImportItems(reader, true)
+	assert.Nil(err)
+	assert.Equal(5, report.Rows)
+	assert.Equal(1, report.Imported)
+	assert.Equal(4, report.Failed)
+	assert.Equal([]int{3, 4, 5, 6}, importErrorLines(report))
+	_, err = items.GetItem(camera)
+	assert.NotNil(err, "a dry run does not import anything")
+
+	reader, _ = NewItemReader(strings.NewReader(csvItems), FormatCSV)
+	report, err = items.ImportItems(reader, false)
+	assert.Nil(err)
+	assert.Equal(1, report.Imported)
+	item, err := items.GetItem(camera)
+	assert.Nil(err)
+	assert.Equal([]string{"photo", "vintage"}, item.Categories)
+	assert.Equal(map[string]string{"brand": "Leica", "year": "1950"}, item.Attributes)
+	assert.Equal(int64(100), item.EndTime)
+
+	reader, _ = NewItemReader(strings.NewReader(`{"itemuuid":"`+uuid.Must(uuid.NewV4()).String()+`","title":"Bike"}`+"\n{\n"), FormatJSONL)
+	report, err = items.ImportItems(reader, false)
+	assert.Nil(err)
+	assert.Equal(1, report.Imported)
+	assert.Equal([]int{2}, importErrorLines(report))
+
+	reader, _ = NewItemReader(strings.NewReader("title\nBike\n"), FormatCSV)
+	_, err = items.ImportItems(reader, false)
+	assert.NotNil(err, "the header lacks the itemuuid")
+}
+
+func TestImportBids(t *testing.T) {
+	assert := assert.New(t)
+
+	itemuuid := uuid.Must(uuid.NewV4())
+	items := NewBidManagement(itemuuid)
+	assert.Nil(items.SetAuctionEnd(itemuuid, 100))
+	user := uuid.Must(uuid.NewV4()).String()
+	csvBids := "itemuuid,useruuid,timestamp,amount\n" +
+		itemuuid.String() + "," + user + ",10,5\n" +
+		itemuuid.String() + "," + user + ",20,7.5\n" +
+		itemuuid.String() + "," + user + ",100,9\n" +
+		uuid.Must(uuid.NewV4()).String() + "," + user + ",20,7.5\n" +
+		itemuuid.String() + "," + user + ",20,0\n" +
+		itemuuid.String() + "," + user + ",20,abc\n"
+
+	reader, _ := NewBidReader(strings.NewReader(csvBids), FormatCSV)
+	report, err := items.ImportBids(reader, true)
+	assert.Nil(err)
+	assert.Equal(6, report.Rows)
+	assert.Equal(2, report.Imported)
+	assert.Equal([]int{4, 5, 6, 7}, importErrorLines(report))
+	bids, _ := items.GetBids(itemuuid)
+	assert.Empty(bids)
+
+	reader, _ = NewBidReader(strings.NewReader(csvBids), FormatCSV)
+	report, err = items.ImportBids(reader, false)
+	assert.Nil(err)
+	assert.Equal(2, report.Imported)
+	winning, err := items.CurrentWinningBid(itemuuid)
+	assert.Nil(err)
+	assert.Equal(7.5, winning.Amount)
+
+	var buffer bytes.Buffer
+	writer, _ := NewBidWriter(&buffer, FormatCSV)
+	count, err := items.ExportBids(writer, uuid.Nil)
+	assert.Nil(err)
+	assert.Nil(writer.Flush())
+	assert.Equal(2, count)
+	assert.Equal(strings.Join(strings.Split(csvBids, "\n")[:3], "\n")+"\n", buffer.String())
+
+	_, err = items.ExportBids(writer, uuid.Must(uuid.NewV4()))
+	assert.NotNil(err)
+}
+
+func TestImportReportErrorsAreCapped(t *testing.T) {
+	assert := assert.New(t)
+
+	items := NewBidManagement()
+	reader, _ := NewItemReader(strings.NewReader(strings.Repeat("{}\n", MaxImportErrors+5)), FormatJSONL)
+	report, err := items.ImportItems(reader, false)
+	assert.Nil(err)
+	assert.Equal(MaxImportErrors+5, report.Failed)
+	assert.Len(report.Errors, MaxImportErrors)
+}
+
+func importErrorLines(report ImportReport) []int {
+	lines := []int{}
+	for _, importError := range report.Errors {
+		lines = append(lines, importError.Line)
+	}
+	return lines
+}
@@ -0,0 +1,192 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+// Item describes what is being auctioned
+type Item struct {
+	UUID        uuid.UUID `json:"itemuuid" yaml:"itemuuid"`
+	Title       string    `json:"title" yaml:"title"`
+	Description string    `json:"description" yaml:"description"`
+	Categories  []string  `json:"categories" yaml:"categories"`
+	SellerUUID  uuid.UUID `json:"selleruuid" yaml:"selleruuid"`
+	// Images are absolute http(s) URLs or references to an image store
+	Images     []string          `json:"images" yaml:"images"`
+	Attributes map[string]string `json:"attributes" yaml:"attributes"`
+	// EndTime is the unix timestamp at which the auction closes, 0 if it never does
+	EndTime int64 `json:"endtime" yaml:"endtime"`
+}
+
+// Validate checks the item metadata
+func (item Item) Validate() error {
+	if item.UUID == uuid.Nil {
+		return invalidf("itemuuid", "Item uuid is required")
+	}
+	if strings.TrimSpace(item.Title) == "" {
+		return invalidf("title", "Item title is required")
+	}
+	if item.EndTime < 0 {
+		return invalidf("endtime", "Item end time can not be negative")
+	}
+	for _, category := range item.Categories {
+		if strings.TrimSpace(category) == "" {
+			return invalidf("categories", "Item categories can not be empty")
+		}
+	}
+	for _, image := range item.Images {
+		if err := validateImage(image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateImage accepts absolute http(s) URLs, or references without any whitespace
+func validateImage(image string) error {
+	if image == "" || strings.ContainsAny(image, " \t\r\n") {
+		return invalidf("images", "Invalid image reference %q", image)
+	}
+	if strings.Contains(image, "://") {
+		u, err := url.Parse(image)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return invalidf("images", "Invalid image url %q", image)
+		}
+	}
+	return nil
+}
+
+// AddItem makes a new item available for bidding
+func (ibm *BidManagement) AddItem(item Item) error {
+	if err := item.Validate(); err != nil {
+		return err
+	}
+
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	if _, ok := ibm.itemsMap[item.UUID]; ok {
+		return fmt.Errorf("%w. %s", ErrItemExists, item.UUID)
+	}
+	ibm.itemsMap[item.UUID] = ItemBidState{
+		ItemID:      item.UUID,
+		Bids:        []Bid{},
+		Item:        item,
+		leaderboard: newLeaderboard(),
+	}
+	ibm.searchIndex.index(item)
+	return nil
+}
+
+// UpdateItem replaces the metadata of an existing item, its bids are kept. The end of the auction
+// can not change once the item has bids or has closed, so that a seller can neither reopen an auction
+// nor end it early while losing.
+func (ibm *BidManagement) UpdateItem(item Item) error {
+	return ibm.updateItem(item, false)
+}
+
+// ModerateItem replaces the metadata of an existing item like UpdateItem, but may change the end of
+// an auction that has bids or has closed
+func (ibm *BidManagement) ModerateItem(item Item) error {
+	return ibm.updateItem(item, true)
+}
+
+func (ibm *BidManagement) updateItem(item Item, moderated bool) error {
+	if err := item.Validate(); err != nil {
+		return err
+	}
+
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	itemMetaInfo, ok := ibm.itemsMap[item.UUID]
+	if !ok {
+		return fmt.Errorf("%w. %s", ErrItemNotFound, item.UUID)
+	}
+	endChanged := itemMetaInfo.Item.EndTime != item.EndTime
+	if endChanged && !moderated && (len(itemMetaInfo.Bids) > 0 || itemMetaInfo.closed(ibm.now())) {
+		return fmt.Errorf("%w. %s", ErrAuctionEndLocked, item.UUID)
+	}
+	if itemMetaInfo.Item.SellerUUID != item.SellerUUID {
+		// The bids of the item count for another seller now
+		ibm.shillStats = nil
+	}
+	itemMetaInfo.Item = item
+	ibm.itemsMap[item.UUID] = itemMetaInfo
+	ibm.searchIndex.index(item)
+	return nil
+}
+
+// GetItem returns the metadata of an item
+func (ibm *BidManagement) GetItem(itemuuid uuid.UUID) (Item, error) {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	itemMetaInfo, ok := ibm.itemsMap[itemuuid]
+	if !ok {
+		return Item{}, fmt.Errorf("%w. %s", ErrItemNotFound, itemuuid)
+	}
+	return itemMetaInfo.Item, nil
+}
+
+// ListItems returns the metadata of every item, optionally only the ones of a seller
+// or in a category, ordered by title
+func (ibm *BidManagement) ListItems(selleruuid uuid.UUID, category string) []Item {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	items := []Item{}
+	for _, itemMetaInfo := range ibm.itemsMap {
+		item := itemMetaInfo.Item
+		if selleruuid != uuid.Nil && item.SellerUUID != selleruuid {
+			continue
+		}
+		if category != "" && !hasCategory(item, category) {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Title != items[j].Title {
+			return items[i].Title < items[j].Title
+		}
+		return items[i].UUID.String() < items[j].UUID.String()
+	})
+	return items
+}
+
+func hasCategory(item Item, category string) bool {
+	for _, c := range item.Categories {
+		if strings.EqualFold(c, category) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package bidtracker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	item := Item{
+		UUID:   uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148")),
+		Title:  "Vintage camera",
+		Images: []string{"https://images.example.com/camera.jpg", "s3/bucket/camera-back.jpg"},
+	}
+	assert.Nil(item.Validate())
+
+	noTitle := item
+	noTitle.Title = " "
+	assert.NotNil(noTitle.Validate())
+
+	badImage := item
+	badImage.Images = []string{"ftp://example.com/camera.jpg"}
+	assert.NotNil(badImage.Validate())
+
+	badImage.Images = []string{"camera front.jpg"}
+	assert.NotNil(badImage.Validate())
+}
+
+func TestAddAndUpdateItem(t *testing.T) {
+	assert := assert.New(t)
+
+	seller := uuid.Must(uuid.FromString("8f2f2a79-9091-44fb-9fe3-3eb5f0d76746"))
+	camera := Item{
+		UUID:       uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148")),
+		Title:      "Vintage camera",
+		Categories: []string{"Photography"},
+		SellerUUID: seller,
+		Attributes: map[string]string{"condition": "used"},
+	}
+	lamp := Item{
+		UUID:  uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba")),
+		Title: "Desk lamp",
+	}
+
+	items := NewBidManagement()
+	assert.Nil(items.AddItem(camera))
+	assert.Nil(items.AddItem(lamp))
+	assert.NotNil(items.AddItem(lamp), "Items can not be added twice")
+
+	// New items can be bid on right away
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: camera.UUID, UserUUID: uuid.Must(uuid.NewV4()), Timestamp: 1, Amount: 10}))
+
+	assert.Equal([]Item{lamp, camera}, items.ListItems(uuid.Nil, ""))
+	assert.Equal([]Item{camera}, items.ListItems(seller, ""))
+	assert.Equal([]Item{camera}, items.ListItems(uuid.Nil, "photography"))
+
+	camera.Description = "Works fine"
+	camera.EndTime = time.Now().Add(-time.Minute).Unix()
+	err := items.UpdateItem(camera)
+	assert.True(errors.Is(err, ErrAuctionEndLocked), "The end of an auction with bids is locked")
+	assert.Nil(items.ModerateItem(camera))
+
+	got, err := items.GetItem(camera.UUID)
+	assert.Nil(err)
+	assert.Equal("Works fine", got.Description)
+	assert.Equal(1, len(items.itemsMap[camera.UUID].Bids), "Bids are kept on update")
+
+	// The auction of the updated item is over, and can not be reopened but by a moderator
+	assert.NotNil(items.InsertBid(&Bid{ItemUUID: camera.UUID, UserUUID: uuid.Must(uuid.NewV4()), Timestamp: 2, Amount: 20}))
+	camera.Description = "Works fine, lens included"
+	assert.Nil(items.UpdateItem(camera), "The rest of the item can still change")
+	reopened := camera
+	reopened.EndTime = 0
+	assert.True(errors.Is(items.UpdateItem(reopened), ErrAuctionEndLocked))
+
+	// The end of an auction without bids can change until it closes
+	lamp.EndTime = time.Now().Add(time.Hour).Unix()
+	assert.Nil(items.UpdateItem(lamp))
+	lamp.EndTime = time.Now().Add(-time.Minute).Unix()
+	assert.Nil(items.UpdateItem(lamp))
+	lamp.EndTime = 0
+	assert.True(errors.Is(items.UpdateItem(lamp), ErrAuctionEndLocked), "The end of a closed auction is locked")
+
+	assert.NotNil(items.UpdateItem(Item{UUID: uuid.Must(uuid.NewV4()), Title: "Unknown"}))
+	_, err = items.GetItem(uuid.Must(uuid.NewV4()))
+	assert.NotNil(err)
+}
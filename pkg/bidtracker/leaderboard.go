@@ -0,0 +1,150 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// DefaultLeaderboardSize is the number of users returned when no size is requested
+	DefaultLeaderboardSize = 10
+
+	// MaxLeaderboardSize is the largest leaderboard which can be requested
+	MaxLeaderboardSize = 100
+)
+
+// LeaderboardEntry is the best bid of one user on an item, and the rank it gives them
+type LeaderboardEntry struct {
+	Rank     int       `json:"rank"`
+	UserUUID uuid.UUID `json:"useruuid"`
+	BestBid  Bid       `json:"bestbid"`
+}
+
+// rankedBid is the best bid of a user, seq is its position in the item history
+// so that of two equal amounts the earlier one ranks higher
+type rankedBid struct {
+	bid Bid
+	seq int
+}
+
+func (a rankedBid) ahead(b rankedBid) bool {
+	if a.bid.Amount != b.bid.Amount {
+		return a.bid.Amount > b.bid.Amount
+	}
+	return a.seq < b.seq
+}
+
+// leaderboard keeps the best bid of every user of an item, best first.
+// It is maintained by InsertBid so reading it never sorts the bid history.
+type leaderboard struct {
+	ranked []rankedBid
+	best   map[uuid.UUID]rankedBid
+}
+
+func newLeaderboard() *leaderboard {
+	return &leaderboard{best: make(map[uuid.UUID]rankedBid)}
+}
+
+// search returns the position at which r is, or would be, in the ranking
+func (lb *leaderboard) search(r rankedBid) int {
+	return sort.Search(len(lb.ranked), func(i int) bool {
+		return !lb.ranked[i].ahead(r)
+	})
+}
+
+// update records a new bid, which only moves its user if it beats their best bid
+func (lb *leaderboard) update(bid Bid, seq int) {
+	r := rankedBid{bid, seq}
+	previous, ok := lb.best[bid.UserUUID]
+	if ok {
+		if !r.ahead(previous) {
+			return
+		}
+		i := lb.search(previous)
+		lb.ranked = append(lb.ranked[:i], lb.ranked[i+1:]...)
+	}
+	lb.best[bid.UserUUID] = r
+
+	i := lb.search(r)
+	lb.ranked = append(lb.ranked, rankedBid{})
+	copy(lb.ranked[i+1:], lb.ranked[i:])
+	lb.ranked[i] = r
+}
+
+func (lb *leaderboard) top(n int) []LeaderboardEntry {
+	if n > len(lb.ranked) {
+		n = len(lb.ranked)
+	}
+	entries := make([]LeaderboardEntry, n)
+	for i := 0; i < n; i++ {
+		entries[i] = LeaderboardEntry{Rank: i + 1, UserUUID: lb.ranked[i].bid.UserUUID, BestBid: lb.ranked[i].bid}
+	}
+	return entries
+}
+
+func (lb *leaderboard) rank(useruuid uuid.UUID) (LeaderboardEntry, bool) {
+	r, ok := lb.best[useruuid]
+	if !ok {
+		return LeaderboardEntry{}, false
+	}
+	return LeaderboardEntry{Rank: lb.search(r) + 1, UserUUID: useruuid, BestBid: r.bid}, true
+}
+
+// Leaderboard returns the top n distinct users on an item, ranked by their best bid
+func (ibm *BidManagement) Leaderboard(itemuuid uuid.UUID, n int) ([]LeaderboardEntry, error) {
+	if n == 0 {
+		n = DefaultLeaderboardSize
+	}
+	if n < 0 || n > MaxLeaderboardSize {
+		return nil, invalidf("limit", "Leaderboard size must be between 1 and %d", MaxLeaderboardSize)
+	}
+
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	itemMetaInfo, ok := ibm.itemsMap[itemuuid]
+	if !ok {
+		return nil, fmt.Errorf("%w. %s", ErrItemNotFound, itemuuid)
+	}
+	return itemMetaInfo.leaderboard.top(n), nil
+}
+
+// UserRank returns the rank of a user on an item and the best bid it comes from
+func (ibm *BidManagement) UserRank(itemuuid, useruuid uuid.UUID) (LeaderboardEntry, error) {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	itemMetaInfo, ok := ibm.itemsMap[itemuuid]
+	if !ok {
+		return LeaderboardEntry{}, fmt.Errorf("%w. %s", ErrItemNotFound, itemuuid)
+	}
+
+	entry, ok := itemMetaInfo.leaderboard.rank(useruuid)
+	if !ok {
+		return LeaderboardEntry{}, fmt.Errorf("%w for user %s on item %s", ErrNoBids, useruuid, itemuuid)
+	}
+	return entry, nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package bidtracker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderboard(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148"))
+	userA := uuid.Must(uuid.FromString("8f2f2a79-9091-44fb-9fe3-3eb5f0d76746"))
+	userB := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+	userC := uuid.Must(uuid.FromString("f475091b-a8f1-4679-83bd-483b616e5260"))
+
+	items := NewBidManagement(itemUUID)
+	bids := []struct {
+		user   uuid.UUID
+		amount float64
+	}{
+		{userA, 10}, {userB, 20}, {userC, 25}, {userA, 30},
+	}
+	for i, b := range bids {
+		assert.Nil(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: b.user, Timestamp: int64(i), Amount: b.amount}))
+	}
+
+	board, err := items.Leaderboard(itemUUID, 0)
+	assert.Nil(err)
+	if assert.Len(board, 3) {
+		assert.Equal(LeaderboardEntry{Rank: 1, UserUUID: userA, BestBid: items.itemsMap[itemUUID].Bids[3]}, board[0])
+		assert.Equal(userC, board[1].UserUUID)
+		assert.Equal(25.0, board[1].BestBid.Amount)
+		assert.Equal(userB, board[2].UserUUID)
+	}
+
+	board, err = items.Leaderboard(itemUUID, 1)
+	assert.Nil(err)
+	assert.Len(board, 1)
+
+	rank, err := items.UserRank(itemUUID, userB)
+	assert.Nil(err)
+	assert.Equal(3, rank.Rank)
+
+	_, err = items.UserRank(itemUUID, uuid.Must(uuid.NewV4()))
+	assert.NotNil(err)
+
+	_, err = items.Leaderboard(itemUUID, MaxLeaderboardSize+1)
+	assert.NotNil(err)
+}
+
+func TestLeaderboardImportedHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148"))
+	userA := "8f2f2a79-9091-44fb-9fe3-3eb5f0d76746"
+	userB := "ae8f7716-867b-4479-b455-c5769e7475ba"
+	userC := "f475091b-a8f1-4679-83bd-483b616e5260"
+
+	// Imported bids do not have to beat the winning bid, so ties and lower bids show up
+	csvBids := "itemuuid,useruuid,timestamp,amount\n"
+	for i, b := range []struct {
+		user   string
+		amount string
+	}{
+		{userA, "10"}, {userB, "20"}, {userC, "20"}, {userA, "15"}, {userB, "5"}, {userA, "30"},
+	} {
+		csvBids += fmt.Sprintf("%s,%s,%d,%s\n", itemUUID, b.user, i, b.amount)
+	}
+	items := NewBidManagement(itemUUID)
+	reader, _ := NewBidReader(strings.NewReader(csvBids), FormatCSV)
+	report, err := items.ImportBids(reader, false)
+	assert.Nil(err)
+	assert.Equal(6, report.Imported)
+
+	board, err := items.Leaderboard(itemUUID, 0)
+	assert.Nil(err)
+	if assert.Len(board, 3) {
+		// A lower later bid does not move userB, who reached 20 before userC
+		assert.Equal(LeaderboardEntry{Rank: 1, UserUUID: uuid.FromStringOrNil(userA), BestBid: items.itemsMap[itemUUID].Bids[5]}, board[0])
+		assert.Equal(userB, board[1].UserUUID.String())
+		assert.Equal(20.0, board[1].BestBid.Amount)
+		assert.Equal(userC, board[2].UserUUID.String())
+	}
+}
@@ -0,0 +1,106 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"time"
+)
+
+// RejectReason tells why a bid was not accepted
+type RejectReason string
+
+const (
+	// RejectUnknownItem bids were placed on an item the tracker does not hold
+	RejectUnknownItem RejectReason = "unknown_item"
+
+	// RejectAuctionClosed bids came in after the end of the auction
+	RejectAuctionClosed RejectReason = "auction_closed"
+
+	// RejectBidTooLow bids were not positive or not above the current winning bid
+	RejectBidTooLow RejectReason = "bid_too_low"
+
+	// RejectShillBid bids came from a user flagged by a blocking shill detector
+	RejectShillBid RejectReason = "shill_bid"
+
+	// RejectBatchAborted bids were valid but another bid of their all-or-nothing batch was rejected
+	RejectBatchAborted RejectReason = "batch_aborted"
+)
+
+// BidRejectedError is returned by InsertBid when a bid is not accepted
+type BidRejectedError struct {
+	Reason RejectReason
+	Err    error
+}
+
+func (e *BidRejectedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *BidRejectedError) Unwrap() error {
+	return e.Err
+}
+
+// Observer is notified of what happens inside BidManagement, e.g. to export metrics.
+// Its methods are called with the lock held: they must be quick and must not call back into BidManagement.
+type Observer interface {
+	BidAccepted(bid Bid)
+	BidRejected(bid Bid, reason RejectReason)
+	// LockWaited reports how long a caller waited for the lock
+	LockWaited(wait time.Duration)
+}
+
+// SetObserver sets the observer notified of bids and lock contention, nil disables it
+func (ibm *BidManagement) SetObserver(observer Observer) {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	ibm.observer = observer
+}
+
+// Lock locks ibm, reporting the time spent waiting for the lock to the observer
+func (ibm *BidManagement) Lock() {
+	start := time.Now()
+	ibm.Mutex.Lock()
+	if ibm.observer != nil {
+		ibm.observer.LockWaited(time.Since(start))
+	}
+}
+
+// Size returns the number of items held and the number of bids placed on them
+func (ibm *BidManagement) Size() (items, bids int) {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	for _, itemMetaInfo := range ibm.itemsMap {
+		bids += len(itemMetaInfo.Bids)
+	}
+	return len(ibm.itemsMap), bids
+}
+
+// reject notifies the observer about a rejected bid and returns err with its reason
+func (ibm *BidManagement) reject(bid *Bid, reason RejectReason, err error) error {
+	if ibm.observer != nil {
+		ibm.observer.BidRejected(*bid, reason)
+	}
+	return &BidRejectedError{Reason: reason, Err: err}
+}
@@ -0,0 +1,111 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"fmt"
+
+	"github.com/gofrs/uuid"
+)
+
+// PortfolioStatus is the standing of a user on an item
+type PortfolioStatus string
+
+const (
+	// StatusWinning means the user holds the highest bid of an open auction
+	StatusWinning PortfolioStatus = "winning"
+
+	// StatusOutbid means someone else holds the highest bid of an open auction
+	StatusOutbid PortfolioStatus = "outbid"
+
+	// StatusWon means the user held the highest bid when the auction closed
+	StatusWon PortfolioStatus = "won"
+
+	// StatusLost means someone else held the highest bid when the auction closed
+	StatusLost PortfolioStatus = "lost"
+)
+
+// PortfolioEntry is the position of a user on one item
+type PortfolioEntry struct {
+	ItemUUID uuid.UUID `json:"itemuuid"`
+	// HighestBid is the best bid of the user on this item
+	HighestBid Bid `json:"highestbid"`
+	BidCount   int `json:"bidcount"`
+	// LeadingAmount is the current highest bid on this item, by anyone
+	LeadingAmount float64         `json:"leadingamount"`
+	Status        PortfolioStatus `json:"status"`
+	// EndTime is the unix timestamp at which the auction closes, 0 if it never does
+	EndTime int64 `json:"endtime"`
+}
+
+// GetPortfolio returns the position of a user on every item they bid on,
+// in the order they first bid on them. Everything is read under a single lock.
+func (ibm *BidManagement) GetPortfolio(useruuid uuid.UUID) ([]PortfolioEntry, error) {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	userBidInfo, ok := ibm.userBidMap[useruuid]
+	if !ok {
+		return nil, fmt.Errorf("%w with uuid %s", ErrUserNotFound, useruuid)
+	}
+
+	now := ibm.now()
+	positions := make(map[uuid.UUID]int)
+	portfolio := []PortfolioEntry{}
+	for _, bid := range userBidInfo.Bids {
+		i, seen := positions[bid.ItemUUID]
+		if !seen {
+			i = len(portfolio)
+			positions[bid.ItemUUID] = i
+			portfolio = append(portfolio, PortfolioEntry{ItemUUID: bid.ItemUUID, HighestBid: bid})
+		}
+
+		entry := &portfolio[i]
+		entry.BidCount++
+		if bid.Amount > entry.HighestBid.Amount {
+			entry.HighestBid = bid
+		}
+	}
+
+	for i := range portfolio {
+		entry := &portfolio[i]
+		itemMetaInfo := ibm.itemsMap[entry.ItemUUID]
+		entry.EndTime = itemMetaInfo.Item.EndTime
+
+		leading := itemMetaInfo.currentWinndingBid != nil && itemMetaInfo.currentWinndingBid.UserUUID == useruuid
+		if itemMetaInfo.currentWinndingBid != nil {
+			entry.LeadingAmount = itemMetaInfo.currentWinndingBid.Amount
+		}
+
+		switch closed := itemMetaInfo.closed(now); {
+		case closed && leading:
+			entry.Status = StatusWon
+		case closed:
+			entry.Status = StatusLost
+		case leading:
+			entry.Status = StatusWinning
+		default:
+			entry.Status = StatusOutbid
+		}
+	}
+	return portfolio, nil
+}
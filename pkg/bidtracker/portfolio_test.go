@@ -0,0 +1,79 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package bidtracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPortfolio(t *testing.T) {
+	assert := assert.New(t)
+
+	openItem := uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148"))
+	closingItem := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+	user := uuid.Must(uuid.FromString("8f2f2a79-9091-44fb-9fe3-3eb5f0d76746"))
+	rival := uuid.Must(uuid.FromString("f475091b-a8f1-4679-83bd-483b616e5260"))
+
+	now := time.Unix(1000, 0)
+	items := NewBidManagement(openItem, closingItem)
+	items.now = func() time.Time { return now }
+	assert.Nil(items.SetAuctionEnd(closingItem, 2000))
+
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: openItem, UserUUID: user, Timestamp: 1, Amount: 10}))
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: closingItem, UserUUID: user, Timestamp: 2, Amount: 20}))
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: openItem, UserUUID: user, Timestamp: 3, Amount: 12}))
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: openItem, UserUUID: rival, Timestamp: 4, Amount: 15}))
+
+	portfolio, err := items.GetPortfolio(user)
+	assert.Nil(err)
+	if assert.Len(portfolio, 2) {
+		assert.Equal(openItem, portfolio[0].ItemUUID)
+		assert.Equal(12.0, portfolio[0].HighestBid.Amount)
+		assert.Equal(2, portfolio[0].BidCount)
+		assert.Equal(15.0, portfolio[0].LeadingAmount)
+		assert.Equal(StatusOutbid, portfolio[0].Status)
+
+		assert.Equal(closingItem, portfolio[1].ItemUUID)
+		assert.Equal(StatusWinning, portfolio[1].Status)
+		assert.Equal(int64(2000), portfolio[1].EndTime)
+	}
+
+	// WHEN the auction closes
+	now = time.Unix(2000, 0)
+	assert.NotNil(items.InsertBid(&Bid{ItemUUID: closingItem, UserUUID: rival, Timestamp: 5, Amount: 50}))
+
+	portfolio, err = items.GetPortfolio(user)
+	assert.Nil(err)
+	assert.Equal(StatusOutbid, portfolio[0].Status)
+	assert.Equal(StatusWon, portfolio[1].Status)
+
+	portfolio, err = items.GetPortfolio(rival)
+	assert.Nil(err)
+	if assert.Len(portfolio, 1) {
+		assert.Equal(StatusWinning, portfolio[0].Status)
+	}
+
+	_, err = items.GetPortfolio(uuid.Must(uuid.NewV4()))
+	assert.NotNil(err)
+}
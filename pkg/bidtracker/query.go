@@ -0,0 +1,270 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"container/heap"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// DefaultBidPageLimit is the page size used when a BidQuery does not set one
+	DefaultBidPageLimit = 100
+
+	// MaxBidPageLimit is the largest page size a BidQuery can ask for
+	MaxBidPageLimit = 1000
+)
+
+// BidSortField is the field bids are ordered by
+type BidSortField string
+
+const (
+	// SortByTime orders bids by their timestamp, then by arrival
+	SortByTime BidSortField = "time"
+
+	// SortByAmount orders bids by their amount, then by arrival
+	SortByAmount BidSortField = "amount"
+)
+
+// BidQuery filters, sorts and paginates a list of bids.
+// Zero values mean no filtering, ascending time order and DefaultBidPageLimit.
+type BidQuery struct {
+	Limit int
+	// Cursor is the NextCursor of the previous page
+	Cursor     string
+	SortBy     BidSortField
+	Descending bool
+
+	// MinAmount and MaxAmount bound the amount, inclusive. Zero is unbounded.
+	MinAmount float64
+	MaxAmount float64
+	// From and To bound the timestamp, inclusive. Zero is unbounded.
+	From int64
+	To   int64
+	// UserUUID only keeps the bids of this user
+	UserUUID uuid.UUID
+	// ItemUUID only keeps the bids on this item
+	ItemUUID uuid.UUID
+}
+
+// BidPage is a page of bids together with the cursor to the next one.
+// NextCursor is empty on the last page.
+type BidPage struct {
+	Bids       []Bid
+	NextCursor string
+}
+
+// Validate checks the query for inconsistent values
+func (q BidQuery) Validate() error {
+	switch q.SortBy {
+	case "", SortByTime, SortByAmount:
+	default:
+		return invalidf("sort", "Unknown sort field %q, expected %s or %s", q.SortBy, SortByTime, SortByAmount)
+	}
+	if q.Limit < 0 || q.Limit > MaxBidPageLimit {
+		return invalidf("limit", "Limit must be between 1 and %d", MaxBidPageLimit)
+	}
+	if q.MaxAmount != 0 && q.MinAmount > q.MaxAmount {
+		return invalidf("min_amount", "Minimum amount %v is greater than maximum amount %v", q.MinAmount, q.MaxAmount)
+	}
+	if q.To != 0 && q.From > q.To {
+		return invalidf("from", "From %d is after to %d", q.From, q.To)
+	}
+	return nil
+}
+
+func (q BidQuery) matches(bid Bid) bool {
+	return (q.MinAmount == 0 || bid.Amount >= q.MinAmount) &&
+		(q.MaxAmount == 0 || bid.Amount <= q.MaxAmount) &&
+		(q.From == 0 || bid.Timestamp >= q.From) &&
+		(q.To == 0 || bid.Timestamp <= q.To) &&
+		(q.UserUUID == uuid.Nil || bid.UserUUID == q.UserUUID) &&
+		(q.ItemUUID == uuid.Nil || bid.ItemUUID == q.ItemUUID)
+}
+
+// bidPosition is the place of a bid in the sort order: its sort key, then its index in the history.
+// Histories are append only, so positions are stable between pages.
+type bidPosition struct {
+	key   float64
+	index int
+}
+
+func (q BidQuery) position(bids []Bid, index int) bidPosition {
+	if q.SortBy == SortByAmount {
+		return bidPosition{bids[index].Amount, index}
+	}
+	return bidPosition{float64(bids[index].Timestamp), index}
+}
+
+// before reports whether a comes before b in the order requested by the query
+func (q BidQuery) before(a, b bidPosition) bool {
+	if a.key != b.key {
+		return (a.key < b.key) != q.Descending
+	}
+	if a.index != b.index {
+		return (a.index < b.index) != q.Descending
+	}
+	return false
+}
+
+func (q BidQuery) sortName() string {
+	name := string(SortByTime)
+	if q.SortBy == SortByAmount {
+		name = string(SortByAmount)
+	}
+	if q.Descending {
+		return name + ".desc"
+	}
+	return name + ".asc"
+}
+
+func (q BidQuery) encodeCursor(pos bidPosition) string {
+	raw := fmt.Sprintf("%s|%s|%d", q.sortName(), strconv.FormatFloat(pos.key, 'g', -1, 64), pos.index)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func (q BidQuery) decodeCursor() (bidPosition, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return bidPosition{}, invalidf("cursor", "Malformed cursor")
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return bidPosition{}, invalidf("cursor", "Malformed cursor")
+	}
+	if parts[0] != q.sortName() {
+		return bidPosition{}, invalidf("cursor", "Cursor was issued for sort %s, not %s", parts[0], q.sortName())
+	}
+	key, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return bidPosition{}, invalidf("cursor", "Malformed cursor")
+	}
+	index, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return bidPosition{}, invalidf("cursor", "Malformed cursor")
+	}
+	return bidPosition{key, index}, nil
+}
+
+// pageHeap keeps the first limit+1 positions in query order, with the last one on top
+type pageHeap struct {
+	query     BidQuery
+	positions []bidPosition
+}
+
+func (h *pageHeap) Len() int           { return len(h.positions) }
+func (h *pageHeap) Less(i, j int) bool { return h.query.before(h.positions[j], h.positions[i]) }
+func (h *pageHeap) Swap(i, j int)      { h.positions[i], h.positions[j] = h.positions[j], h.positions[i] }
+func (h *pageHeap) Push(x interface{}) { h.positions = append(h.positions, x.(bidPosition)) }
+func (h *pageHeap) Pop() interface{} {
+	last := h.positions[len(h.positions)-1]
+	h.positions = h.positions[:len(h.positions)-1]
+	return last
+}
+
+// paginate selects one page of bids out of a history without copying or sorting all of it.
+// The caller must hold the lock.
+func paginate(bids []Bid, q BidQuery) (BidPage, error) {
+	if err := q.Validate(); err != nil {
+		return BidPage{}, err
+	}
+
+	limit := q.Limit
+	if limit == 0 {
+		limit = DefaultBidPageLimit
+	}
+
+	var after *bidPosition
+	if q.Cursor != "" {
+		pos, err := q.decodeCursor()
+		if err != nil {
+			return BidPage{}, err
+		}
+		after = &pos
+	}
+
+	h := &pageHeap{query: q}
+	for i := range bids {
+		if !q.matches(bids[i]) {
+			continue
+		}
+		pos := q.position(bids, i)
+		if after != nil && !q.before(*after, pos) {
+			continue
+		}
+		if h.Len() <= limit {
+			heap.Push(h, pos)
+		} else if q.before(pos, h.positions[0]) {
+			h.positions[0] = pos
+			heap.Fix(h, 0)
+		}
+	}
+
+	// One extra position tells whether there is a next page
+	hasMore := h.Len() > limit
+	if hasMore {
+		heap.Pop(h)
+	}
+
+	page := BidPage{Bids: make([]Bid, h.Len())}
+	var last bidPosition
+	for i := h.Len() - 1; i >= 0; i-- {
+		pos := heap.Pop(h).(bidPosition)
+		if i == len(page.Bids)-1 {
+			last = pos
+		}
+		page.Bids[i] = bids[pos.index]
+	}
+	if hasMore {
+		page.NextCursor = q.encodeCursor(last)
+	}
+	return page, nil
+}
+
+// ListBids returns one page of the bids on an item
+func (ibm *BidManagement) ListBids(itemuuid uuid.UUID, query BidQuery) (BidPage, error) {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	itemMetaInfo, ok := ibm.itemsMap[itemuuid]
+	if !ok {
+		return BidPage{}, fmt.Errorf("%w. %s", ErrItemNotFound, itemuuid)
+	}
+	return paginate(itemMetaInfo.Bids, query)
+}
+
+// ListBidsByUser returns one page of the bids of a user
+func (ibm *BidManagement) ListBidsByUser(useruuid uuid.UUID, query BidQuery) (BidPage, error) {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	userBidInfo, ok := ibm.userBidMap[useruuid]
+	if !ok {
+		return BidPage{}, fmt.Errorf("%w with uuid %s", ErrUserNotFound, useruuid)
+	}
+	return paginate(userBidInfo.Bids, query)
+}
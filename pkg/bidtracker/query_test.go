@@ -0,0 +1,110 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package bidtracker
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListBidsPagination(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148"))
+	userA := uuid.Must(uuid.FromString("8f2f2a79-9091-44fb-9fe3-3eb5f0d76746"))
+	userB := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+
+	// The bids are imported, as live bids must beat the winning bid there would be no ties to page through
+	items := NewBidManagement(itemUUID)
+	amounts := []float64{10, 30, 20, 30, 50}
+	for i, amount := range amounts {
+		user := userA
+		if i%2 == 1 {
+			user = userB
+		}
+		assert.Nil(items.importBid(Bid{ItemUUID: itemUUID, UserUUID: user, Timestamp: int64(100 + i), Amount: amount}, false))
+	}
+
+	// Walk all the pages by descending amount
+	var got []float64
+	query := BidQuery{Limit: 2, SortBy: SortByAmount, Descending: true}
+	for pages := 0; ; pages++ {
+		page, err := items.ListBids(itemUUID, query)
+		assert.Nil(err)
+		for _, bid := range page.Bids {
+			got = append(got, bid.Amount)
+		}
+		if page.NextCursor == "" {
+			assert.Equal(2, pages)
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal([]float64{50, 30, 30, 20, 10}, got)
+
+	// Filters are combined
+	page, err := items.ListBids(itemUUID, BidQuery{UserUUID: userB, MinAmount: 20})
+	assert.Nil(err)
+	assert.Equal(2, len(page.Bids))
+	assert.Empty(page.NextCursor)
+
+	page, err = items.ListBids(itemUUID, BidQuery{From: 101, To: 102})
+	assert.Nil(err)
+	assert.Equal([]Bid{items.itemsMap[itemUUID].Bids[1], items.itemsMap[itemUUID].Bids[2]}, page.Bids)
+
+	// A cursor can only be used with the sort it was issued for
+	page, err = items.ListBids(itemUUID, BidQuery{Limit: 1})
+	assert.Nil(err)
+	_, err = items.ListBids(itemUUID, BidQuery{Limit: 1, Cursor: page.NextCursor, SortBy: SortByAmount})
+	assert.NotNil(err)
+
+	_, err = items.ListBids(itemUUID, BidQuery{Cursor: "garbage"})
+	assert.NotNil(err)
+
+	_, err = items.ListBids(itemUUID, BidQuery{SortBy: "popularity"})
+	assert.NotNil(err)
+}
+
+func TestListBidsByUser(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID1 := uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148"))
+	itemUUID2 := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+	userUUID := uuid.Must(uuid.FromString("8f2f2a79-9091-44fb-9fe3-3eb5f0d76746"))
+
+	items := NewBidManagement(itemUUID1, itemUUID2)
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: itemUUID1, UserUUID: userUUID, Timestamp: 1, Amount: 10}))
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: itemUUID2, UserUUID: userUUID, Timestamp: 2, Amount: 20}))
+
+	page, err := items.ListBidsByUser(userUUID, BidQuery{ItemUUID: itemUUID2})
+	assert.Nil(err)
+	if assert.Len(page.Bids, 1) {
+		assert.Equal(20.0, page.Bids[0].Amount)
+	}
+
+	page, err = items.ListBidsByUser(userUUID, BidQuery{Descending: true})
+	assert.Nil(err)
+	assert.Equal(itemUUID2, page.Bids[0].ItemUUID)
+
+	_, err = items.ListBidsByUser(uuid.Must(uuid.NewV4()), BidQuery{})
+	assert.NotNil(err)
+}
@@ -0,0 +1,384 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofrs/uuid"
+)
+
+// ItemStatus tells where an auction stands
+type ItemStatus string
+
+const (
+	// ItemOpen items accept bids
+	ItemOpen ItemStatus = "open"
+
+	// ItemClosingSoon items accept bids, but not for longer than ClosingSoonWindow
+	ItemClosingSoon ItemStatus = "closing_soon"
+
+	// ItemClosed items do not accept bids anymore
+	ItemClosed ItemStatus = "closed"
+)
+
+// ClosingSoonWindow is how long before its end an auction is closing soon
+const ClosingSoonWindow = time.Hour
+
+// ItemSort is the order of search results
+type ItemSort string
+
+const (
+	// SortByRelevance ranks title matches above description matches, it is the default when searching text
+	SortByRelevance ItemSort = "relevance"
+
+	// SortByEndingSoonest puts the auctions closing first on top, auctions without end last
+	SortByEndingSoonest ItemSort = "ending_soonest"
+
+	// SortByMostBids puts the items with the most bids on top
+	SortByMostBids ItemSort = "most_bids"
+
+	// SortByHighestPrice puts the items with the highest current bid on top
+	SortByHighestPrice ItemSort = "highest_price"
+)
+
+const (
+	// DefaultItemSearchLimit is the page size used when an ItemSearch does not set one
+	DefaultItemSearchLimit = 20
+
+	// MaxItemSearchLimit is the largest page size an ItemSearch can ask for
+	MaxItemSearchLimit = 100
+)
+
+// priceRangeBounds are the lower bounds of the price range facets
+var priceRangeBounds = []float64{0, 10, 50, 100, 500, 1000}
+
+// ItemSearch is a full text search over titles and descriptions, narrowed down by facets.
+// Zero values mean no filtering.
+type ItemSearch struct {
+	Text string
+	// Categories keeps items in any of them
+	Categories []string
+	Status     ItemStatus
+	// MinPrice and MaxPrice bound the current price, inclusive. Zero is unbounded.
+	MinPrice float64
+	MaxPrice float64
+	SortBy   ItemSort
+	Limit    int
+	Offset   int
+}
+
+// ItemSummary is an item together with where its auction stands
+type ItemSummary struct {
+	Item
+	Status       ItemStatus `json:"status"`
+	BidCount     int        `json:"bidcount"`
+	CurrentPrice float64    `json:"currentprice"`
+}
+
+// PriceRangeFacet counts the items with a current price in [Min, Max). Max is 0 for the last range.
+type PriceRangeFacet struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// ItemFacets counts the items matching the text of a search, before any facet is applied
+type ItemFacets struct {
+	Categories  map[string]int     `json:"categories"`
+	Status      map[ItemStatus]int `json:"status"`
+	PriceRanges []PriceRangeFacet  `json:"priceranges"`
+}
+
+// ItemSearchResult is one page of search results
+type ItemSearchResult struct {
+	Items  []ItemSummary `json:"items"`
+	Total  int           `json:"total"`
+	Facets ItemFacets    `json:"facets"`
+}
+
+// Validate checks the search for inconsistent values
+func (s ItemSearch) Validate() error {
+	switch s.Status {
+	case "", ItemOpen, ItemClosingSoon, ItemClosed:
+	default:
+		return invalidf("status", "Unknown status %q", s.Status)
+	}
+	switch s.SortBy {
+	case "", SortByRelevance, SortByEndingSoonest, SortByMostBids, SortByHighestPrice:
+	default:
+		return invalidf("sort", "Unknown sort %q", s.SortBy)
+	}
+	if s.Limit < 0 || s.Limit > MaxItemSearchLimit {
+		return invalidf("limit", "Limit must be between 1 and %d", MaxItemSearchLimit)
+	}
+	if s.Offset < 0 {
+		return invalidf("offset", "Offset can not be negative")
+	}
+	if s.MaxPrice != 0 && s.MinPrice > s.MaxPrice {
+		return invalidf("min_price", "Minimum price %v is greater than maximum price %v", s.MinPrice, s.MaxPrice)
+	}
+	return nil
+}
+
+// title matches weigh more than description matches
+const (
+	titleWeight       = 2
+	descriptionWeight = 1
+)
+
+// searchIndex is an inverted index from lowercase tokens to the items containing them.
+// It is kept in sync with the catalogue by AddItem and UpdateItem. Prices, bid counts and
+// statuses are read from the item state at query time, under the same lock as InsertBid.
+type searchIndex struct {
+	postings map[string]map[uuid.UUID]int
+	tokens   map[uuid.UUID][]string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[uuid.UUID]int),
+		tokens:   make(map[uuid.UUID][]string),
+	}
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// index replaces whatever was indexed for the item
+func (idx *searchIndex) index(item Item) {
+	idx.remove(item.UUID)
+
+	weights := make(map[string]int)
+	for _, token := range tokenize(item.Title) {
+		weights[token] += titleWeight
+	}
+	for _, token := range tokenize(item.Description) {
+		weights[token] += descriptionWeight
+	}
+
+	tokens := make([]string, 0, len(weights))
+	for token, weight := range weights {
+		if idx.postings[token] == nil {
+			idx.postings[token] = make(map[uuid.UUID]int)
+		}
+		idx.postings[token][item.UUID] = weight
+		tokens = append(tokens, token)
+	}
+	idx.tokens[item.UUID] = tokens
+}
+
+func (idx *searchIndex) remove(itemuuid uuid.UUID) {
+	for _, token := range idx.tokens[itemuuid] {
+		delete(idx.postings[token], itemuuid)
+		if len(idx.postings[token]) == 0 {
+			delete(idx.postings, token)
+		}
+	}
+	delete(idx.tokens, itemuuid)
+}
+
+// match returns the items containing every token of the text with their score,
+// or nil if the text has no token at all
+func (idx *searchIndex) match(text string) map[uuid.UUID]int {
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	scores := make(map[uuid.UUID]int)
+	for itemuuid, weight := range idx.postings[tokens[0]] {
+		scores[itemuuid] = weight
+	}
+	for _, token := range tokens[1:] {
+		postings := idx.postings[token]
+		for itemuuid, score := range scores {
+			weight, ok := postings[itemuuid]
+			if !ok {
+				delete(scores, itemuuid)
+				continue
+			}
+			scores[itemuuid] = score + weight
+		}
+	}
+	return scores
+}
+
+func (state ItemBidState) status(now time.Time) ItemStatus {
+	switch {
+	case state.closed(now):
+		return ItemClosed
+	case state.Item.EndTime != 0 && now.Add(ClosingSoonWindow).Unix() >= state.Item.EndTime:
+		return ItemClosingSoon
+	}
+	return ItemOpen
+}
+
+func (state ItemBidState) summary(now time.Time) ItemSummary {
+	summary := ItemSummary{
+		Item:     state.Item,
+		Status:   state.status(now),
+		BidCount: len(state.Bids),
+	}
+	if state.currentWinndingBid != nil {
+		summary.CurrentPrice = state.currentWinndingBid.Amount
+	}
+	return summary
+}
+
+// priceRange returns the index of the facet of price, prices below the first bound count in the first facet
+func priceRange(price float64) int {
+	i := sort.SearchFloat64s(priceRangeBounds, price)
+	if i == len(priceRangeBounds) || priceRangeBounds[i] != price {
+		i--
+	}
+	if i < 0 {
+		return 0
+	}
+	return i
+}
+
+func (s ItemSearch) matchesFacets(summary ItemSummary) bool {
+	if len(s.Categories) > 0 {
+		found := false
+		for _, category := range s.Categories {
+			if hasCategory(summary.Item, category) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return (s.Status == "" || summary.Status == s.Status) &&
+		(s.MinPrice == 0 || summary.CurrentPrice >= s.MinPrice) &&
+		(s.MaxPrice == 0 || summary.CurrentPrice <= s.MaxPrice)
+}
+
+// SearchItems runs a full text search over the catalogue, and counts the facets of the matching items
+func (ibm *BidManagement) SearchItems(search ItemSearch) (ItemSearchResult, error) {
+	if err := search.Validate(); err != nil {
+		return ItemSearchResult{}, err
+	}
+
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	now := ibm.now()
+	scores := ibm.searchIndex.match(search.Text)
+	if scores == nil && strings.TrimSpace(search.Text) == "" {
+		scores = make(map[uuid.UUID]int, len(ibm.itemsMap))
+		for itemuuid := range ibm.itemsMap {
+			scores[itemuuid] = 0
+		}
+	}
+
+	facets := ItemFacets{
+		Categories:  make(map[string]int),
+		Status:      make(map[ItemStatus]int),
+		PriceRanges: make([]PriceRangeFacet, len(priceRangeBounds)),
+	}
+	for i, min := range priceRangeBounds {
+		facets.PriceRanges[i].Min = min
+		if i+1 < len(priceRangeBounds) {
+			facets.PriceRanges[i].Max = priceRangeBounds[i+1]
+		}
+	}
+
+	var matches []ItemSummary
+	for itemuuid := range scores {
+		summary := ibm.itemsMap[itemuuid].summary(now)
+
+		for _, category := range summary.Categories {
+			facets.Categories[strings.ToLower(category)]++
+		}
+		facets.Status[summary.Status]++
+		facets.PriceRanges[priceRange(summary.CurrentPrice)].Count++
+
+		if search.matchesFacets(summary) {
+			matches = append(matches, summary)
+		}
+	}
+
+	sortBy := search.SortBy
+	if sortBy == "" {
+		sortBy = SortByEndingSoonest
+		if len(tokenize(search.Text)) > 0 {
+			sortBy = SortByRelevance
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch sortBy {
+		case SortByRelevance:
+			if scores[a.UUID] != scores[b.UUID] {
+				return scores[a.UUID] > scores[b.UUID]
+			}
+		case SortByEndingSoonest:
+			// Closed auctions have ended already, they come after every open one
+			if closedA, closedB := a.Status == ItemClosed, b.Status == ItemClosed; closedA != closedB {
+				return closedB
+			}
+			if endA, endB := endOrInfinity(a.EndTime), endOrInfinity(b.EndTime); endA != endB {
+				return endA < endB
+			}
+		case SortByMostBids:
+			if a.BidCount != b.BidCount {
+				return a.BidCount > b.BidCount
+			}
+		case SortByHighestPrice:
+			if a.CurrentPrice != b.CurrentPrice {
+				return a.CurrentPrice > b.CurrentPrice
+			}
+		}
+		return a.UUID.String() < b.UUID.String()
+	})
+
+	limit := search.Limit
+	if limit == 0 {
+		limit = DefaultItemSearchLimit
+	}
+	result := ItemSearchResult{Items: []ItemSummary{}, Total: len(matches), Facets: facets}
+	if search.Offset < len(matches) {
+		end := search.Offset + limit
+		if end > len(matches) {
+			end = len(matches)
+		}
+		result.Items = matches[search.Offset:end]
+	}
+	return result, nil
+}
+
+func endOrInfinity(endTime int64) int64 {
+	if endTime == 0 {
+		return math.MaxInt64
+	}
+	return endTime
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchItems(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(10000, 0)
+	items := NewBidManagement()
+	items.now = func() time.Time { return now }
+
+	camera := Item{
+		UUID:        uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148")),
+		Title:       "Vintage camera",
+		Description: "A film camera from 1970, with a leather case",
+		Categories:  []string{"Photography"},
+		EndTime:     now.Add(30 * time.Minute).Unix(),
+	}
+	lens := Item{
+		UUID:        uuid.Must(uuid.FromString("0c6f3a54-97c6-4b8f-8c09-2d4f1f3fbe15")),
+		Title:       "Zoom lens",
+		Description: "Fits any vintage camera body",
+		Categories:  []string{"Photography", "Optics"},
+		EndTime:     now.Add(48 * time.Hour).Unix(),
+	}
+	chair := Item{
+		UUID:        uuid.Must(uuid.FromString("e2b7b0f3-86f4-43a5-9d7b-5fb7a4a3cb51")),
+		Title:       "Leather chair",
+		Description: "Vintage armchair",
+		Categories:  []string{"Furniture"},
+		EndTime:     now.Add(-time.Hour).Unix(),
+	}
+	for _, item := range []Item{camera, lens} {
+		assert.Nil(items.AddItem(item))
+	}
+	// the chair closes after its bids are in
+	chair.EndTime = 0
+	assert.Nil(items.AddItem(chair))
+
+	user := uuid.Must(uuid.NewV4())
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: camera.UUID, UserUUID: user, Amount: 40}))
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: lens.UUID, UserUUID: user, Amount: 120}))
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: lens.UUID, UserUUID: uuid.Must(uuid.NewV4()), Amount: 150}))
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: chair.UUID, UserUUID: user, Amount: 700}))
+	assert.Nil(items.SetAuctionEnd(chair.UUID, now.Add(-time.Hour).Unix()))
+
+	result, err := items.SearchItems(ItemSearch{Text: "Vintage CAMERA"})
+	assert.Nil(err)
+	assert.Equal(2, result.Total)
+	// the title match ranks first
+	assert.Equal(camera.UUID, result.Items[0].UUID)
+	assert.Equal(lens.UUID, result.Items[1].UUID)
+	assert.Equal(ItemClosingSoon, result.Items[0].Status)
+	assert.Equal(2, result.Facets.Categories["photography"])
+	assert.Equal(1, result.Facets.Categories["optics"])
+	assert.Equal(1, result.Facets.Status[ItemOpen])
+	assert.Equal(1, result.Facets.PriceRanges[1].Count)
+	assert.Equal(1, result.Facets.PriceRanges[3].Count)
+
+	result, err = items.SearchItems(ItemSearch{Text: "vintage", SortBy: SortByMostBids})
+	assert.Nil(err)
+	assert.Equal(3, result.Total)
+	assert.Equal(lens.UUID, result.Items[0].UUID)
+	assert.Equal(2, result.Items[0].BidCount)
+	assert.Equal(150.0, result.Items[0].CurrentPrice)
+
+	result, err = items.SearchItems(ItemSearch{Text: "vintage", Status: ItemClosed})
+	assert.Nil(err)
+	assert.Equal(1, result.Total)
+	assert.Equal(chair.UUID, result.Items[0].UUID)
+	assert.Equal(3, len(result.Facets.Status), "facets are counted before filtering")
+
+	result, err = items.SearchItems(ItemSearch{Categories: []string{"optics", "furniture"}, SortBy: SortByHighestPrice})
+	assert.Nil(err)
+	assert.Equal([]uuid.UUID{chair.UUID, lens.UUID}, []uuid.UUID{result.Items[0].UUID, result.Items[1].UUID})
+
+	result, err = items.SearchItems(ItemSearch{MinPrice: 100, MaxPrice: 500})
+	assert.Nil(err)
+	assert.Equal(1, result.Total)
+	assert.Equal(lens.UUID, result.Items[0].UUID)
+
+	result, err = items.SearchItems(ItemSearch{SortBy: SortByEndingSoonest, Limit: 1, Offset: 1})
+	assert.Nil(err)
+	assert.Equal(3, result.Total)
+	assert.Equal([]ItemSummary{result.Items[0]}, result.Items)
+	assert.Equal(lens.UUID, result.Items[0].UUID)
+
+	// closed auctions come after the open ones
+	result, err = items.SearchItems(ItemSearch{})
+	assert.Nil(err)
+	assert.Equal([]uuid.UUID{camera.UUID, lens.UUID, chair.UUID},
+		[]uuid.UUID{result.Items[0].UUID, result.Items[1].UUID, result.Items[2].UUID})
+
+	// the index follows item updates
+	camera.Title = "Rangefinder"
+	camera.Description = ""
+	assert.Nil(items.UpdateItem(camera))
+	result, err = items.SearchItems(ItemSearch{Text: "camera"})
+	assert.Nil(err)
+	assert.Equal(1, result.Total)
+	assert.Equal(lens.UUID, result.Items[0].UUID)
+	result, err = items.SearchItems(ItemSearch{Text: "rangefinder"})
+	assert.Nil(err)
+	assert.Equal(camera.UUID, result.Items[0].UUID)
+
+	result, err = items.SearchItems(ItemSearch{Text: "nothing like this"})
+	assert.Nil(err)
+	assert.Equal(0, result.Total)
+	assert.Empty(result.Items)
+
+	_, err = items.SearchItems(ItemSearch{Status: "pending"})
+	assert.NotNil(err)
+	_, err = items.SearchItems(ItemSearch{MinPrice: 10, MaxPrice: 5})
+	assert.NotNil(err)
+}
+
+func TestSearchItemsPriceRanges(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0, priceRange(-5), "prices below the first bound count in the first range")
+	assert.Equal(0, priceRange(0))
+	assert.Equal(len(priceRangeBounds)-1, priceRange(1e9))
+
+	item := uuid.Must(uuid.NewV4())
+	items := NewBidManagement(item)
+	err := items.InsertBid(&Bid{ItemUUID: item, UserUUID: uuid.Must(uuid.NewV4()), Amount: -5})
+	assert.True(errors.Is(err, ErrInvalidArgument), "bids must be positive")
+	assert.NotPanics(func() {
+		_, err = items.SearchItems(ItemSearch{})
+	})
+	assert.Nil(err)
+}
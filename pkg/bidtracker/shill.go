@@ -0,0 +1,365 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// SuspicionKind names a suspicious bidding pattern
+type SuspicionKind string
+
+const (
+	// SuspicionNeverWinning flags a user who keeps bidding up the items of one seller without winning any
+	SuspicionNeverWinning SuspicionKind = "never-winning"
+
+	// SuspicionNewUserBurst flags a burst of bids on an item from freshly seen users
+	SuspicionNewUserBurst SuspicionKind = "new-user-burst"
+
+	// SuspicionAlternatingPair flags two users taking turns outbidding each other
+	SuspicionAlternatingPair SuspicionKind = "alternating-pair"
+)
+
+// Suspicion is a flag raised by the ShillDetector against one or more users
+type Suspicion struct {
+	Kind      SuspicionKind `json:"kind"`
+	Users     []uuid.UUID   `json:"users"`
+	ItemUUIDs []uuid.UUID   `json:"itemuuids"`
+	Reason    string        `json:"reason"`
+}
+
+// ShillDetectorConfig holds the thresholds of the ShillDetector. A zero threshold disables the check.
+type ShillDetectorConfig struct {
+	// NeverWinningMinBids is the number of price raising bids a user must place on the items
+	// of one seller, while leading none of them, to be flagged
+	NeverWinningMinBids int
+
+	// NewUserAge is how long after their first bid a user is considered new
+	NewUserAge time.Duration
+	// BurstWindow and BurstMinUsers flag an item receiving bids from at least
+	// BurstMinUsers new users within BurstWindow
+	BurstWindow   time.Duration
+	BurstMinUsers int
+
+	// AlternationMinBids is the length of a strictly alternating run of bids
+	// between two users on one item for the pair to be flagged
+	AlternationMinBids int
+
+	// Block makes InsertBid reject bids from users who are currently flagged
+	Block bool
+}
+
+// DefaultShillDetectorConfig returns thresholds suitable for a report, without blocking bids
+func DefaultShillDetectorConfig() ShillDetectorConfig {
+	return ShillDetectorConfig{
+		NeverWinningMinBids: 10,
+		NewUserAge:          time.Hour,
+		BurstWindow:         time.Minute,
+		BurstMinUsers:       5,
+		AlternationMinBids:  8,
+	}
+}
+
+// ShillDetector looks for shill bidding and collusion patterns in the bid history
+type ShillDetector struct {
+	config ShillDetectorConfig
+}
+
+// NewShillDetector creates a new detector with the given thresholds
+func NewShillDetector(config ShillDetectorConfig) *ShillDetector {
+	return &ShillDetector{config: config}
+}
+
+// sellerOf groups items for the never-winning check.
+// Items without a seller in the catalogue are a group of their own.
+func sellerOf(state ItemBidState) uuid.UUID {
+	if state.Item.SellerUUID != uuid.Nil {
+		return state.Item.SellerUUID
+	}
+	return state.ItemID
+}
+
+// analyze runs every check over the given items. users is needed to know when a user was first seen.
+func (d *ShillDetector) analyze(items []ItemBidState, users map[uuid.UUID]UserBids) []Suspicion {
+	var suspicions []Suspicion
+	if d.config.NeverWinningMinBids > 0 {
+		suspicions = append(suspicions, d.neverWinning(items)...)
+	}
+
+	for _, state := range items {
+		if d.config.BurstMinUsers > 0 && d.config.BurstWindow > 0 {
+			suspicions = append(suspicions, d.newUserBursts(state, users)...)
+		}
+		if d.config.AlternationMinBids > 0 {
+			suspicions = append(suspicions, d.alternatingPairs(state)...)
+		}
+	}
+	return suspicions
+}
+
+func (d *ShillDetector) neverWinning(items []ItemBidState) []Suspicion {
+	raises := make(map[sellerUser]int)
+	itemsOf := make(map[sellerUser][]uuid.UUID)
+	leading := make(map[sellerUser]bool)
+
+	for _, state := range items {
+		seller := sellerOf(state)
+		if state.currentWinndingBid != nil {
+			leading[sellerUser{seller, state.currentWinndingBid.UserUUID}] = true
+		}
+
+		var highest float64
+		counted := make(map[uuid.UUID]bool)
+		for i, bid := range state.Bids {
+			if i > 0 && bid.Amount <= highest {
+				continue
+			}
+			highest = bid.Amount
+			key := sellerUser{seller, bid.UserUUID}
+			raises[key]++
+			if !counted[bid.UserUUID] {
+				counted[bid.UserUUID] = true
+				itemsOf[key] = append(itemsOf[key], state.ItemID)
+			}
+		}
+	}
+
+	var suspicions []Suspicion
+	for key, count := range raises {
+		if count < d.config.NeverWinningMinBids || leading[key] {
+			continue
+		}
+		suspicions = append(suspicions, Suspicion{
+			Kind:      SuspicionNeverWinning,
+			Users:     []uuid.UUID{key.user},
+			ItemUUIDs: itemsOf[key],
+			Reason:    fmt.Sprintf("raised the price %d times on items of one seller without leading any", count),
+		})
+	}
+	return suspicions
+}
+
+func (d *ShillDetector) newUserBursts(state ItemBidState, users map[uuid.UUID]UserBids) []Suspicion {
+	newUserAge := int64(d.config.NewUserAge / time.Second)
+	window := int64(d.config.BurstWindow / time.Second)
+
+	// Keep only the bids placed by users while they were new, ordered by time
+	var bids []Bid
+	for _, bid := range state.Bids {
+		if bid.Timestamp-firstSeen(users[bid.UserUUID]) <= newUserAge {
+			bids = append(bids, bid)
+		}
+	}
+	sort.SliceStable(bids, func(i, j int) bool { return bids[i].Timestamp < bids[j].Timestamp })
+
+	var suspicions []Suspicion
+	inWindow := make(map[uuid.UUID]int)
+	flagged := make(map[uuid.UUID]bool)
+	start := 0
+	for end, bid := range bids {
+		inWindow[bid.UserUUID]++
+		for bids[start].Timestamp < bid.Timestamp-window {
+			old := bids[start].UserUUID
+			if inWindow[old]--; inWindow[old] == 0 {
+				delete(inWindow, old)
+			}
+			start++
+		}
+		if len(inWindow) < d.config.BurstMinUsers {
+			continue
+		}
+
+		var burstUsers []uuid.UUID
+		for _, b := range bids[start : end+1] {
+			if !flagged[b.UserUUID] {
+				flagged[b.UserUUID] = true
+				burstUsers = append(burstUsers, b.UserUUID)
+			}
+		}
+		if len(burstUsers) > 0 {
+			suspicions = append(suspicions, Suspicion{
+				Kind:      SuspicionNewUserBurst,
+				Users:     burstUsers,
+				ItemUUIDs: []uuid.UUID{state.ItemID},
+				Reason:    fmt.Sprintf("%d new users bid within %s", len(inWindow), d.config.BurstWindow),
+			})
+		}
+	}
+	return suspicions
+}
+
+func firstSeen(userBids UserBids) int64 {
+	if len(userBids.Bids) == 0 {
+		return 0
+	}
+	first := userBids.Bids[0].Timestamp
+	for _, bid := range userBids.Bids[1:] {
+		if bid.Timestamp < first {
+			first = bid.Timestamp
+		}
+	}
+	return first
+}
+
+func (d *ShillDetector) alternatingPairs(state ItemBidState) []Suspicion {
+	type pair struct {
+		a, b uuid.UUID
+	}
+	flagged := make(map[pair]bool)
+
+	var suspicions []Suspicion
+	run := 1
+	for i := 1; i < len(state.Bids); i++ {
+		prev, cur := state.Bids[i-1].UserUUID, state.Bids[i].UserUUID
+		switch {
+		case cur == prev:
+			run = 1
+			continue
+		case i >= 2 && state.Bids[i-2].UserUUID == cur:
+			run++
+		default:
+			run = 2
+		}
+
+		if run < d.config.AlternationMinBids {
+			continue
+		}
+		p := pair{prev, cur}
+		if prev.String() > cur.String() {
+			p = pair{cur, prev}
+		}
+		if flagged[p] {
+			continue
+		}
+		flagged[p] = true
+		suspicions = append(suspicions, Suspicion{
+			Kind:      SuspicionAlternatingPair,
+			Users:     []uuid.UUID{p.a, p.b},
+			ItemUUIDs: []uuid.UUID{state.ItemID},
+			Reason:    fmt.Sprintf("took turns bidding at least %d times in a row", run),
+		})
+	}
+	return suspicions
+}
+
+// isFlagged reports whether the user is part of any of the suspicions
+func isFlagged(suspicions []Suspicion, user uuid.UUID) (Suspicion, bool) {
+	for _, s := range suspicions {
+		for _, u := range s.Users {
+			if u == user {
+				return s, true
+			}
+		}
+	}
+	return Suspicion{}, false
+}
+
+// SetShillDetector configures the detector used by ShillReport, and by InsertBid if it blocks bids
+func (ibm *BidManagement) SetShillDetector(detector *ShillDetector) {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	ibm.shillDetector = detector
+	ibm.shillStats = nil
+}
+
+// ShillReport analyzes every item and returns the suspicious patterns found
+func (ibm *BidManagement) ShillReport() []Suspicion {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	detector := ibm.shillDetector
+	if detector == nil {
+		detector = NewShillDetector(DefaultShillDetectorConfig())
+	}
+
+	items := make([]ItemBidState, 0, len(ibm.itemsMap))
+	for _, state := range ibm.itemsMap {
+		items = append(items, state)
+	}
+	suspicions := ibm.withoutCleared(detector.analyze(items, ibm.userBidMap))
+
+	sort.SliceStable(suspicions, func(i, j int) bool {
+		if suspicions[i].Kind != suspicions[j].Kind {
+			return suspicions[i].Kind < suspicions[j].Kind
+		}
+		return suspicions[i].ItemUUIDs[0].String() < suspicions[j].ItemUUIDs[0].String()
+	})
+	return suspicions
+}
+
+// checkShillBid rejects the bid if blocking is enabled and its user is flagged on
+// the items sharing a seller with the bid item. The caller must hold the lock.
+func (ibm *BidManagement) checkShillBid(bid *Bid) error {
+	if ibm.shillDetector == nil || !ibm.shillDetector.config.Block {
+		return nil
+	}
+
+	if ibm.shillCleared[bid.UserUUID] {
+		return nil
+	}
+	seller := sellerOf(ibm.itemsMap[bid.ItemUUID])
+	if kind, flagged := ibm.shillStatsOf(ibm.shillDetector.config).check(seller, bid.UserUUID); flagged {
+		return fmt.Errorf("%w, user %s is flagged for %s bidding", ErrShillBid, bid.UserUUID, kind)
+	}
+	return nil
+}
+
+// ClearShillFlags clears the user of shill bidding, e.g. once a moderator found the flags to be a false positive.
+// InsertBid accepts the bids of the user again and ShillReport leaves the user out.
+func (ibm *BidManagement) ClearShillFlags(useruuid uuid.UUID) error {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	if _, ok := ibm.userBidMap[useruuid]; !ok {
+		return fmt.Errorf("%w with uuid %s", ErrUserNotFound, useruuid)
+	}
+	ibm.shillCleared[useruuid] = true
+	return nil
+}
+
+// withoutCleared drops the cleared users from the suspicions, and the suspicions left without users.
+// The caller must hold the lock.
+func (ibm *BidManagement) withoutCleared(suspicions []Suspicion) []Suspicion {
+	if len(ibm.shillCleared) == 0 {
+		return suspicions
+	}
+
+	kept := suspicions[:0]
+	for _, s := range suspicions {
+		var users []uuid.UUID
+		for _, user := range s.Users {
+			if !ibm.shillCleared[user] {
+				users = append(users, user)
+			}
+		}
+		if len(users) > 0 {
+			s.Users = users
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package bidtracker
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShillNeverWinning(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148"))
+	shill := uuid.Must(uuid.FromString("8f2f2a79-9091-44fb-9fe3-3eb5f0d76746"))
+	buyer := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+
+	items := NewBidManagement(itemUUID)
+	items.SetShillDetector(NewShillDetector(ShillDetectorConfig{NeverWinningMinBids: 3}))
+
+	amount := 10.0
+	for i := 0; i < 3; i++ {
+		assert.Nil(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: shill, Timestamp: int64(i), Amount: amount}))
+		assert.Nil(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: buyer, Timestamp: int64(i), Amount: amount + 1}))
+		amount += 2
+	}
+
+	report := items.ShillReport()
+	if assert.Len(report, 1) {
+		assert.Equal(SuspicionNeverWinning, report[0].Kind)
+		assert.Equal([]uuid.UUID{shill}, report[0].Users)
+		assert.Equal([]uuid.UUID{itemUUID}, report[0].ItemUUIDs)
+	}
+}
+
+func TestShillNewUserBurst(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148"))
+	items := NewBidManagement(itemUUID)
+	items.SetShillDetector(NewShillDetector(ShillDetectorConfig{
+		NewUserAge:    time.Hour,
+		BurstWindow:   time.Minute,
+		BurstMinUsers: 3,
+	}))
+
+	// An established user bids long before
+	regular := uuid.Must(uuid.NewV4())
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: regular, Timestamp: 0, Amount: 1}))
+	assert.Empty(items.ShillReport())
+
+	for i := 0; i < 3; i++ {
+		bid := &Bid{ItemUUID: itemUUID, UserUUID: uuid.Must(uuid.NewV4()), Timestamp: 10000 + int64(i*10), Amount: float64(i + 2)}
+		assert.Nil(items.InsertBid(bid))
+	}
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: regular, Timestamp: 10030, Amount: 10}))
+
+	report := items.ShillReport()
+	if assert.Len(report, 1) {
+		assert.Equal(SuspicionNewUserBurst, report[0].Kind)
+		assert.Len(report[0].Users, 3)
+		assert.NotContains(report[0].Users, regular)
+	}
+}
+
+func TestShillAlternatingPairBlocked(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148"))
+	userA := uuid.Must(uuid.FromString("8f2f2a79-9091-44fb-9fe3-3eb5f0d76746"))
+	userB := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+	other := uuid.Must(uuid.FromString("f475091b-a8f1-4679-83bd-483b616e5260"))
+
+	items := NewBidManagement(itemUUID)
+	items.SetShillDetector(NewShillDetector(ShillDetectorConfig{AlternationMinBids: 4, Block: true}))
+
+	users := []uuid.UUID{userA, userB, userA, userB}
+	for i, user := range users {
+		assert.Nil(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: user, Timestamp: int64(i), Amount: float64(i + 1)}))
+	}
+
+	report := items.ShillReport()
+	if assert.Len(report, 1) {
+		assert.Equal(SuspicionAlternatingPair, report[0].Kind)
+		assert.ElementsMatch([]uuid.UUID{userA, userB}, report[0].Users)
+	}
+
+	// THEN the flagged users can not bid anymore, others still can
+	assert.NotNil(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: userA, Timestamp: 5, Amount: 10}))
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: other, Timestamp: 5, Amount: 10}))
+	assert.Equal(5, len(items.itemsMap[itemUUID].Bids))
+}
+
+func TestShillClearFlags(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148"))
+	userA := uuid.Must(uuid.FromString("8f2f2a79-9091-44fb-9fe3-3eb5f0d76746"))
+	userB := uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+
+	items := NewBidManagement(itemUUID)
+	items.SetShillDetector(NewShillDetector(ShillDetectorConfig{AlternationMinBids: 4, Block: true}))
+
+	for i, user := range []uuid.UUID{userA, userB, userA, userB} {
+		assert.Nil(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: user, Timestamp: int64(i), Amount: float64(i + 1)}))
+	}
+	assert.True(errors.Is(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: userA, Timestamp: 5, Amount: 10}), ErrShillBid))
+
+	// WHEN a moderator clears userA
+	assert.True(errors.Is(items.ClearShillFlags(uuid.Must(uuid.NewV4())), ErrUserNotFound))
+	assert.Nil(items.ClearShillFlags(userA))
+
+	// THEN the bids of userA are accepted again, and only userB is left in the report
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: userA, Timestamp: 5, Amount: 10}))
+	report := items.ShillReport()
+	if assert.Len(report, 1) {
+		assert.Equal([]uuid.UUID{userB}, report[0].Users)
+	}
+	assert.True(errors.Is(items.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: userB, Timestamp: 6, Amount: 11}), ErrShillBid))
+
+	// THEN the clearance survives a snapshot and the replay of the flags
+	restored := NewBidManagement()
+	restored.SetShillDetector(NewShillDetector(ShillDetectorConfig{AlternationMinBids: 4, Block: true}))
+	assert.Nil(restored.Restore(items.Snapshot()))
+	assert.Nil(restored.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: userA, Timestamp: 7, Amount: 12}))
+	assert.True(errors.Is(restored.InsertBid(&Bid{ItemUUID: itemUUID, UserUUID: userB, Timestamp: 8, Amount: 13}), ErrShillBid))
+}
+
+func TestShillStatsMatchAnalyze(t *testing.T) {
+	assert := assert.New(t)
+
+	seller := uuid.Must(uuid.NewV4())
+	itemUUIDs := []uuid.UUID{uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())}
+	items := NewBidManagement(itemUUIDs[2])
+	for _, itemUUID := range itemUUIDs[:2] {
+		assert.Nil(items.AddItem(Item{UUID: itemUUID, Title: "Item", SellerUUID: seller}))
+	}
+	detector := NewShillDetector(ShillDetectorConfig{
+		NeverWinningMinBids: 4,
+		NewUserAge:          10 * time.Minute,
+		BurstWindow:         time.Minute,
+		BurstMinUsers:       3,
+		AlternationMinBids:  4,
+		Block:               true,
+	})
+	items.SetShillDetector(detector)
+
+	users := make([]uuid.UUID, 12)
+	for i := range users {
+		users[i] = uuid.Must(uuid.NewV4())
+	}
+
+	// WHEN bids come in over time from a growing set of users
+	random := rand.New(rand.NewSource(42))
+	highest := make(map[uuid.UUID]float64)
+	timestamp := int64(0)
+	rejected := 0
+	for i := 0; i < 600; i++ {
+		timestamp += random.Int63n(20)
+		itemUUID := itemUUIDs[random.Intn(len(itemUUIDs))]
+		// new users show up four at a time
+		user := users[random.Intn(4+i/200*4)]
+		bid := Bid{ItemUUID: itemUUID, UserUUID: user, Timestamp: timestamp, Amount: highest[itemUUID] + 1 + float64(random.Intn(5))}
+
+		var sellerItems []ItemBidState
+		for _, state := range items.itemsMap {
+			if sellerOf(state) == sellerOf(items.itemsMap[itemUUID]) {
+				sellerItems = append(sellerItems, state)
+			}
+		}
+		_, flagged := isFlagged(detector.analyze(sellerItems, items.userBidMap), user)
+
+		// THEN the bid is rejected exactly when analyzing the whole history flags its user
+		err := items.InsertBid(&bid)
+		assert.Equal(flagged, errors.Is(err, ErrShillBid), "bid %d", i)
+		if err == nil {
+			highest[itemUUID] = bid.Amount
+		} else {
+			rejected++
+		}
+	}
+	assert.True(rejected > 0 && rejected < 600, "%d bids rejected", rejected)
+	kinds := make(map[SuspicionKind]bool)
+	for _, suspicion := range items.ShillReport() {
+		kinds[suspicion.Kind] = true
+	}
+	assert.Len(kinds, 3, "every check flags someone")
+
+	// THEN the stats are rebuilt the same from the history
+	rebuilt := items.shillStats
+	items.shillStats = nil
+	assert.Equal(rebuilt.flagged, items.shillStatsOf(detector.config).flagged)
+}
@@ -0,0 +1,187 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// sellerUser is a user bidding on the items of a seller, as grouped by sellerOf
+type sellerUser struct {
+	seller, user uuid.UUID
+}
+
+// shillStats keeps what checkShillBid needs up to date bid after bid, instead of analyzing the whole history of
+// a seller on every bid. Fed with the bids of each item in order, it flags the users ShillDetector.analyze flags.
+type shillStats struct {
+	config ShillDetectorConfig
+	// raises counts the bids of a user raising the price of the items of a seller, leads the items it leads
+	raises map[sellerUser]int
+	leads  map[sellerUser]int
+	// flagged holds the users caught in a burst or an alternation, the bids behind it stay in the history
+	flagged   map[sellerUser]SuspicionKind
+	firstSeen map[uuid.UUID]int64
+	items     map[uuid.UUID]*itemShillStats
+}
+
+// itemShillStats follows the bids of a single item
+type itemShillStats struct {
+	bids int
+	// last and beforeLast are the users of the last two bids, run is the length of their alternation
+	last, beforeLast uuid.UUID
+	run              int
+	// recent holds the bids placed by new users within the burst window of the last of them
+	recent []Bid
+}
+
+func newShillStats(config ShillDetectorConfig) *shillStats {
+	return &shillStats{
+		config:    config,
+		raises:    make(map[sellerUser]int),
+		leads:     make(map[sellerUser]int),
+		flagged:   make(map[sellerUser]SuspicionKind),
+		firstSeen: make(map[uuid.UUID]int64),
+		items:     make(map[uuid.UUID]*itemShillStats),
+	}
+}
+
+// add accounts for a bid on an item of seller. previousLeader is the winning bid of the item before it,
+// and raise reports whether the bid became the winning bid.
+func (s *shillStats) add(seller uuid.UUID, bid Bid, previousLeader *Bid, raise bool) {
+	if first, ok := s.firstSeen[bid.UserUUID]; !ok || bid.Timestamp < first {
+		s.firstSeen[bid.UserUUID] = bid.Timestamp
+	}
+
+	key := sellerUser{seller, bid.UserUUID}
+	if raise {
+		s.raises[key]++
+		if previousLeader != nil {
+			previous := sellerUser{seller, previousLeader.UserUUID}
+			if s.leads[previous]--; s.leads[previous] <= 0 {
+				delete(s.leads, previous)
+			}
+		}
+		s.leads[key]++
+	}
+
+	item, ok := s.items[bid.ItemUUID]
+	if !ok {
+		item = &itemShillStats{}
+		s.items[bid.ItemUUID] = item
+	}
+	s.alternate(seller, item, bid)
+	s.burst(seller, item, bid)
+	item.bids++
+}
+
+// alternate follows the run of alternatingPairs, the item is not updated with the bid yet
+func (s *shillStats) alternate(seller uuid.UUID, item *itemShillStats, bid Bid) {
+	switch {
+	case item.bids == 0 || bid.UserUUID == item.last:
+		item.run = 1
+	case item.bids >= 2 && bid.UserUUID == item.beforeLast:
+		item.run++
+	default:
+		item.run = 2
+	}
+	previous := item.last
+	item.beforeLast, item.last = item.last, bid.UserUUID
+
+	if s.config.AlternationMinBids > 0 && item.run >= s.config.AlternationMinBids {
+		s.flag(sellerUser{seller, previous}, SuspicionAlternatingPair)
+		s.flag(sellerUser{seller, bid.UserUUID}, SuspicionAlternatingPair)
+	}
+}
+
+// burst follows the sliding window of newUserBursts over the bids of new users
+func (s *shillStats) burst(seller uuid.UUID, item *itemShillStats, bid Bid) {
+	if s.config.BurstMinUsers <= 0 || s.config.BurstWindow <= 0 {
+		return
+	}
+	if bid.Timestamp-s.firstSeen[bid.UserUUID] > int64(s.config.NewUserAge/time.Second) {
+		return
+	}
+
+	window := int64(s.config.BurstWindow / time.Second)
+	start := 0
+	for start < len(item.recent) && item.recent[start].Timestamp < bid.Timestamp-window {
+		start++
+	}
+	item.recent = append(item.recent[start:], bid)
+
+	users := make(map[uuid.UUID]bool, len(item.recent))
+	for _, recent := range item.recent {
+		users[recent.UserUUID] = true
+	}
+	if len(users) < s.config.BurstMinUsers {
+		return
+	}
+	for user := range users {
+		s.flag(sellerUser{seller, user}, SuspicionNewUserBurst)
+	}
+}
+
+func (s *shillStats) flag(key sellerUser, kind SuspicionKind) {
+	if _, ok := s.flagged[key]; !ok {
+		s.flagged[key] = kind
+	}
+}
+
+// check returns the suspicion the user is flagged for on the items of seller, if any
+func (s *shillStats) check(seller, user uuid.UUID) (SuspicionKind, bool) {
+	key := sellerUser{seller, user}
+	if kind, ok := s.flagged[key]; ok {
+		return kind, true
+	}
+	if s.config.NeverWinningMinBids > 0 && s.raises[key] >= s.config.NeverWinningMinBids && s.leads[key] == 0 {
+		return SuspicionNeverWinning, true
+	}
+	return "", false
+}
+
+// shillStatsOf returns the stats of the tracker, replaying the history if they have been reset.
+// The caller must hold the lock.
+func (ibm *BidManagement) shillStatsOf(config ShillDetectorConfig) *shillStats {
+	if ibm.shillStats != nil {
+		return ibm.shillStats
+	}
+
+	stats := newShillStats(config)
+	for user, userBids := range ibm.userBidMap {
+		stats.firstSeen[user] = firstSeen(userBids)
+	}
+	for _, state := range ibm.itemsMap {
+		seller := sellerOf(state)
+		var leader *Bid
+		for i, bid := range state.Bids {
+			raise := leader == nil || bid.Amount > leader.Amount
+			stats.add(seller, bid, leader, raise)
+			if raise {
+				leader = &state.Bids[i]
+			}
+		}
+	}
+	ibm.shillStats = stats
+	return stats
+}
@@ -0,0 +1,174 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// SnapshotVersion is the version of the snapshot format written by this package
+const SnapshotVersion = 1
+
+// Snapshot is the state of a tracker that survives a restart
+type Snapshot struct {
+	Version int            `json:"version"`
+	Items   []SnapshotItem `json:"items"`
+	// ShillCleared are the users a moderator cleared of shill bidding
+	ShillCleared []uuid.UUID `json:"shillcleared,omitempty"`
+}
+
+// SnapshotItem is an item with its bids in the order they were placed
+type SnapshotItem struct {
+	Item Item  `json:"item"`
+	Bids []Bid `json:"bids"`
+}
+
+// Snapshot copies the items and bids held by the tracker, ordered by itemuuid
+func (ibm *BidManagement) Snapshot() Snapshot {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	snapshot := Snapshot{Version: SnapshotVersion, Items: make([]SnapshotItem, 0, len(ibm.itemsMap))}
+	for _, itemMetaInfo := range ibm.itemsMap {
+		snapshot.Items = append(snapshot.Items, SnapshotItem{
+			Item: itemMetaInfo.Item,
+			Bids: append([]Bid{}, itemMetaInfo.Bids...),
+		})
+	}
+	sort.Slice(snapshot.Items, func(i, j int) bool {
+		return snapshot.Items[i].Item.UUID.String() < snapshot.Items[j].Item.UUID.String()
+	})
+
+	for user := range ibm.shillCleared {
+		snapshot.ShillCleared = append(snapshot.ShillCleared, user)
+	}
+	sort.Slice(snapshot.ShillCleared, func(i, j int) bool {
+		return snapshot.ShillCleared[i].String() < snapshot.ShillCleared[j].String()
+	})
+	return snapshot
+}
+
+// Restore replaces the items and bids held by the tracker with the snapshot.
+// Bids are replayed without validation, the bids of a user end up in timestamp order.
+func (ibm *BidManagement) Restore(snapshot Snapshot) error {
+	if snapshot.Version != SnapshotVersion {
+		return fmt.Errorf("Unsupported snapshot version %d", snapshot.Version)
+	}
+
+	type replay struct {
+		key int64
+		bid Bid
+	}
+	var replays []replay
+	itemsMap := make(map[uuid.UUID]ItemBidState, len(snapshot.Items))
+	searchIndex := newSearchIndex()
+	for _, snapshotItem := range snapshot.Items {
+		item := snapshotItem.Item
+		if _, ok := itemsMap[item.UUID]; ok {
+			return fmt.Errorf("Item %s is repeated in the snapshot", item.UUID)
+		}
+		itemsMap[item.UUID] = ItemBidState{
+			ItemID:      item.UUID,
+			Bids:        []Bid{},
+			Item:        item,
+			leaderboard: newLeaderboard(),
+		}
+		searchIndex.index(item)
+
+		// The running maximum keeps the bids of an item in order once sorted by timestamp
+		var key int64
+		for i, bid := range snapshotItem.Bids {
+			if bid.ItemUUID != item.UUID {
+				return fmt.Errorf("Bid %d of item %s belongs to item %s", i, item.UUID, bid.ItemUUID)
+			}
+			if i == 0 || bid.Timestamp > key {
+				key = bid.Timestamp
+			}
+			replays = append(replays, replay{key, bid})
+		}
+	}
+	sort.SliceStable(replays, func(i, j int) bool { return replays[i].key < replays[j].key })
+
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	ibm.itemsMap = itemsMap
+	ibm.userBidMap = make(map[uuid.UUID]UserBids)
+	ibm.searchIndex = searchIndex
+	ibm.shillStats = nil
+	ibm.shillCleared = make(map[uuid.UUID]bool, len(snapshot.ShillCleared))
+	for _, user := range snapshot.ShillCleared {
+		ibm.shillCleared[user] = true
+	}
+	for i := range replays {
+		bid := replays[i].bid
+		ibm.applyBid(ibm.itemsMap[bid.ItemUUID], &bid)
+	}
+	return nil
+}
+
+// SaveSnapshot writes the snapshot of the tracker as json to path, atomically replacing the previous one
+func (ibm *BidManagement) SaveSnapshot(path string) error {
+	content, err := json.Marshal(ibm.Snapshot())
+	if err != nil {
+		return errors.WithMessage(err, "Failed to encode the snapshot")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.WithMessage(err, "Failed to write the snapshot")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return errors.WithMessage(err, "Failed to write the snapshot")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.WithMessage(err, "Failed to write the snapshot")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.WithMessage(err, "Failed to write the snapshot")
+	}
+	return errors.WithMessage(os.Rename(tmp.Name(), path), "Failed to write the snapshot")
+}
+
+// LoadSnapshot reads a snapshot written by SaveSnapshot
+func LoadSnapshot(path string) (Snapshot, error) {
+	var snapshot Snapshot
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, errors.WithMessage(err, "Failed to read the snapshot")
+	}
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return snapshot, errors.WithMessagef(err, "Failed to parse the snapshot %s", path)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	camera := Item{UUID: uuid.Must(uuid.FromString("6aa04324-8aea-4a42-a948-e1da58c86148")), Title: "Vintage camera"}
+	bare := uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	items := NewBidManagement(bare)
+	assert.Nil(items.AddItem(camera))
+
+	// Imported history may hold equal bids, which the restore must replay in order
+	alice, bob := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	for _, bid := range []Bid{
+		{ItemUUID: camera.UUID, UserUUID: alice, Timestamp: 10, Amount: 5},
+		{ItemUUID: bare, UserUUID: alice, Timestamp: 20, Amount: 7},
+		{ItemUUID: camera.UUID, UserUUID: bob, Timestamp: 15, Amount: 9},
+		{ItemUUID: camera.UUID, UserUUID: alice, Timestamp: 30, Amount: 9},
+	} {
+		assert.Nil(items.importBid(bid, false))
+	}
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	assert.Nil(items.SaveSnapshot(path))
+	snapshot, err := LoadSnapshot(path)
+	assert.Nil(err)
+	assert.Equal(items.Snapshot(), snapshot)
+
+	restored := NewBidManagement()
+	assert.Nil(restored.Restore(snapshot))
+	assert.Equal(items.Snapshot(), restored.Snapshot())
+
+	winning, err := restored.CurrentWinningBid(camera.UUID)
+	assert.Nil(err)
+	assert.Equal(bob, winning.UserUUID, "The earlier of two equal bids keeps winning")
+	leaderboard, err := restored.Leaderboard(camera.UUID, 0)
+	assert.Nil(err)
+	assert.Equal([]uuid.UUID{bob, alice}, []uuid.UUID{leaderboard[0].UserUUID, leaderboard[1].UserUUID})
+
+	aliceBids, err := restored.GetBidsByUser(alice)
+	assert.Nil(err)
+	assert.Equal([]int64{10, 20, 30}, []int64{aliceBids[0].Timestamp, aliceBids[1].Timestamp, aliceBids[2].Timestamp})
+
+	result, err := restored.SearchItems(ItemSearch{Text: "camera"})
+	assert.Nil(err)
+	assert.Equal(1, result.Total)
+}
+
+func TestRestoreRejectsInvalidSnapshots(t *testing.T) {
+	assert := assert.New(t)
+
+	itemUUID := uuid.Must(uuid.NewV4())
+	items := NewBidManagement()
+	assert.NotNil(items.Restore(Snapshot{Version: SnapshotVersion + 1}))
+	assert.NotNil(items.Restore(Snapshot{Version: SnapshotVersion, Items: []SnapshotItem{
+		{Item: Item{UUID: itemUUID}}, {Item: Item{UUID: itemUUID}},
+	}}))
+	assert.NotNil(items.Restore(Snapshot{Version: SnapshotVersion, Items: []SnapshotItem{
+		{Item: Item{UUID: itemUUID}, Bids: []Bid{{ItemUUID: uuid.Must(uuid.NewV4())}}},
+	}}))
+
+	_, err := LoadSnapshot(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NotNil(err)
+}
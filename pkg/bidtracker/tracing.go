@@ -0,0 +1,57 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/ansrivas/bid-tracker/pkg/bidtracker"
+
+// tracerProviderHolder keeps the concrete type stored in the atomic.Value constant
+type tracerProviderHolder struct {
+	trace.TracerProvider
+}
+
+// SetTracerProvider sets the provider of the spans created by BidManagement.
+// The global provider of otel is used if this is not called.
+func (ibm *BidManagement) SetTracerProvider(provider trace.TracerProvider) {
+	ibm.tracerProvider.Store(tracerProviderHolder{provider})
+}
+
+func (ibm *BidManagement) tracer() trace.Tracer {
+	if holder, ok := ibm.tracerProvider.Load().(tracerProviderHolder); ok && holder.TracerProvider != nil {
+		return holder.Tracer(tracerName)
+	}
+	return otel.Tracer(tracerName)
+}
+
+// endSpan records err on span, if any, and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
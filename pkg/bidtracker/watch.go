@@ -0,0 +1,142 @@
+//
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// WatchBufferSize is the number of events a watch may fall behind before it is dropped
+const WatchBufferSize = 64
+
+var (
+	// ErrWatchLagged is returned by Watch.Err once the watch was dropped for not keeping up with the bids
+	ErrWatchLagged = errors.New("Watch fell behind the bids and was dropped")
+
+	// ErrWatchStopped is returned by Watch.Err once the watch was stopped by CloseWatches
+	ErrWatchStopped = errors.New("Watch stopped, the tracker is shutting down")
+)
+
+// ItemEvent is sent to the watches of an item whenever a bid on it is accepted
+type ItemEvent struct {
+	Bid Bid `json:"bid"`
+	// Leader is set if the bid became the current winning bid
+	Leader     bool `json:"leader"`
+	WinningBid Bid  `json:"winningbid"`
+}
+
+// Watch receives the events of an item until it is closed
+type Watch struct {
+	ibm      *BidManagement
+	itemuuid uuid.UUID
+	events   chan ItemEvent
+	// err is guarded by the lock of ibm
+	err error
+}
+
+// Events returns the channel of events, it is closed once the watch ends
+func (w *Watch) Events() <-chan ItemEvent {
+	return w.events
+}
+
+// Err tells why Events was closed: nil after Close, ErrWatchLagged or ErrWatchStopped otherwise
+func (w *Watch) Err() error {
+	w.ibm.Lock()
+	defer w.ibm.Unlock()
+
+	return w.err
+}
+
+// Close stops the watch, it can be called more than once
+func (w *Watch) Close() {
+	w.ibm.Lock()
+	defer w.ibm.Unlock()
+
+	w.ibm.dropWatch(w, nil)
+}
+
+// WatchItem returns a Watch receiving the bids accepted on the item from now on
+func (ibm *BidManagement) WatchItem(itemuuid uuid.UUID) (*Watch, error) {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	if _, ok := ibm.itemsMap[itemuuid]; !ok {
+		return nil, fmt.Errorf("%w. %s", ErrItemNotFound, itemuuid)
+	}
+	if ibm.watches == nil {
+		ibm.watches = make(map[uuid.UUID]map[*Watch]struct{})
+	}
+	if ibm.watches[itemuuid] == nil {
+		ibm.watches[itemuuid] = make(map[*Watch]struct{})
+	}
+
+	w := &Watch{ibm: ibm, itemuuid: itemuuid, events: make(chan ItemEvent, WatchBufferSize)}
+	ibm.watches[itemuuid][w] = struct{}{}
+	return w, nil
+}
+
+// CloseWatches stops every watch, e.g. before shutting down the servers streaming them
+func (ibm *BidManagement) CloseWatches() {
+	ibm.Lock()
+	defer ibm.Unlock()
+
+	for _, watches := range ibm.watches {
+		for w := range watches {
+			ibm.dropWatch(w, ErrWatchStopped)
+		}
+	}
+}
+
+// notifyWatches sends the event of an accepted bid to the watches of its item, dropping the ones
+// that are too far behind. It must be called with the lock held.
+func (ibm *BidManagement) notifyWatches(bid Bid, leader bool) {
+	watches := ibm.watches[bid.ItemUUID]
+	if len(watches) == 0 {
+		return
+	}
+
+	event := ItemEvent{Bid: bid, Leader: leader, WinningBid: *ibm.itemsMap[bid.ItemUUID].currentWinndingBid}
+	for w := range watches {
+		select {
+		case w.events <- event:
+		default:
+			ibm.dropWatch(w, ErrWatchLagged)
+		}
+	}
+}
+
+// dropWatch unregisters w and closes its events with err, it must be called with the lock held
+func (ibm *BidManagement) dropWatch(w *Watch, err error) {
+	watches := ibm.watches[w.itemuuid]
+	if _, ok := watches[w]; !ok {
+		return
+	}
+	delete(watches, w)
+	if len(watches) == 0 {
+		delete(ibm.watches, w.itemuuid)
+	}
+	w.err = err
+	close(w.events)
+}
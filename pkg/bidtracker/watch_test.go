@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bidtracker
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchItem(t *testing.T) {
+	assert := assert.New(t)
+
+	itemuuid := uuid.Must(uuid.NewV4())
+	items := NewBidManagement(itemuuid)
+	user := uuid.Must(uuid.NewV4())
+
+	_, err := items.WatchItem(uuid.Must(uuid.NewV4()))
+	assert.NotNil(err)
+
+	watch, err := items.WatchItem(itemuuid)
+	assert.Nil(err)
+	assert.Nil(items.InsertBid(&Bid{ItemUUID: itemuuid, UserUUID: user, Amount: 10}))
+	assert.NotNil(items.InsertBid(&Bid{ItemUUID: itemuuid, UserUUID: user, Amount: 5}), "rejected bids are not sent")
+	// Imported bids do not have to beat the winning bid
+	assert.Nil(items.importBid(Bid{ItemUUID: itemuuid, UserUUID: user, Amount: 5}, false))
+
+	event := <-watch.Events()
+	assert.True(event.Leader)
+	assert.Equal(10.0, event.Bid.Amount)
+	event = <-watch.Events()
+	assert.False(event.Leader)
+	assert.Equal(10.0, event.WinningBid.Amount)
+
+	watch.Close()
+	watch.Close()
+	_, open := <-watch.Events()
+	assert.False(open)
+	assert.Nil(watch.Err())
+}
+
+func TestWatchItemDropped(t *testing.T) {
+	assert := assert.New(t)
+
+	itemuuid := uuid.Must(uuid.NewV4())
+	items := NewBidManagement(itemuuid)
+	user := uuid.Must(uuid.NewV4())
+
+	lagging, _ := items.WatchItem(itemuuid)
+	for i := 0; i <= WatchBufferSize; i++ {
+		assert.Nil(items.InsertBid(&Bid{ItemUUID: itemuuid, UserUUID: user, Amount: float64(i + 1)}))
+	}
+	for range lagging.Events() {
+	}
+	assert.Equal(ErrWatchLagged, lagging.Err())
+
+	stopped, _ := items.WatchItem(itemuuid)
+	items.CloseWatches()
+	_, open := <-stopped.Events()
+	assert.False(open)
+	assert.Equal(ErrWatchStopped, stopped.Err())
+}
@@ -0,0 +1,81 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// exportBids writes the bids of the store ordered by item, in the order they were placed
+func exportBids(args []string, env Env) error {
+	flags, store := newFlagSet("bids export", env)
+	format := flags.String("format", bidtracker.FormatJSONL, "jsonl or csv")
+	item := flags.String("item", "", "only export the bids of this itemuuid")
+	out := flags.String("out", "-", "file to write, - for stdout")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	var itemuuid uuid.UUID
+	if *item != "" {
+		var err error
+		if itemuuid, err = uuid.FromString(*item); err != nil {
+			return errors.WithMessage(err, "Invalid itemuuid")
+		}
+	}
+
+	tracker, err := openStore(*store, false)
+	if err != nil {
+		return err
+	}
+
+	var output io.Writer = env.Stdout
+	if *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return errors.WithMessage(err, "Failed to create the output")
+		}
+		defer file.Close()
+		output = file
+	}
+
+	writer, err := bidtracker.NewBidWriter(output, *format)
+	if err != nil {
+		return err
+	}
+
+	count, err := tracker.ExportBids(writer, itemuuid)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to write the bids")
+	}
+	if err := writer.Flush(); err != nil {
+		return errors.WithMessage(err, "Failed to write the bids")
+	}
+	fmt.Fprintf(env.Stderr, "Exported %d bids\n", count)
+	return nil
+}
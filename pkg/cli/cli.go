@@ -0,0 +1,148 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package cli implements the offline commands of bid-tracker, they work on the json snapshot
+// written by the file storage backend without a running server.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/pkg/errors"
+)
+
+// StoreEnv is the environment variable holding the default snapshot of every command
+const StoreEnv = "BIDTRACKER_STORAGE_PATH"
+
+// Env is what a command may use from its surroundings
+type Env struct {
+	// Getenv is usually os.Getenv
+	Getenv func(string) string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// command is a subcommand, e.g. "items list"
+type command struct {
+	name        string
+	description string
+	run         func(args []string, env Env) error
+}
+
+var commands = []command{
+	{"items import", "add the items of a json lines or csv file to the store", importItems},
+	{"items list", "list the items of the store", listItems},
+	{"bids import", "record the historical bids of a json lines or csv file in the store", importBids},
+	{"bids export", "write the bids of the store as json lines or csv", exportBids},
+	{"inspect", "print a summary of the store", inspect},
+	{"replay", "apply a log of bids to the store, validating every bid", replay},
+}
+
+// IsCommand reports whether name is the first word of a command handled by Run
+func IsCommand(name string) bool {
+	for _, c := range commands {
+		if strings.Fields(c.name)[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes the command at the start of args, e.g. ["items", "list", "-store", "state.json"].
+// flag.ErrHelp is returned once the usage has been printed.
+func Run(args []string, env Env) error {
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == c.name {
+			return c.run(args[len(words):], env)
+		}
+	}
+	Usage(env.Stderr)
+	if len(args) == 0 {
+		return flag.ErrHelp
+	}
+	return fmt.Errorf("Unknown command %q", strings.Join(args, " "))
+}
+
+// Usage prints the commands handled by Run
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Offline commands, see bid-tracker <command> -h:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", c.name, c.description)
+	}
+}
+
+// newFlagSet returns the flags of the command name with the -store flag every command has
+func newFlagSet(name string, env Env) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet("bid-tracker "+name, flag.ContinueOnError)
+	flags.SetOutput(env.Stderr)
+	store := flags.String("store", env.Getenv(StoreEnv), "json snapshot written by the file storage backend")
+	return flags, store
+}
+
+// parseFlags parses args, keeping flag.ErrHelp as is since the usage has been printed already
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errors.WithMessage(err, "Invalid command line")
+	}
+	return nil
+}
+
+// openStore returns a tracker holding the snapshot at path, an empty one if missing is set and there is no file yet
+func openStore(path string, missing bool) (*bidtracker.BidManagement, error) {
+	if path == "" {
+		return nil, fmt.Errorf("A store is required, set -store or %s", StoreEnv)
+	}
+
+	tracker := bidtracker.NewBidManagement()
+	snapshot, err := bidtracker.LoadSnapshot(path)
+	if missing && errors.Is(err, os.ErrNotExist) {
+		return tracker, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tracker.Restore(snapshot); err != nil {
+		return nil, errors.WithMessage(err, "Failed to restore the snapshot")
+	}
+	return tracker, nil
+}
+
+// openInput returns the file at path, or stdin for "-"
+func openInput(path string, env Env) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(env.Stdin), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to open the input")
+	}
+	return file, nil
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testItems = `{"itemuuid":"11111111-1111-1111-1111-111111111111","title":"Camera","categories":["photo"],"endtime":1000}
+{"itemuuid":"22222222-2222-2222-2222-222222222222","title":"Bike"}
+`
+	testBids = `{"itemuuid":"11111111-1111-1111-1111-111111111111","useruuid":"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa","timestamp":900,"amount":10}
+{"itemuuid":"11111111-1111-1111-1111-111111111111","useruuid":"bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb","timestamp":950,"amount":12.5}
+{"itemuuid":"22222222-2222-2222-2222-222222222222","useruuid":"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa","timestamp":1200,"amount":5}
+`
+)
+
+// run executes args against store, returning what was written to stdout
+func run(t *testing.T, store, stdin string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	err := Run(args, Env{
+		Getenv: func(name string) string {
+			if name == StoreEnv {
+				return store
+			}
+			return ""
+		},
+		Stdin:  strings.NewReader(stdin),
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	t.Log(stderr.String())
+	return stdout.String(), err
+}
+
+func TestImportAndReplay(t *testing.T) {
+	assert := assert.New(t)
+	store := filepath.Join(t.TempDir(), "state.json")
+
+	out, err := run(t, store, testItems, "items", "import", "-")
+	assert.Nil(err)
+	assert.Contains(out, "Imported 2 of 2 rows, 0 failed")
+
+	out, err = run(t, store, testBids, "replay", "-")
+	assert.Nil(err)
+	assert.Contains(out, "Applied 3 bids, 0 invalid")
+
+	snapshot, err := bidtracker.LoadSnapshot(store)
+	assert.Nil(err)
+	assert.Len(snapshot.Items, 2)
+	assert.Len(snapshot.Items[0].Bids, 2)
+
+	out, err = run(t, store, "", "items", "list", "-category", "photo")
+	assert.Nil(err)
+	assert.Contains(out, "Camera")
+	assert.Contains(out, "12.5")
+	assert.NotContains(out, "Bike")
+
+	out, err = run(t, store, "", "inspect")
+	assert.Nil(err)
+	assert.Contains(out, "2 (2 with bids)")
+	assert.Contains(out, "Bidders          2")
+}
+
+func TestReplayRejections(t *testing.T) {
+	assert := assert.New(t)
+	store := filepath.Join(t.TempDir(), "state.json")
+
+	_, err := run(t, store, testItems, "items", "import", "-")
+	assert.Nil(err)
+
+	// The auction of the camera ends at 1000
+	late := `{"itemuuid":"11111111-1111-1111-1111-111111111111","useruuid":"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa","timestamp":1100,"amount":20}`
+	bids := testBids + late + "\nnot json\n"
+
+	out, err := run(t, store, bids, "replay", "-")
+	assert.NotNil(err)
+	assert.Contains(out, "Applied 3 bids, 1 invalid, 1 rejected as auction_closed")
+	snapshot, err := bidtracker.LoadSnapshot(store)
+	assert.Nil(err)
+	assert.Empty(snapshot.Items[0].Bids, "the store is left untouched")
+
+	_, err = run(t, store, bids, "replay", "-dry-run", "-")
+	assert.Nil(err)
+
+	_, err = run(t, store, bids, "replay", "-partial", "-")
+	assert.Nil(err)
+	snapshot, err = bidtracker.LoadSnapshot(store)
+	assert.Nil(err)
+	assert.Len(snapshot.Items[0].Bids, 2)
+}
+
+func TestImportBids(t *testing.T) {
+	assert := assert.New(t)
+	store := filepath.Join(t.TempDir(), "state.json")
+
+	_, err := run(t, store, testItems, "items", "import", "-")
+	assert.Nil(err)
+
+	// Historical bids are checked against their own timestamp, the camera closes at 1000
+	bids := "itemuuid,useruuid,timestamp,amount\n" +
+		"11111111-1111-1111-1111-111111111111,aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa,900,10\n" +
+		"11111111-1111-1111-1111-111111111111,aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa,1100,20\n"
+
+	out, err := run(t, store, bids, "bids", "import", "-format", "csv", "-dry-run", "-")
+	assert.Nil(err)
+	assert.Contains(out, "Imported 1 of 2 rows, 1 failed")
+
+	_, err = run(t, store, bids, "bids", "import", "-format", "csv", "-")
+	assert.NotNil(err)
+	_, err = run(t, store, bids, "bids", "import", "-format", "csv", "-partial", "-")
+	assert.Nil(err)
+
+	snapshot, err := bidtracker.LoadSnapshot(store)
+	assert.Nil(err)
+	assert.Len(snapshot.Items[0].Bids, 1)
+}
+
+func TestExportBids(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	store := filepath.Join(dir, "state.json")
+
+	_, err := run(t, store, testItems, "items", "import", "-")
+	assert.Nil(err)
+	_, err = run(t, store, testBids, "replay", "-")
+	assert.Nil(err)
+
+	out, err := run(t, store, "", "bids", "export", "-format", "csv", "-item", "22222222-2222-2222-2222-222222222222")
+	assert.Nil(err)
+	assert.Equal("itemuuid,useruuid,timestamp,amount\n22222222-2222-2222-2222-222222222222,aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa,1200,5\n", out)
+
+	exported := filepath.Join(dir, "bids.jsonl")
+	_, err = run(t, store, "", "bids", "export", "-out", exported)
+	assert.Nil(err)
+	content, err := os.ReadFile(exported)
+	assert.Nil(err)
+	assert.Equal(testBids, string(content))
+
+	_, err = run(t, store, "", "bids", "export", "-item", uuid.Must(uuid.NewV4()).String())
+	assert.NotNil(err)
+	_, err = run(t, store, "", "bids", "export", "-format", "xml")
+	assert.NotNil(err)
+}
+
+func TestRunErrors(t *testing.T) {
+	assert := assert.New(t)
+	store := filepath.Join(t.TempDir(), "state.json")
+
+	_, err := run(t, store, "")
+	assert.ErrorIs(err, flag.ErrHelp)
+	_, err = run(t, store, "", "items", "delete")
+	assert.NotNil(err)
+	_, err = run(t, store, "", "inspect", "-h")
+	assert.ErrorIs(err, flag.ErrHelp)
+	_, err = run(t, store, "", "inspect")
+	assert.NotNil(err, "the store does not exist")
+	_, err = run(t, "", "", "inspect")
+	assert.NotNil(err, "no store")
+	_, err = run(t, store, `{"itemuuid":"11111111-1111-1111-1111-111111111111"}`, "items", "import", "-")
+	assert.NotNil(err, "items require a title")
+
+	assert.True(IsCommand("items"))
+	assert.False(IsCommand("serve"))
+}
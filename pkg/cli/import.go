@@ -0,0 +1,102 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+)
+
+// importer reads rows of format from r into tracker
+type importer func(tracker *bidtracker.BidManagement, r io.Reader, format string, dryRun bool) (bidtracker.ImportReport, error)
+
+// importItems adds the items of a csv or json lines file to the store, creating it if needed
+func importItems(args []string, env Env) error {
+	return runImport("items import", true, args, env, func(tracker *bidtracker.BidManagement, r io.Reader, format string, dryRun bool) (bidtracker.ImportReport, error) {
+		reader, err := bidtracker.NewItemReader(r, format)
+		if err != nil {
+			return bidtracker.ImportReport{}, err
+		}
+		return tracker.ImportItems(reader, dryRun)
+	})
+}
+
+// importBids records the historical bids of a csv or json lines file in the store
+func importBids(args []string, env Env) error {
+	return runImport("bids import", false, args, env, func(tracker *bidtracker.BidManagement, r io.Reader, format string, dryRun bool) (bidtracker.ImportReport, error) {
+		reader, err := bidtracker.NewBidReader(r, format)
+		if err != nil {
+			return bidtracker.ImportReport{}, err
+		}
+		return tracker.ImportBids(reader, dryRun)
+	})
+}
+
+// runImport prints the report of an import into the store. The store is only written if every row
+// was imported, unless -partial is set.
+func runImport(name string, create bool, args []string, env Env, run importer) error {
+	flags, store := newFlagSet(name, env)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: bid-tracker %s [-store path] [flags] <file|->\n", name)
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", bidtracker.FormatJSONL, "jsonl or csv")
+	dryRun := flags.Bool("dry-run", false, "only report what would be imported")
+	partial := flags.Bool("partial", false, "write the store even if some rows were not imported")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("Expected one file to import, got %d", flags.NArg())
+	}
+
+	tracker, err := openStore(*store, create)
+	if err != nil {
+		return err
+	}
+
+	input, err := openInput(flags.Arg(0), env)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	report, err := run(tracker, input, *format, *dryRun)
+	for _, importError := range report.Errors {
+		fmt.Fprintf(env.Stderr, "Line %d: %s\n", importError.Line, importError.Message)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "Imported %d of %d rows, %d failed\n", report.Imported, report.Rows, report.Failed)
+
+	if *dryRun {
+		return nil
+	}
+	if report.Failed > 0 && !*partial {
+		return fmt.Errorf("Some rows were not imported, the store is left untouched. Use -partial to write it anyway")
+	}
+	return tracker.SaveSnapshot(*store)
+}
@@ -0,0 +1,85 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+)
+
+// inspectTopItems is the number of items with the most bids printed by inspect
+const inspectTopItems = 5
+
+// inspect prints the totals of the store and the items with the most bids
+func inspect(args []string, env Env) error {
+	flags, store := newFlagSet("inspect", env)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	tracker, err := openStore(*store, false)
+	if err != nil {
+		return err
+	}
+	analytics, err := tracker.SystemAnalytics(bidtracker.AnalyticsQuery{})
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(env.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(table, "Store\t%s\n", *store)
+	fmt.Fprintf(table, "Items\t%d (%d with bids)\n", analytics.ItemCount, analytics.ItemsWithBids)
+	fmt.Fprintf(table, "Bids\t%d\n", analytics.BidCount)
+	fmt.Fprintf(table, "Bidders\t%d\n", analytics.UniqueBidders)
+	if analytics.BidCount > 0 {
+		fmt.Fprintf(table, "First bid\t%s\n", time.Unix(analytics.FirstBid, 0).UTC().Format(time.RFC3339))
+		fmt.Fprintf(table, "Last bid\t%s\n", time.Unix(analytics.LastBid, 0).UTC().Format(time.RFC3339))
+		fmt.Fprintf(table, "Bids per minute\t%.2f\n", analytics.BidsPerMinute)
+	}
+	fmt.Fprintf(table, "Leading volume\t%.2f\n", analytics.LeadingVolume)
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	items := tracker.Snapshot().Items
+	sort.SliceStable(items, func(i, j int) bool { return len(items[i].Bids) > len(items[j].Bids) })
+	if len(items) > inspectTopItems {
+		items = items[:inspectTopItems]
+	}
+	if len(items) == 0 || len(items[0].Bids) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(env.Stdout, "\nMost bids:")
+	table = tabwriter.NewWriter(env.Stdout, 0, 4, 2, ' ', 0)
+	for _, item := range items {
+		if len(item.Bids) == 0 {
+			break
+		}
+		fmt.Fprintf(table, "  %s\t%s\t%d\n", item.Item.UUID, item.Item.Title, len(item.Bids))
+	}
+	return table.Flush()
+}
@@ -0,0 +1,64 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// listItems prints a table of the items of the store ordered by title
+func listItems(args []string, env Env) error {
+	flags, store := newFlagSet("items list", env)
+	category := flags.String("category", "", "only list the items of this category")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	tracker, err := openStore(*store, false)
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(env.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "ITEMUUID\tTITLE\tBIDS\tLEADING\tENDS")
+	for _, item := range tracker.ListItems(uuid.Nil, *category) {
+		bids, err := tracker.GetBids(item.UUID)
+		if err != nil {
+			return err
+		}
+		leading := "-"
+		if winning, err := tracker.CurrentWinningBid(item.UUID); err == nil {
+			leading = strconv.FormatFloat(winning.Amount, 'f', -1, 64)
+		}
+		ends := "-"
+		if item.EndTime != 0 {
+			ends = time.Unix(item.EndTime, 0).UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\n", item.UUID, item.Title, len(bids), leading, ends)
+	}
+	return table.Flush()
+}
@@ -0,0 +1,128 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/pkg/errors"
+)
+
+// replay inserts the bids of a log into the store as if they were placed at their timestamp,
+// reporting the ones that are malformed or rejected. The store is only written if every bid was applied,
+// unless -partial is set.
+func replay(args []string, env Env) error {
+	flags, store := newFlagSet("replay", env)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: bid-tracker replay [-store path] [flags] <bids.jsonl|->")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", bidtracker.FormatJSONL, "jsonl or csv")
+	out := flags.String("out", "", "file to write the resulting store to, the store itself by default")
+	dryRun := flags.Bool("dry-run", false, "only report what would be applied")
+	partial := flags.Bool("partial", false, "write the store even if some bids were not applied")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("Expected one log of bids, got %d", flags.NArg())
+	}
+
+	tracker, err := openStore(*store, false)
+	if err != nil {
+		return err
+	}
+
+	input, err := openInput(flags.Arg(0), env)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	reader, err := bidtracker.NewBidReader(input, *format)
+	if err != nil {
+		return err
+	}
+
+	// Auctions are closed according to the time of the bid being replayed, not the current time
+	var at int64
+	tracker.SetClock(func() time.Time { return time.Unix(at, 0) })
+
+	applied, invalid := 0, 0
+	rejected := map[bidtracker.RejectReason]int{}
+	for {
+		bid, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *bidtracker.RowError
+		if errors.As(err, &rowErr) {
+			invalid++
+			fmt.Fprintln(env.Stderr, err)
+			continue
+		}
+		if err != nil {
+			return errors.WithMessage(err, "Failed to read the bids")
+		}
+
+		at = bid.Timestamp
+		if err := tracker.InsertBid(&bid); err != nil {
+			var rejection *bidtracker.BidRejectedError
+			if !errors.As(err, &rejection) {
+				return err
+			}
+			rejected[rejection.Reason]++
+			fmt.Fprintf(env.Stderr, "Line %d rejected, %s: %s\n", reader.Line(), rejection.Reason, err)
+			continue
+		}
+		applied++
+	}
+
+	fmt.Fprintf(env.Stdout, "Applied %d bids, %d invalid", applied, invalid)
+	reasons := make([]string, 0, len(rejected))
+	for reason := range rejected {
+		reasons = append(reasons, string(reason))
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(env.Stdout, ", %d rejected as %s", rejected[bidtracker.RejectReason(reason)], reason)
+	}
+	fmt.Fprintln(env.Stdout)
+
+	failed := invalid + len(reasons)
+	if *dryRun {
+		return nil
+	}
+	if failed > 0 && !*partial {
+		return fmt.Errorf("Some bids were not applied, the store is left untouched. Use -partial to write it anyway")
+	}
+
+	if *out == "" {
+		*out = *store
+	}
+	return tracker.SaveSnapshot(*out)
+}
@@ -0,0 +1,116 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/ansrivas/bid-tracker/pkg/api"
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofrs/uuid"
+)
+
+// ImportItems sends items in bulk, as bidtracker.FormatJSONL or bidtracker.FormatCSV, and returns the report
+// of the import. A dry run only validates them. The body is streamed and never retried.
+func (c *Client) ImportItems(ctx context.Context, body io.Reader, format string, dryRun bool) (bidtracker.ImportReport, error) {
+	return c.importRows(ctx, api.URLImportItems, body, format, dryRun)
+}
+
+// ImportBids sends historical bids in bulk, like ImportItems
+func (c *Client) ImportBids(ctx context.Context, body io.Reader, format string, dryRun bool) (bidtracker.ImportReport, error) {
+	return c.importRows(ctx, api.URLImportBids, body, format, dryRun)
+}
+
+func (c *Client) importRows(ctx context.Context, path string, body io.Reader, format string, dryRun bool) (bidtracker.ImportReport, error) {
+	query := url.Values{}
+	if format != "" {
+		query.Set("format", format)
+	}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+	var report bidtracker.ImportReport
+	_, err := c.do(ctx, request{method: http.MethodPost, path: path, query: query, body: body, contentType: "text/plain"}, &report)
+	return report, err
+}
+
+// ExportBids streams every bid, or only the bids of itemuuid if it is set, as bidtracker.FormatJSONL
+// or bidtracker.FormatCSV. The returned body must be closed, bidtracker.NewBidReader reads it.
+func (c *Client) ExportBids(ctx context.Context, format string, itemuuid uuid.UUID) (io.ReadCloser, error) {
+	query := url.Values{}
+	if format != "" {
+		query.Set("format", format)
+	}
+	if itemuuid != uuid.Nil {
+		query.Set("itemuuid", itemuuid.String())
+	}
+
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: api.URLExportBids, query: query})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		content, _ := io.ReadAll(resp.Body)
+		return nil, responseError(resp, content)
+	}
+	return resp.Body, nil
+}
+
+// ShillReport returns the users flagged for shill bidding or collusion
+func (c *Client) ShillReport(ctx context.Context) ([]bidtracker.Suspicion, error) {
+	var suspicions []bidtracker.Suspicion
+	_, err := c.do(ctx, request{method: http.MethodGet, path: api.URLAdminShillReport}, &suspicions)
+	return suspicions, err
+}
+
+// APIKeys lists the api keys known to the server, without their secrets
+func (c *Client) APIKeys(ctx context.Context) ([]apikey.Key, error) {
+	var keys []apikey.Key
+	_, err := c.do(ctx, request{method: http.MethodGet, path: api.URLAdminAPIKeys}, &keys)
+	return keys, err
+}
+
+// CreateAPIKey issues a new api key, its token is only ever returned here
+func (c *Client) CreateAPIKey(ctx context.Context, spec api.APIKeyCreateRequest) (api.APIKeyIssued, error) {
+	var issued api.APIKeyIssued
+	_, err := c.do(ctx, request{method: http.MethodPost, path: api.URLAdminAPIKeys, body: spec}, &issued)
+	return issued, err
+}
+
+// RotateAPIKey issues a new secret for an api key
+func (c *Client) RotateAPIKey(ctx context.Context, keyID string, rotation api.APIKeyRotateRequest) (api.APIKeyIssued, error) {
+	var issued api.APIKeyIssued
+	_, err := c.do(ctx, request{method: http.MethodPost, path: routePath(api.URLAdminAPIKeyRotate, keyID), body: rotation}, &issued)
+	return issued, err
+}
+
+// RevokeAPIKey revokes an api key and returns it
+func (c *Client) RevokeAPIKey(ctx context.Context, keyID string) (apikey.Key, error) {
+	var key apikey.Key
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: routePath(api.URLAdminAPIKey, keyID)}, &key)
+	return key, err
+}
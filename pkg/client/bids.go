@@ -0,0 +1,137 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ansrivas/bid-tracker/pkg/api"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofrs/uuid"
+)
+
+// PlaceBid places a bid and returns it as recorded
+func (c *Client) PlaceBid(ctx context.Context, bid bidtracker.Bid) (bidtracker.Bid, error) {
+	var placed bidtracker.Bid
+	_, err := c.do(ctx, request{method: http.MethodPost, path: api.URLBidItem, body: bid}, &placed)
+	return placed, err
+}
+
+// PlaceBids places up to api.MaxBidBatchSize bids at once. An atomic batch is rejected as a whole if any
+// bid is, the result is returned along with an error matching ErrRejected then.
+func (c *Client) PlaceBids(ctx context.Context, bids []bidtracker.Bid, atomic bool) (bidtracker.BidBatchResult, error) {
+	var result bidtracker.BidBatchResult
+	query := url.Values{}
+	if atomic {
+		query.Set("atomic", "true")
+	}
+	_, err := c.do(ctx, request{method: http.MethodPost, path: api.URLBidBatch, query: query, body: bids}, &result)
+	return result, err
+}
+
+// ListBids returns a page of the bids on an item, the NextCursor of the page is the Cursor of the next query
+func (c *Client) ListBids(ctx context.Context, itemuuid uuid.UUID, query bidtracker.BidQuery) (bidtracker.BidPage, error) {
+	return c.bidPage(ctx, routePath(api.URLBidGetAll, itemuuid.String()), query)
+}
+
+// WinningBid returns the current winning bid on an item
+func (c *Client) WinningBid(ctx context.Context, itemuuid uuid.UUID) (bidtracker.Bid, error) {
+	var bid bidtracker.Bid
+	_, err := c.do(ctx, request{method: http.MethodGet, path: routePath(api.URLBidGetWinning, itemuuid.String())}, &bid)
+	return bid, err
+}
+
+// Leaderboard returns the best bid of the limit best users on an item, the server default if limit is 0
+func (c *Client) Leaderboard(ctx context.Context, itemuuid uuid.UUID, limit int) ([]bidtracker.LeaderboardEntry, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var entries []bidtracker.LeaderboardEntry
+	_, err := c.do(ctx, request{method: http.MethodGet, path: routePath(api.URLBidGetLeaderboard, itemuuid.String()), query: query}, &entries)
+	return entries, err
+}
+
+// UserRank returns the rank of a user on an item
+func (c *Client) UserRank(ctx context.Context, itemuuid, useruuid uuid.UUID) (bidtracker.LeaderboardEntry, error) {
+	var entry bidtracker.LeaderboardEntry
+	_, err := c.do(ctx, request{method: http.MethodGet, path: routePath(api.URLBidGetRank, itemuuid.String(), useruuid.String())}, &entry)
+	return entry, err
+}
+
+// UserBids returns a page of the bids of a user
+func (c *Client) UserBids(ctx context.Context, useruuid uuid.UUID, query bidtracker.BidQuery) (bidtracker.BidPage, error) {
+	return c.bidPage(ctx, routePath(api.URLUserGetAllBids, useruuid.String()), query)
+}
+
+// Portfolio returns the standing of a user on every item they bid on
+func (c *Client) Portfolio(ctx context.Context, useruuid uuid.UUID) ([]bidtracker.PortfolioEntry, error) {
+	var portfolio []bidtracker.PortfolioEntry
+	_, err := c.do(ctx, request{method: http.MethodGet, path: routePath(api.URLUserGetPortfolio, useruuid.String())}, &portfolio)
+	return portfolio, err
+}
+
+func (c *Client) bidPage(ctx context.Context, path string, query bidtracker.BidQuery) (bidtracker.BidPage, error) {
+	page := bidtracker.BidPage{}
+	env, err := c.do(ctx, request{method: http.MethodGet, path: path, query: bidQueryValues(query)}, &page.Bids)
+	page.NextCursor = env.NextCursor
+	return page, err
+}
+
+// bidQueryValues encodes the query the way the bid listings of the api read it
+func bidQueryValues(query bidtracker.BidQuery) url.Values {
+	values := url.Values{}
+	if query.Limit > 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+	if query.Cursor != "" {
+		values.Set("cursor", query.Cursor)
+	}
+	if query.SortBy != "" {
+		values.Set("sort", string(query.SortBy))
+	}
+	if query.Descending {
+		values.Set("order", "desc")
+	}
+	if query.MinAmount != 0 {
+		values.Set("min_amount", strconv.FormatFloat(query.MinAmount, 'f', -1, 64))
+	}
+	if query.MaxAmount != 0 {
+		values.Set("max_amount", strconv.FormatFloat(query.MaxAmount, 'f', -1, 64))
+	}
+	if query.From != 0 {
+		values.Set("from", strconv.FormatInt(query.From, 10))
+	}
+	if query.To != 0 {
+		values.Set("to", strconv.FormatInt(query.To, 10))
+	}
+	if query.UserUUID != uuid.Nil {
+		values.Set("useruuid", query.UserUUID.String())
+	}
+	if query.ItemUUID != uuid.Nil {
+		values.Set("itemuuid", query.ItemUUID.String())
+	}
+	return values
+}
@@ -0,0 +1,322 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package client is the Go client of the bid tracker REST api
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/api"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultAPIVersion is the api version of the server when none is configured
+	DefaultAPIVersion = "/api/v1"
+
+	// DefaultRetries is the number of times a failed request is retried when WithRetries is not provided
+	DefaultRetries = 2
+
+	// DefaultBackoff is the wait before the first retry, it doubles on every retry
+	DefaultBackoff = 100 * time.Millisecond
+)
+
+// Doer sends http requests, *http.Client is one
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc turns a function into a Doer, e.g. the Test method of a fiber app
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Option configures a Client
+type Option struct {
+	setup func(c *Client)
+}
+
+// WithAPIKey sends token as the api key of every request
+func WithAPIKey(token string) Option {
+	return Option{func(c *Client) {
+		c.apiKey = token
+	}}
+}
+
+// WithAPIVersion sets the prefix of the api routes, DefaultAPIVersion if this option is not provided
+func WithAPIVersion(apiVersion string) Option {
+	return Option{func(c *Client) {
+		c.apiVersion = apiVersion
+	}}
+}
+
+// WithProxyPrefix sets the prefix the server is reached under behind a reverse proxy, before the api version
+func WithProxyPrefix(proxyPrefix string) Option {
+	return Option{func(c *Client) {
+		c.proxyPrefix = proxyPrefix
+	}}
+}
+
+// WithHTTPClient sends the requests with doer instead of http.DefaultClient
+func WithHTTPClient(doer Doer) Option {
+	return Option{func(c *Client) {
+		c.doer = doer
+	}}
+}
+
+// WithRetries sets how many times a request failing with a network error, 429, 502, 503 or 504 is retried,
+// waiting backoff before the first retry and twice as long before every next one, or as long as the server asks.
+// POST requests are retried with the same idempotency key so that they run at most once.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return Option{func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}}
+}
+
+// Client calls the REST api of a bid tracker server, it is safe for concurrent use
+type Client struct {
+	baseURL     *url.URL
+	apiVersion  string
+	proxyPrefix string
+	apiKey      string
+	doer        Doer
+	retries     int
+	backoff     time.Duration
+}
+
+// New returns a client of the server at baseURL, e.g. "http://localhost:3000"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Invalid base url")
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.Errorf("Invalid base url %q, expected scheme://host[:port]", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		apiVersion: DefaultAPIVersion,
+		doer:       http.DefaultClient,
+		retries:    DefaultRetries,
+		backoff:    DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt.setup(c)
+	}
+	return c, nil
+}
+
+// idempotencyKeyContext is the context key of the idempotency key set by WithIdempotencyKey
+type idempotencyKeyContext struct{}
+
+// WithIdempotencyKey returns a context whose POST requests are sent with key instead of a random one,
+// e.g. to retry a bid after a restart of the caller without placing it twice. Imports get no random key.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContext{}, key)
+}
+
+// request is a call to a route of the server
+type request struct {
+	method string
+	// path is relative to the api version, unless unversioned is set
+	path        string
+	unversioned bool
+	query       url.Values
+	// body is either json encoded, or sent as is if it is an io.Reader. Readers are not retried.
+	body        interface{}
+	contentType string
+	accept      string
+}
+
+// routePath fills the parameters of a route pattern, e.g. api.URLBidGetAll, in order
+func routePath(pattern string, params ...string) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") && len(params) > 0 {
+			segments[i] = url.PathEscape(params[0])
+			params = params[1:]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// url returns the absolute url of the request
+func (c *Client) url(r request) string {
+	u := *c.baseURL
+	prefix := c.proxyPrefix
+	if !r.unversioned {
+		prefix = path.Join(prefix, c.apiVersion)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path.Join("/", prefix, r.path)
+	u.RawQuery = r.query.Encode()
+	return u.String()
+}
+
+// send runs the request, retrying it as configured, and returns the last response. Its body must be closed.
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	var body []byte
+	var stream io.Reader
+	switch b := r.body.(type) {
+	case nil:
+	case io.Reader:
+		stream = b
+	default:
+		var err error
+		if body, err = json.Marshal(b); err != nil {
+			return nil, errors.WithMessage(err, "Failed to encode the request")
+		}
+		if r.contentType == "" {
+			r.contentType = "application/json"
+		}
+	}
+
+	idempotencyKey := ""
+	if r.method == http.MethodPost {
+		idempotencyKey, _ = ctx.Value(idempotencyKeyContext{}).(string)
+		// Streams are not retried, and the server does not keep the responses of the routes streaming their body
+		if idempotencyKey == "" && stream == nil {
+			idempotencyKey = uuid.Must(uuid.NewV4()).String()
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		var reqBody io.Reader = stream
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, r.method, c.url(r), reqBody)
+		if err != nil {
+			return nil, errors.WithMessage(err, "Failed to create the request")
+		}
+		if r.contentType != "" {
+			req.Header.Set("Content-Type", r.contentType)
+		}
+		if r.accept != "" {
+			req.Header.Set("Accept", r.accept)
+		}
+		if c.apiKey != "" {
+			req.Header.Set(api.APIKeyHeader, c.apiKey)
+		}
+		if idempotencyKey != "" {
+			req.Header.Set(api.IdempotencyKeyHeader, idempotencyKey)
+		}
+
+		resp, err := c.doer.Do(req)
+		if attempt >= c.retries || stream != nil || ctx.Err() != nil || (err == nil && !retryable(resp.StatusCode)) {
+			if err != nil {
+				return nil, errors.WithMessagef(err, "Failed to send %s %s", r.method, r.path)
+			}
+			return resp, nil
+		}
+
+		wait := c.backoff * time.Duration(math.Pow(2, float64(attempt)))
+		if err == nil {
+			if retryAfter := retryAfter(resp); retryAfter > wait {
+				wait = retryAfter
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether a request failing with the status may succeed later
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter reads the seconds of the Retry-After header, 0 without one
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// envelope is the Response every route of the api answers with
+type envelope struct {
+	Status     int
+	Message    string
+	Data       json.RawMessage
+	RequestID  string
+	NextCursor string
+}
+
+// do runs the request and decodes the Data of the response into out, if not nil. The Data of failed responses
+// is decoded as well, e.g. the results of a rejected batch. It returns the envelope of the response.
+func (c *Client) do(ctx context.Context, r request, out interface{}) (envelope, error) {
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return envelope{}, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return envelope{}, errors.WithMessage(err, "Failed to read the response")
+	}
+
+	var env envelope
+	if err := json.Unmarshal(content, &env); err != nil {
+		if resp.StatusCode >= http.StatusBadRequest {
+			return env, responseError(resp, content)
+		}
+		return env, errors.WithMessage(err, "Failed to decode the response")
+	}
+	if out != nil && len(env.Data) > 0 {
+		if err := json.Unmarshal(env.Data, out); err != nil && resp.StatusCode < http.StatusBadRequest {
+			return env, errors.WithMessage(err, "Failed to decode the data of the response")
+		}
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return env, responseError(resp, content)
+	}
+	return env, nil
+}
@@ -0,0 +1,342 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ansrivas/bid-tracker/pkg/api"
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	itemUUID  = uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))
+	aliceUUID = uuid.Must(uuid.FromString("ae8f7716-867b-4479-b455-c5769e7475ba"))
+	bobUUID   = uuid.Must(uuid.FromString("f475091b-a8f1-4679-83bd-483b616e5260"))
+)
+
+// newTestServer serves the api under a proxy prefix, with api keys enabled, and returns the admin token
+func newTestServer(t *testing.T, options ...api.RegisterRoutesOption) (*api.API, string) {
+	store := apikey.NewStore()
+	adminToken, _, err := store.Create(apikey.Spec{Name: "admin", Role: string(api.RoleAdmin), Scopes: []apikey.Scope{apikey.ScopeAdmin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := api.NewAPIWithSettings(bidtracker.NewBidManagement(itemUUID), fiber.New(fiber.Config{DisableStartupMessage: true}))
+	options = append([]api.RegisterRoutesOption{
+		api.RegisterWithAPIVersion("/api/v1"),
+		api.RegisterWithAPIProxyPrefix("/proxy"),
+		api.RegisterWithAPIKeys(store),
+		api.RegisterWithHealth(api.NewHealth()),
+		api.RegisterWithBuildInfo(api.NewBuildInfo("1.2.3", "now")),
+	}, options...)
+	err = api.RegisterRoutes(server, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, adminToken
+}
+
+// appDoer sends the requests to the fiber app of server without a network
+func appDoer(server *api.API) DoerFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		return server.FiberApp().Test(req, -1)
+	}
+}
+
+func newTestClient(t *testing.T, server *api.API, opts ...Option) *Client {
+	opts = append([]Option{WithHTTPClient(appDoer(server)), WithProxyPrefix("/proxy")}, opts...)
+	c, err := New("http://bidtracker.test", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	server, adminToken := newTestServer(t)
+	c := newTestClient(t, server, WithAPIKey(adminToken))
+
+	// WHEN bids are placed
+	for i, bid := range []bidtracker.Bid{
+		{ItemUUID: itemUUID, UserUUID: aliceUUID, Timestamp: 1351807721, Amount: 30},
+		{ItemUUID: itemUUID, UserUUID: bobUUID, Timestamp: 1351807722, Amount: 31},
+		{ItemUUID: itemUUID, UserUUID: aliceUUID, Timestamp: 1351807723, Amount: 32},
+	} {
+		placed, err := c.PlaceBid(ctx, bid)
+		assert.Nil(err, "bid %d", i)
+		assert.Equal(bid, placed)
+	}
+
+	// THEN they are read back through every listing
+	winning, err := c.WinningBid(ctx, itemUUID)
+	assert.Nil(err)
+	assert.Equal(32.0, winning.Amount)
+
+	page, err := c.ListBids(ctx, itemUUID, bidtracker.BidQuery{Limit: 2})
+	assert.Nil(err)
+	assert.Len(page.Bids, 2)
+	assert.NotEmpty(page.NextCursor)
+	page, err = c.ListBids(ctx, itemUUID, bidtracker.BidQuery{Limit: 2, Cursor: page.NextCursor})
+	assert.Nil(err)
+	assert.Len(page.Bids, 1)
+	assert.Empty(page.NextCursor)
+
+	page, err = c.UserBids(ctx, bobUUID, bidtracker.BidQuery{})
+	assert.Nil(err)
+	assert.Equal([]bidtracker.Bid{{ItemUUID: itemUUID, UserUUID: bobUUID, Timestamp: 1351807722, Amount: 31}}, page.Bids)
+
+	leaderboard, err := c.Leaderboard(ctx, itemUUID, 1)
+	assert.Nil(err)
+	if assert.Len(leaderboard, 1) {
+		assert.Equal(aliceUUID, leaderboard[0].UserUUID)
+	}
+	rank, err := c.UserRank(ctx, itemUUID, bobUUID)
+	assert.Nil(err)
+	assert.Equal(2, rank.Rank)
+
+	portfolio, err := c.Portfolio(ctx, bobUUID)
+	assert.Nil(err)
+	if assert.Len(portfolio, 1) {
+		assert.Equal(bidtracker.StatusOutbid, portfolio[0].Status)
+	}
+
+	// THEN an atomic batch with a bad bid is rejected along with its results
+	result, err := c.PlaceBids(ctx, []bidtracker.Bid{
+		{ItemUUID: itemUUID, UserUUID: bobUUID, Timestamp: 1351807724, Amount: 40},
+		{ItemUUID: bobUUID, UserUUID: bobUUID, Timestamp: 1351807724, Amount: 41},
+	}, true)
+	assert.True(errors.Is(err, ErrRejected))
+	assert.Equal(2, result.Rejected)
+
+	// THEN items are managed
+	created, err := c.CreateItem(ctx, bidtracker.Item{UUID: bobUUID, Title: "Vintage camera", Categories: []string{"photography"}})
+	assert.Nil(err)
+	assert.Equal("Vintage camera", created.Title)
+	created.Description = "Works"
+	updated, err := c.UpdateItem(ctx, created)
+	assert.Nil(err)
+	assert.Equal("Works", updated.Description)
+	items, err := c.ListItems(ctx, uuid.Nil, "photography")
+	assert.Nil(err)
+	assert.Len(items, 1)
+	search, err := c.SearchItems(ctx, bidtracker.ItemSearch{Text: "camera"})
+	assert.Nil(err)
+	assert.Equal(1, search.Total)
+
+	analytics, err := c.ItemAnalytics(ctx, itemUUID, bidtracker.AnalyticsQuery{HistogramBuckets: 2})
+	assert.Nil(err)
+	assert.Equal(3, analytics.BidCount)
+	_, err = c.SystemAnalytics(ctx, bidtracker.AnalyticsQuery{})
+	assert.Nil(err)
+
+	// THEN bids are imported and exported
+	report, err := c.ImportBids(ctx, strings.NewReader(`{"itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","useruuid":"f475091b-a8f1-4679-83bd-483b616e5260","timestamp":1351807725,"amount":50}`), bidtracker.FormatJSONL, true)
+	assert.Nil(err)
+	assert.Equal(1, report.Rows)
+	assert.True(report.DryRun)
+	export, err := c.ExportBids(ctx, bidtracker.FormatCSV, itemUUID)
+	if assert.Nil(err) {
+		content, _ := io.ReadAll(export)
+		export.Close()
+		assert.Equal(4, strings.Count(string(content), "\n"), "a header and a line per bid")
+	}
+
+	// THEN api keys are managed
+	issued, err := c.CreateAPIKey(ctx, api.APIKeyCreateRequest{Name: "reader", Scopes: []string{string(apikey.ScopeBidsRead)}})
+	assert.Nil(err)
+	assert.NotEmpty(issued.Token)
+	keys, err := c.APIKeys(ctx)
+	assert.Nil(err)
+	assert.Len(keys, 2)
+	revoked, err := c.RevokeAPIKey(ctx, issued.Key.ID)
+	assert.Nil(err)
+	assert.False(revoked.RevokedAt.IsZero())
+	_, err = c.ShillReport(ctx)
+	assert.Nil(err)
+
+	// THEN the health routes are found next to the api version
+	assert.Nil(c.Healthz(ctx))
+	readiness, err := c.Readyz(ctx)
+	assert.True(errors.Is(err, ErrUnavailable), "the state was never marked recovered")
+	assert.False(readiness.Ready)
+	info, err := c.Version(ctx)
+	assert.Nil(err)
+	assert.Equal("1.2.3", info.Version)
+
+	// THEN GraphQL is run as well
+	var data struct {
+		Item struct {
+			WinningBid bidtracker.Bid `json:"winningBid"`
+		} `json:"item"`
+	}
+	assert.Nil(c.GraphQL(ctx, `query($item: ID!) { item(itemUuid: $item) { winningBid { amount } } }`, map[string]interface{}{"item": itemUUID.String()}, &data))
+	assert.Equal(32.0, data.Item.WinningBid.Amount)
+	var graphQLErr *GraphQLError
+	assert.True(errors.As(c.GraphQL(ctx, `{ item(itemUuid: "nope") { title } }`, nil, nil), &graphQLErr))
+}
+
+func TestClientErrors(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	server, adminToken := newTestServer(t)
+
+	// WHEN no api key is sent
+	_, err := newTestClient(t, server).WinningBid(ctx, itemUUID)
+	var clientErr *Error
+	if assert.True(errors.As(err, &clientErr)) {
+		assert.Equal(fiber.StatusUnauthorized, clientErr.StatusCode)
+		assert.Equal("Missing api key", clientErr.Message)
+		assert.NotEmpty(clientErr.RequestID)
+	}
+	assert.True(errors.Is(err, ErrUnauthorized))
+
+	// WHEN the key is not permitted the route
+	c := newTestClient(t, server, WithAPIKey(adminToken))
+	issued, err := c.CreateAPIKey(ctx, api.APIKeyCreateRequest{Name: "reader", Scopes: []string{string(apikey.ScopeBidsRead)}})
+	assert.Nil(err)
+	_, err = newTestClient(t, server, WithAPIKey(issued.Token)).PlaceBid(ctx, bidtracker.Bid{ItemUUID: itemUUID, UserUUID: aliceUUID, Amount: 1})
+	assert.True(errors.Is(err, ErrForbidden))
+
+	// WHEN the item is unknown
+	_, err = c.GetItem(ctx, bobUUID)
+	assert.True(errors.Is(err, ErrNotFound))
+	assert.False(errors.Is(err, ErrServer))
+
+	// WHEN the route does not exist, e.g. behind another proxy prefix
+	_, err = newTestClient(t, server, WithAPIKey(adminToken), WithProxyPrefix("/other")).GetItem(ctx, itemUUID)
+	assert.True(errors.Is(err, ErrNotFound))
+
+	_, err = New("localhost:3000")
+	assert.NotNil(err)
+}
+
+func TestClientProblemDetails(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	server, adminToken := newTestServer(t, api.RegisterWithProblemDetails())
+	c := newTestClient(t, server, WithAPIKey(adminToken))
+
+	// WHEN the item is unknown, the code of the problem is kept
+	_, err := c.GetItem(ctx, bobUUID)
+	var clientErr *Error
+	if assert.True(errors.As(err, &clientErr)) {
+		assert.Equal("item_not_found", clientErr.Code)
+		assert.Contains(clientErr.Message, "Requested item is not available for bidding")
+		assert.NotEmpty(clientErr.RequestID)
+	}
+	assert.True(errors.Is(err, ErrNotFound))
+
+	// WHEN a query is invalid, the field is reported
+	_, err = c.ListBids(ctx, itemUUID, bidtracker.BidQuery{Limit: bidtracker.MaxBidPageLimit + 1})
+	if assert.True(errors.As(err, &clientErr)) {
+		assert.Equal("invalid_argument", clientErr.Code)
+		if assert.Len(clientErr.Fields, 1) {
+			assert.Equal("limit", clientErr.Fields[0].Field)
+		}
+	}
+	assert.True(errors.Is(err, ErrBadRequest))
+
+	// WHEN an atomic batch is rejected, the results are still returned
+	result, err := c.PlaceBids(ctx, []bidtracker.Bid{
+		{ItemUUID: itemUUID, UserUUID: aliceUUID, Amount: 10},
+		{ItemUUID: bobUUID, UserUUID: aliceUUID, Amount: 20},
+	}, true)
+	assert.True(errors.Is(err, ErrRejected))
+	assert.Equal(2, result.Rejected)
+}
+
+func TestClientRetries(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	server, adminToken := newTestServer(t)
+
+	// WHEN the first attempt reaches the server but its response is lost,
+	// and the second one is answered by an overloaded proxy
+	var mu sync.Mutex
+	var keys []string
+	attempts := 0
+	doer := DoerFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		keys = append(keys, req.Header.Get(api.IdempotencyKeyHeader))
+		switch attempts {
+		case 1:
+			if _, err := server.FiberApp().Test(req, -1); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("connection reset by peer")
+		case 2:
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"0"}}, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+		return server.FiberApp().Test(req, -1)
+	})
+	c, err := New("http://bidtracker.test", WithHTTPClient(doer), WithProxyPrefix("/proxy"), WithAPIKey(adminToken), WithRetries(3, 0))
+	assert.Nil(err)
+
+	bid := bidtracker.Bid{ItemUUID: itemUUID, UserUUID: aliceUUID, Timestamp: 1351807721, Amount: 30}
+	placed, err := c.PlaceBid(ctx, bid)
+
+	// THEN the bid is placed once, every attempt carrying the same idempotency key
+	assert.Nil(err)
+	assert.Equal(bid, placed)
+	assert.Equal(3, attempts)
+	assert.NotEmpty(keys[0])
+	assert.Equal([]string{keys[0], keys[0], keys[0]}, keys)
+	page, _ := c.ListBids(ctx, itemUUID, bidtracker.BidQuery{})
+	assert.Len(page.Bids, 1)
+
+	// THEN a key of the caller is used instead of a random one
+	attempts = 3
+	keys = nil
+	bid.Amount = 40
+	_, err = c.PlaceBid(WithIdempotencyKey(ctx, "bid-42"), bid)
+	assert.Nil(err)
+	assert.Equal([]string{"bid-42"}, keys)
+
+	// THEN streamed imports get no random key
+	attempts = 3
+	keys = nil
+	_, err = c.ImportBids(ctx, strings.NewReader(`{"itemuuid":"b2f9ee6d-79fe-4b14-9c19-35a69a89219a","useruuid":"f475091b-a8f1-4679-83bd-483b616e5260","timestamp":1351807725,"amount":50}`), bidtracker.FormatJSONL, true)
+	assert.Nil(err)
+	assert.Equal([]string{""}, keys)
+
+	// THEN failures which would fail again are not retried
+	attempts = 3
+	keys = nil
+	_, err = c.PlaceBid(ctx, bidtracker.Bid{ItemUUID: bobUUID, UserUUID: aliceUUID, Amount: 1})
+	assert.True(errors.Is(err, ErrNotFound))
+	assert.Len(keys, 1)
+}
@@ -0,0 +1,127 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/api"
+)
+
+var (
+	// ErrBadRequest matches errors of requests the server could not parse or validate (400)
+	ErrBadRequest = errors.New("Bad request")
+
+	// ErrUnauthorized matches errors of requests without a valid api key (401)
+	ErrUnauthorized = errors.New("Unauthorized")
+
+	// ErrForbidden matches errors of requests whose api key is not permitted the route (403)
+	ErrForbidden = errors.New("Forbidden")
+
+	// ErrNotFound matches errors of requests for unknown items, users, api keys or routes (404)
+	ErrNotFound = errors.New("Not found")
+
+	// ErrConflict matches errors of requests conflicting with the state of the server (409)
+	ErrConflict = errors.New("Conflict")
+
+	// ErrRejected matches errors of valid requests the tracker refused, e.g. a bid on a closed auction (422)
+	ErrRejected = errors.New("Rejected")
+
+	// ErrRateLimited matches errors of requests over the rate limit of the client (429)
+	ErrRateLimited = errors.New("Rate limited")
+
+	// ErrUnavailable matches errors of requests to a server which is not ready or draining (503)
+	ErrUnavailable = errors.New("Unavailable")
+
+	// ErrServer matches every other failure of the server (5xx)
+	ErrServer = errors.New("Server error")
+)
+
+// statusErrors maps the status codes to the error they match
+var statusErrors = map[int]error{
+	http.StatusBadRequest:          ErrBadRequest,
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusForbidden:           ErrForbidden,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusConflict:            ErrConflict,
+	http.StatusUnprocessableEntity: ErrRejected,
+	http.StatusTooManyRequests:     ErrRateLimited,
+	http.StatusServiceUnavailable:  ErrUnavailable,
+}
+
+// Error is returned for every response with a status of 400 or more. It matches the Err* variable
+// of its status code with errors.Is.
+type Error struct {
+	StatusCode int
+	// Message is the Message of the response
+	Message string
+	// RequestID is the X-Request-ID of the request, to find it in the logs of the server
+	RequestID string
+	// RetryAfter is how long the server asked to wait, e.g. when rate limited
+	RetryAfter time.Duration
+	// Code names the problem, e.g. item_not_found, if the server sent problem details
+	Code string
+	// Fields lists the invalid fields of the request, if the server sent problem details
+	Fields []api.FieldError
+}
+
+// responseError builds the Error of a failed response from its content, the Message of the Response,
+// the detail of the Problem, or the text of the content if it is neither, e.g. for unknown routes
+func responseError(resp *http.Response, content []byte) *Error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(content)),
+		RequestID:  resp.Header.Get(api.RequestIDHeader),
+		RetryAfter: retryAfter(resp),
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), api.MIMEApplicationProblemJSON) {
+		var problem api.Problem
+		if json.Unmarshal(content, &problem) == nil {
+			e.Message = problem.Detail
+			e.Code = problem.Code
+			e.Fields = problem.Errors
+		}
+		return e
+	}
+	var response api.Response
+	if json.Unmarshal(content, &response) == nil && response.Message != "" {
+		e.Message = response.Message
+	}
+	return e
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Request failed with status %d. %s", e.StatusCode, e.Message)
+}
+
+// Is reports whether target is the Err* variable of the status code
+func (e *Error) Is(target error) bool {
+	if err, ok := statusErrors[e.StatusCode]; ok {
+		return err == target
+	}
+	return e.StatusCode >= http.StatusInternalServerError && target == ErrServer
+}
@@ -0,0 +1,170 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ansrivas/bid-tracker/pkg/api"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// leaderChangedSubscription selects every field of the events WatchLeader decodes
+const leaderChangedSubscription = `subscription($itemUuid: ID!) {
+	leaderChanged(itemUuid: $itemUuid) {
+		bid { itemUuid userUuid timestamp amount }
+		leader
+		winningBid { itemUuid userUuid timestamp amount }
+	}
+}`
+
+// GraphQLError is returned when a GraphQL response has errors, the data present is decoded all the same
+type GraphQLError struct {
+	Messages []string
+}
+
+func (e *GraphQLError) Error() string {
+	return "GraphQL request failed. " + strings.Join(e.Messages, "; ")
+}
+
+// graphQLResponse is api.GraphQLResponse as it is received
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// decode decodes the data into out, if not nil, and returns a GraphQLError if the response has errors
+func (r graphQLResponse) decode(out interface{}) error {
+	if out != nil && len(r.Data) > 0 {
+		if err := json.Unmarshal(r.Data, out); err != nil {
+			return errors.WithMessage(err, "Failed to decode the data of the GraphQL response")
+		}
+	}
+	if len(r.Errors) > 0 {
+		e := &GraphQLError{}
+		for _, err := range r.Errors {
+			e.Messages = append(e.Messages, err.Message)
+		}
+		return e
+	}
+	return nil
+}
+
+// GraphQL runs a query or mutation and decodes its data into out, e.g. a struct with a field per root field
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	body := api.GraphQLRequest{Query: query, Variables: variables}
+	resp, err := c.send(ctx, request{method: http.MethodPost, path: api.URLGraphQL, body: body})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to read the response")
+	}
+	var response graphQLResponse
+	if err := json.Unmarshal(content, &response); err != nil || (resp.StatusCode >= http.StatusBadRequest && len(response.Errors) == 0) {
+		if resp.StatusCode >= http.StatusBadRequest {
+			return responseError(resp, content)
+		}
+		return errors.WithMessage(err, "Failed to decode the GraphQL response")
+	}
+	return response.decode(out)
+}
+
+// LeaderChanges receives the bids becoming the winning bid of an item, see WatchLeader
+type LeaderChanges struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+// WatchLeader subscribes to the bids becoming the winning bid of an item, until ctx is done or the
+// subscription is closed. The events are streamed, the Doer of the client must not buffer responses.
+func (c *Client) WatchLeader(ctx context.Context, itemuuid uuid.UUID) (*LeaderChanges, error) {
+	body := api.GraphQLRequest{Query: leaderChangedSubscription, Variables: map[string]interface{}{"itemUuid": itemuuid.String()}}
+	resp, err := c.send(ctx, request{method: http.MethodPost, path: api.URLGraphQL, body: body, accept: "text/event-stream"})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		content, _ := io.ReadAll(resp.Body)
+		return nil, responseError(resp, content)
+	}
+	return &LeaderChanges{body: resp.Body, scanner: bufio.NewScanner(resp.Body)}, nil
+}
+
+// Next blocks until the next leader change. It returns io.EOF once the server completes the subscription,
+// or the error the server ended it with, e.g. a GraphQLError when the server shuts down or the item is unknown.
+func (l *LeaderChanges) Next() (bidtracker.ItemEvent, error) {
+	var event, data string
+	for l.scanner.Scan() {
+		line := l.scanner.Text()
+		switch {
+		case line == "":
+			if event == "complete" {
+				return bidtracker.ItemEvent{}, io.EOF
+			}
+			if event == "next" && data != "" {
+				var response graphQLResponse
+				if err := json.Unmarshal([]byte(data), &response); err != nil {
+					return bidtracker.ItemEvent{}, errors.WithMessage(err, "Failed to decode the event")
+				}
+				var result struct {
+					LeaderChanged *bidtracker.ItemEvent `json:"leaderChanged"`
+				}
+				if err := response.decode(&result); err != nil {
+					return bidtracker.ItemEvent{}, err
+				}
+				if result.LeaderChanged != nil {
+					return *result.LeaderChanged, nil
+				}
+			}
+			event, data = "", ""
+		case strings.HasPrefix(line, ":"):
+			// Comments keep the connection alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+		}
+	}
+	if err := l.scanner.Err(); err != nil {
+		return bidtracker.ItemEvent{}, errors.WithMessage(err, "Failed to read the subscription")
+	}
+	return bidtracker.ItemEvent{}, io.EOF
+}
+
+// Close ends the subscription
+func (l *LeaderChanges) Close() error {
+	return l.body.Close()
+}
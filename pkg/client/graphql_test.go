@@ -0,0 +1,105 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchLeader(t *testing.T) {
+	assert := assert.New(t)
+	server, adminToken := newTestServer(t)
+
+	// The events are streamed, which needs a real connection
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(err) {
+		return
+	}
+	go server.FiberApp().Listener(listener)
+	c, err := New("http://"+listener.Addr().String(), WithProxyPrefix("/proxy"), WithAPIKey(adminToken))
+	assert.Nil(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changes, err := c.WatchLeader(ctx, itemUUID)
+	if !assert.Nil(err) {
+		return
+	}
+	defer changes.Close()
+
+	// The subscription starts asynchronously, bids are placed until the first change arrives
+	stop := make(chan struct{})
+	placed := make(chan struct{})
+	go func() {
+		defer close(placed)
+		for amount := 1.0; ; amount++ {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+				c.PlaceBid(ctx, bidtracker.Bid{ItemUUID: itemUUID, UserUUID: aliceUUID, Timestamp: 1351807721, Amount: amount})
+			}
+		}
+	}()
+
+	// WHEN a bid takes the lead
+	event, err := changes.Next()
+	close(stop)
+	<-placed
+
+	// THEN it is received
+	assert.Nil(err)
+	assert.True(event.Leader)
+	assert.Equal(event.Bid, event.WinningBid)
+	assert.Equal(itemUUID, event.Bid.ItemUUID)
+	assert.Equal(aliceUUID, event.Bid.UserUUID)
+
+	// THEN subscriptions to unknown items end with the reason
+	unknown, err := c.WatchLeader(ctx, uuid.Must(uuid.NewV4()))
+	if assert.Nil(err) {
+		_, err = unknown.Next()
+		var graphQLErr *GraphQLError
+		assert.True(errors.As(err, &graphQLErr))
+		unknown.Close()
+	}
+
+	// WHEN the server shuts down, the subscription ends with the reason
+	err = nil
+	go server.Shutdown(ctx)
+	for err == nil {
+		_, err = changes.Next()
+	}
+	var graphQLErr *GraphQLError
+	if assert.True(errors.As(err, &graphQLErr)) {
+		assert.Equal([]string{bidtracker.ErrWatchStopped.Error()}, graphQLErr.Messages)
+	}
+	_, err = changes.Next()
+	assert.Equal(io.EOF, err)
+}
@@ -0,0 +1,57 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ansrivas/bid-tracker/pkg/api"
+)
+
+// Healthz checks that the server is alive
+func (c *Client) Healthz(ctx context.Context) error {
+	_, err := c.do(ctx, request{method: http.MethodGet, path: api.URLHealthz, unversioned: true}, nil)
+	return err
+}
+
+// Readyz returns the readiness of the server, along with an error matching ErrUnavailable if it is not ready.
+// It is not retried, so that the outcome is the current one.
+func (c *Client) Readyz(ctx context.Context) (api.Readiness, error) {
+	var readiness api.Readiness
+	_, err := c.withoutRetries().do(ctx, request{method: http.MethodGet, path: api.URLReadyz, unversioned: true}, &readiness)
+	return readiness, err
+}
+
+// Version returns the build information of the server
+func (c *Client) Version(ctx context.Context) (api.BuildInfo, error) {
+	var info api.BuildInfo
+	_, err := c.do(ctx, request{method: http.MethodGet, path: api.URLVersion, unversioned: true}, &info)
+	return info, err
+}
+
+// withoutRetries returns a copy of c sending every request once
+func (c *Client) withoutRetries() *Client {
+	once := *c
+	once.retries = 0
+	return &once
+}
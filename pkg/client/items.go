@@ -0,0 +1,128 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ansrivas/bid-tracker/pkg/api"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofrs/uuid"
+)
+
+// ListItems returns the item catalogue, only the items of selleruuid and in category if they are set
+func (c *Client) ListItems(ctx context.Context, selleruuid uuid.UUID, category string) ([]bidtracker.Item, error) {
+	query := url.Values{}
+	if selleruuid != uuid.Nil {
+		query.Set("selleruuid", selleruuid.String())
+	}
+	if category != "" {
+		query.Set("category", category)
+	}
+	var items []bidtracker.Item
+	_, err := c.do(ctx, request{method: http.MethodGet, path: api.URLItems, query: query}, &items)
+	return items, err
+}
+
+// SearchItems runs a full text search over the item catalogue
+func (c *Client) SearchItems(ctx context.Context, search bidtracker.ItemSearch) (bidtracker.ItemSearchResult, error) {
+	query := url.Values{}
+	if search.Text != "" {
+		query.Set("q", search.Text)
+	}
+	if len(search.Categories) > 0 {
+		query.Set("category", strings.Join(search.Categories, ","))
+	}
+	if search.Status != "" {
+		query.Set("status", string(search.Status))
+	}
+	if search.MinPrice != 0 {
+		query.Set("min_price", strconv.FormatFloat(search.MinPrice, 'f', -1, 64))
+	}
+	if search.MaxPrice != 0 {
+		query.Set("max_price", strconv.FormatFloat(search.MaxPrice, 'f', -1, 64))
+	}
+	if search.SortBy != "" {
+		query.Set("sort", string(search.SortBy))
+	}
+	if search.Limit > 0 {
+		query.Set("limit", strconv.Itoa(search.Limit))
+	}
+	if search.Offset > 0 {
+		query.Set("offset", strconv.Itoa(search.Offset))
+	}
+	var result bidtracker.ItemSearchResult
+	_, err := c.do(ctx, request{method: http.MethodGet, path: api.URLItemSearch, query: query}, &result)
+	return result, err
+}
+
+// GetItem returns the metadata of an item
+func (c *Client) GetItem(ctx context.Context, itemuuid uuid.UUID) (bidtracker.Item, error) {
+	var item bidtracker.Item
+	_, err := c.do(ctx, request{method: http.MethodGet, path: routePath(api.URLItem, itemuuid.String())}, &item)
+	return item, err
+}
+
+// CreateItem adds an item to the catalogue, it is open for bidding right away
+func (c *Client) CreateItem(ctx context.Context, item bidtracker.Item) (bidtracker.Item, error) {
+	var created bidtracker.Item
+	_, err := c.do(ctx, request{method: http.MethodPost, path: api.URLItems, body: item}, &created)
+	return created, err
+}
+
+// UpdateItem replaces the metadata of the item with the uuid of item
+func (c *Client) UpdateItem(ctx context.Context, item bidtracker.Item) (bidtracker.Item, error) {
+	var updated bidtracker.Item
+	_, err := c.do(ctx, request{method: http.MethodPut, path: routePath(api.URLItem, item.UUID.String()), body: item}, &updated)
+	return updated, err
+}
+
+// SystemAnalytics returns the totals and the histogram of the bid amounts across all items
+func (c *Client) SystemAnalytics(ctx context.Context, query bidtracker.AnalyticsQuery) (bidtracker.SystemAnalytics, error) {
+	var analytics bidtracker.SystemAnalytics
+	_, err := c.do(ctx, request{method: http.MethodGet, path: api.URLAnalytics, query: analyticsQueryValues(query)}, &analytics)
+	return analytics, err
+}
+
+// ItemAnalytics returns the activity, price series and histogram of the bid amounts of an item
+func (c *Client) ItemAnalytics(ctx context.Context, itemuuid uuid.UUID, query bidtracker.AnalyticsQuery) (bidtracker.ItemAnalytics, error) {
+	var analytics bidtracker.ItemAnalytics
+	path := routePath(api.URLAnalyticsItem, itemuuid.String())
+	_, err := c.do(ctx, request{method: http.MethodGet, path: path, query: analyticsQueryValues(query)}, &analytics)
+	return analytics, err
+}
+
+// analyticsQueryValues encodes the query the way the analytics routes read it
+func analyticsQueryValues(query bidtracker.AnalyticsQuery) url.Values {
+	values := url.Values{}
+	if seconds := int64(query.Interval.Seconds()); seconds > 0 {
+		values.Set("interval", strconv.FormatInt(seconds, 10))
+	}
+	if query.HistogramBuckets > 0 {
+		values.Set("buckets", strconv.Itoa(query.HistogramBuckets))
+	}
+	return values
+}
@@ -0,0 +1,443 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package config loads the settings of the bid tracker server from defaults, a yaml file,
+// environment variables and command line flags, each overriding the previous one.
+package config
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/api"
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/ansrivas/bid-tracker/pkg/tracing"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// StorageMemory keeps every item and bid in memory only
+	StorageMemory = "memory"
+
+	// StorageFile restores the tracker from a json snapshot at startup, and writes it back on shutdown
+	StorageFile = "file"
+)
+
+// Config of the bid tracker server
+type Config struct {
+	// Listen is the address the http server listens on, e.g. ":3000"
+	Listen string `yaml:"listen"`
+	// GRPCListen is the address the grpc server listens on, e.g. ":3001", empty disables it
+	GRPCListen string `yaml:"grpclisten"`
+	// APIVersion prefixes every route, e.g. "/api/v1"
+	APIVersion string `yaml:"apiversion"`
+	// APIVersion2 serves every route a second time with the v2 envelope, e.g. "/api/v2", empty disables it
+	APIVersion2 string `yaml:"apiversion2"`
+	// ProxyPrefix goes before the api version when the server runs behind a reverse proxy
+	ProxyPrefix string `yaml:"proxyprefix"`
+	// ProblemDetails sends every error as RFC 7807 problem details, not only to the clients accepting them
+	ProblemDetails bool `yaml:"problemdetails"`
+	// Items are open for bidding at startup. Items with a title are validated like the items created through the api.
+	Items    []bidtracker.Item `yaml:"items"`
+	Storage  Storage           `yaml:"storage"`
+	Auth     Auth              `yaml:"auth"`
+	Limits   api.RateLimits    `yaml:"limits"`
+	Shill    Shill             `yaml:"shill"`
+	LogLevel string            `yaml:"loglevel"`
+	Tracing  tracing.Config    `yaml:"tracing"`
+	// ShutdownDelay is how long readiness fails before connections are refused once a shutdown starts,
+	// so that load balancers stop routing new requests first
+	ShutdownDelay time.Duration `yaml:"shutdowndelay"`
+	// ShutdownTimeout bounds the time given to in-flight requests once a shutdown starts
+	ShutdownTimeout time.Duration `yaml:"shutdowntimeout"`
+}
+
+// Storage selects where the state of the tracker is kept
+type Storage struct {
+	Backend string `yaml:"backend"`
+	// Path of the snapshot of the file backend
+	Path string `yaml:"path"`
+	// KeysPath is where the file backend keeps the api keys, path with a .keys suffix if empty
+	KeysPath string `yaml:"keyspath"`
+}
+
+// Auth enables api keys once an admin key is set
+type Auth struct {
+	// AdminAPIKey bootstraps the key store, formatted as <id>.<secret>
+	AdminAPIKey string `yaml:"adminapikey"`
+	// PolicyFile replaces the default role based access policy
+	PolicyFile string `yaml:"policyfile"`
+}
+
+// Shill configures the shill bidding detection
+type Shill struct {
+	// Block rejects the bids of flagged users instead of only reporting them
+	Block bool `yaml:"block"`
+}
+
+// Default returns the settings used when nothing else is configured
+func Default() Config {
+	return Config{
+		Listen:      ":3000",
+		APIVersion:  "/api/v1",
+		APIVersion2: "/api/v2",
+		Items: []bidtracker.Item{
+			{UUID: uuid.Must(uuid.FromString("b2f9ee6d-79fe-4b14-9c19-35a69a89219a"))},
+			{UUID: uuid.Must(uuid.FromString("b16ab43e-aa13-4079-b8c5-592e81312c01"))},
+		},
+		Storage:         Storage{Backend: StorageMemory},
+		Limits:          api.DefaultRateLimits(),
+		LogLevel:        zerolog.InfoLevel.String(),
+		Tracing:         tracing.DefaultConfig(),
+		ShutdownTimeout: 15 * time.Second,
+	}
+}
+
+// envVars maps the environment variables to the settings they override
+var envVars = []struct {
+	name  string
+	apply func(c *Config, value string) error
+}{
+	{"BIDTRACKER_LISTEN", func(c *Config, v string) error { c.Listen = v; return nil }},
+	{"BIDTRACKER_GRPC_LISTEN", func(c *Config, v string) error { c.GRPCListen = v; return nil }},
+	{"BIDTRACKER_API_VERSION", func(c *Config, v string) error { c.APIVersion = v; return nil }},
+	{"BIDTRACKER_API_VERSION2", func(c *Config, v string) error { c.APIVersion2 = v; return nil }},
+	{"BIDTRACKER_PROXY_PREFIX", func(c *Config, v string) error { c.ProxyPrefix = v; return nil }},
+	{"BIDTRACKER_PROBLEM_DETAILS", func(c *Config, v string) (err error) {
+		c.ProblemDetails, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"BIDTRACKER_STORAGE_BACKEND", func(c *Config, v string) error { c.Storage.Backend = v; return nil }},
+	{"BIDTRACKER_STORAGE_PATH", func(c *Config, v string) error { c.Storage.Path = v; return nil }},
+	{"BIDTRACKER_STORAGE_KEYS_PATH", func(c *Config, v string) error { c.Storage.KeysPath = v; return nil }},
+	{"BIDTRACKER_ADMIN_API_KEY", func(c *Config, v string) error { c.Auth.AdminAPIKey = v; return nil }},
+	{"BIDTRACKER_POLICY_FILE", func(c *Config, v string) error { c.Auth.PolicyFile = v; return nil }},
+	{"BIDTRACKER_BLOCK_SHILL_BIDS", func(c *Config, v string) (err error) {
+		c.Shill.Block, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"BIDTRACKER_LOG_LEVEL", func(c *Config, v string) error { c.LogLevel = v; return nil }},
+	{"BIDTRACKER_TRACING_EXPORTER", func(c *Config, v string) error { c.Tracing.Exporter = tracing.Exporter(v); return nil }},
+	{"BIDTRACKER_SHUTDOWN_DELAY", func(c *Config, v string) (err error) {
+		c.ShutdownDelay, err = time.ParseDuration(v)
+		return err
+	}},
+	{"BIDTRACKER_SHUTDOWN_TIMEOUT", func(c *Config, v string) (err error) {
+		c.ShutdownTimeout, err = time.ParseDuration(v)
+		return err
+	}},
+}
+
+// ConfigFileEnv names the config file when the -config flag is not given
+const ConfigFileEnv = "BIDTRACKER_CONFIG"
+
+// Load builds the config from the defaults, the yaml file named by the -config flag or BIDTRACKER_CONFIG,
+// the BIDTRACKER_* environment variables and the flags in args, then validates it.
+// getenv is usually os.Getenv.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	config := Default()
+
+	flags := flag.NewFlagSet("bid-tracker", flag.ContinueOnError)
+	configFile := flags.String("config", getenv(ConfigFileEnv), "yaml config file")
+	listen := flags.String("listen", "", "address to listen on, e.g. :3000")
+	grpcListen := flags.String("grpc-listen", "", "address the grpc server listens on, e.g. :3001")
+	apiVersion := flags.String("api-version", "", "prefix of every route, e.g. /api/v1")
+	proxyPrefix := flags.String("proxy-prefix", "", "prefix before the api version when running behind a proxy")
+	storage := flags.String("storage", "", "storage backend")
+	logLevel := flags.String("log-level", "", "log level, e.g. debug")
+	if err := flags.Parse(args); err != nil {
+		// flag.ErrHelp is kept as is, the usage has been printed already
+		if errors.Is(err, flag.ErrHelp) {
+			return config, err
+		}
+		return config, errors.WithMessage(err, "Invalid command line")
+	}
+
+	if *configFile != "" {
+		if err := config.loadFile(*configFile); err != nil {
+			return config, err
+		}
+	}
+
+	for _, env := range envVars {
+		if value := getenv(env.name); value != "" {
+			if err := env.apply(&config, value); err != nil {
+				return config, errors.WithMessagef(err, "Invalid value of %s", env.name)
+			}
+		}
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			config.Listen = *listen
+		case "grpc-listen":
+			config.GRPCListen = *grpcListen
+		case "api-version":
+			config.APIVersion = *apiVersion
+		case "proxy-prefix":
+			config.ProxyPrefix = *proxyPrefix
+		case "storage":
+			config.Storage.Backend = *storage
+		case "log-level":
+			config.LogLevel = *logLevel
+		}
+	})
+
+	return config, config.Validate()
+}
+
+// loadFile overrides config with the settings present in the yaml file at path
+func (c *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to read the config file")
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return errors.WithMessagef(err, "Failed to parse the config file %s", path)
+	}
+	return nil
+}
+
+// Validate checks every setting and reports all the problems at once
+func (c Config) Validate() error {
+	var problems []string
+	check := func(field string, err error) {
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", field, err.Error()))
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		check("listen", errors.Errorf("%q is not a host:port address", c.Listen))
+	}
+	if c.GRPCListen != "" {
+		if _, _, err := net.SplitHostPort(c.GRPCListen); err != nil {
+			check("grpclisten", errors.Errorf("%q is not a host:port address", c.GRPCListen))
+		} else if c.GRPCListen == c.Listen {
+			check("grpclisten", errors.Errorf("%q is already used by listen", c.GRPCListen))
+		}
+	}
+	check("apiversion", validatePrefix(c.APIVersion))
+	if err := validatePrefix(c.APIVersion2); err != nil {
+		check("apiversion2", err)
+	} else if c.APIVersion2 != "" && c.APIVersion2 == c.APIVersion {
+		check("apiversion2", errors.Errorf("%q is already used by apiversion", c.APIVersion2))
+	}
+	check("proxyprefix", validatePrefix(c.ProxyPrefix))
+
+	seen := make(map[uuid.UUID]bool, len(c.Items))
+	for i, item := range c.Items {
+		field := fmt.Sprintf("items[%d]", i)
+		switch {
+		case item.UUID == uuid.Nil:
+			check(field, errors.New("itemuuid is required"))
+		case seen[item.UUID]:
+			check(field, errors.Errorf("itemuuid %s is repeated", item.UUID))
+		case item.Title != "":
+			check(field, item.Validate())
+		}
+		seen[item.UUID] = true
+	}
+
+	switch c.Storage.Backend {
+	case StorageMemory:
+	case StorageFile:
+		if c.Storage.Path == "" {
+			check("storage.path", errors.New("a path is required by the file backend"))
+		}
+	default:
+		check("storage.backend", errors.Errorf("unknown backend %q, expected %s or %s", c.Storage.Backend, StorageMemory, StorageFile))
+	}
+	if c.Auth.PolicyFile != "" {
+		_, err := os.Stat(c.Auth.PolicyFile)
+		check("auth.policyfile", err)
+	}
+	check("limits", c.Limits.Validate())
+	if _, err := zerolog.ParseLevel(c.LogLevel); err != nil {
+		check("loglevel", err)
+	}
+	check("tracing", c.Tracing.Validate())
+	if c.ShutdownDelay < 0 {
+		check("shutdowndelay", errors.New("can not be negative"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		check("shutdowntimeout", errors.New("must be positive"))
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("Invalid configuration, %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validatePrefix accepts empty prefixes or absolute paths without a trailing slash
+func validatePrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	if !strings.HasPrefix(prefix, "/") || strings.HasSuffix(prefix, "/") {
+		return errors.Errorf("%q must start with / and not end with /", prefix)
+	}
+	return nil
+}
+
+// NewTracker returns a tracker holding the seed items, with the configured shill bidding detection.
+// Its state is only restored by Restore.
+func (c Config) NewTracker() (*bidtracker.BidManagement, error) {
+	var bare []uuid.UUID
+	var described []bidtracker.Item
+	for _, item := range c.Items {
+		if item.Title == "" {
+			bare = append(bare, item.UUID)
+		} else {
+			described = append(described, item)
+		}
+	}
+
+	tracker := bidtracker.NewBidManagement(bare...)
+	for _, item := range described {
+		if err := tracker.AddItem(item); err != nil {
+			return nil, errors.WithMessage(err, "Failed to seed the items")
+		}
+	}
+
+	if c.Shill.Block {
+		shillConfig := bidtracker.DefaultShillDetectorConfig()
+		shillConfig.Block = true
+		tracker.SetShillDetector(bidtracker.NewShillDetector(shillConfig))
+	}
+	return tracker, nil
+}
+
+// Restore replaces the state of tracker with the snapshot of the file backend when there is one,
+// keeping the seed items it lacks. It does nothing for the memory backend.
+func (c Config) Restore(tracker *bidtracker.BidManagement) error {
+	if c.Storage.Backend != StorageFile {
+		return nil
+	}
+	snapshot, err := bidtracker.LoadSnapshot(c.Storage.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	restored := make(map[uuid.UUID]bool, len(snapshot.Items))
+	for _, item := range snapshot.Items {
+		restored[item.Item.UUID] = true
+	}
+	for _, seed := range tracker.Snapshot().Items {
+		if !restored[seed.Item.UUID] {
+			snapshot.Items = append(snapshot.Items, seed)
+		}
+	}
+	return errors.WithMessage(tracker.Restore(snapshot), "Failed to restore the snapshot")
+}
+
+// Persist writes the state of tracker to the storage backend, it does nothing for the memory backend
+func (c Config) Persist(tracker *bidtracker.BidManagement) error {
+	if c.Storage.Backend != StorageFile {
+		return nil
+	}
+	return tracker.SaveSnapshot(c.Storage.Path)
+}
+
+// keysPath is where the file backend keeps the api keys
+func (c Config) keysPath() string {
+	if c.Storage.KeysPath != "" {
+		return c.Storage.KeysPath
+	}
+	return c.Storage.Path + ".keys"
+}
+
+// NewKeyStore creates the api key store bootstrapped with the admin key, or returns nil when api keys are disabled.
+// The file backend restores the keys saved on the last shutdown, a rotated or revoked admin key stays that way.
+func (c Config) NewKeyStore() (*apikey.Store, error) {
+	if c.Auth.AdminAPIKey == "" {
+		return nil, nil
+	}
+	keys := apikey.NewStore()
+	if _, err := keys.Import(c.Auth.AdminAPIKey, apikey.Spec{
+		Name:   "bootstrap-admin",
+		Role:   string(api.RoleAdmin),
+		Scopes: []apikey.Scope{apikey.ScopeAdmin},
+	}); err != nil {
+		return nil, errors.WithMessage(err, "Failed to import the admin api key")
+	}
+	if c.Storage.Backend == StorageFile {
+		if err := keys.Load(c.keysPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// KeysFile is where the api keys are saved every time one changes, it is empty for the memory backend
+func (c Config) KeysFile() string {
+	if c.Storage.Backend != StorageFile {
+		return ""
+	}
+	return c.keysPath()
+}
+
+// PersistKeys writes the api keys to the storage backend, it does nothing for the memory backend
+func (c Config) PersistKeys(keys *apikey.Store) error {
+	if c.KeysFile() == "" || keys == nil {
+		return nil
+	}
+	return keys.Save(c.KeysFile())
+}
+
+// ReadinessChecks returns the checks of the storage backend, keyed by name
+func (c Config) ReadinessChecks() map[string]api.ReadinessCheck {
+	checks := make(map[string]api.ReadinessCheck)
+	if c.Storage.Backend == StorageFile {
+		dir := filepath.Dir(c.Storage.Path)
+		// The snapshot is written on shutdown, its directory must stay writable
+		checks["storage"] = func(ctx context.Context) error {
+			probe, err := os.CreateTemp(dir, ".readyz-*")
+			if err != nil {
+				return errors.WithMessage(err, "The snapshot directory is not writable")
+			}
+			probe.Close()
+			return os.Remove(probe.Name())
+		}
+	}
+	return checks
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(name string) string {
+		return vars[name]
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	assert := assert.New(t)
+
+	config, err := Load(nil, env(nil))
+	assert.Nil(err)
+	assert.Equal(Default(), config)
+}
+
+func TestLoadExampleFile(t *testing.T) {
+	assert := assert.New(t)
+
+	config, err := Load([]string{"-config", "../../config.example.yaml"}, env(nil))
+	assert.Nil(err)
+	assert.Len(config.Items, 2)
+	assert.Equal("Vintage camera", config.Items[1].Title)
+	assert.Equal(5, config.Limits.Routes["POST /bids"].Requests)
+	assert.Equal(time.Second, config.Limits.Read.Period)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	assert := assert.New(t)
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(os.WriteFile(file, []byte("listen: \":4000\"\napiversion: /api/v2\napiversion2: /api/v3\nproxyprefix: /file\nloglevel: warn\n"), 0o600))
+
+	config, err := Load([]string{"-listen", ":6000", "-grpc-listen", ":6001"}, env(map[string]string{
+		ConfigFileEnv:                 file,
+		"BIDTRACKER_GRPC_LISTEN":      ":5001",
+		"BIDTRACKER_LISTEN":           ":5000",
+		"BIDTRACKER_PROXY_PREFIX":     "/env",
+		"BIDTRACKER_BLOCK_SHILL_BIDS": "true",
+		"BIDTRACKER_PROBLEM_DETAILS":  "true",
+	}))
+	assert.Nil(err)
+	assert.Equal(":6000", config.Listen, "flags override the environment")
+	assert.Equal(":6001", config.GRPCListen)
+	assert.Equal("/env", config.ProxyPrefix, "the environment overrides the file")
+	assert.Equal("/api/v2", config.APIVersion)
+	assert.Equal("/api/v3", config.APIVersion2)
+	assert.Equal("warn", config.LogLevel)
+	assert.True(config.Shill.Block)
+	assert.True(config.ProblemDetails)
+}
+
+func TestLoadErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := Load([]string{"-listen", "3000", "-storage", "postgres"}, env(map[string]string{
+		"BIDTRACKER_API_VERSION": "api/v1/",
+		"BIDTRACKER_GRPC_LISTEN": "3001",
+	}))
+	if assert.NotNil(err) {
+		assert.Contains(err.Error(), `listen: "3000" is not a host:port address`)
+		assert.Contains(err.Error(), `grpclisten: "3001" is not a host:port address`)
+		assert.Contains(err.Error(), "apiversion:")
+		assert.Contains(err.Error(), `storage.backend: unknown backend "postgres"`)
+	}
+
+	_, err = Load([]string{"-api-version", "/api/v2"}, env(nil))
+	if assert.NotNil(err, "the v2 routes can not take the place of the v1 routes") {
+		assert.Contains(err.Error(), `apiversion2: "/api/v2" is already used by apiversion`)
+	}
+
+	_, err = Load(nil, env(map[string]string{"BIDTRACKER_BLOCK_SHILL_BIDS": "maybe"}))
+	assert.NotNil(err)
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(os.WriteFile(file, []byte("listn: \":4000\"\n"), 0o600))
+	_, err = Load([]string{"-config", file}, env(nil))
+	assert.NotNil(err, "unknown fields are reported")
+
+	config := Default()
+	config.Items = append(config.Items, config.Items[0], bidtracker.Item{})
+	assert.NotNil(config.Validate())
+}
+
+func TestNewTracker(t *testing.T) {
+	assert := assert.New(t)
+
+	config, err := Load([]string{"-config", "../../config.example.yaml"}, env(nil))
+	assert.Nil(err)
+	tracker, err := config.NewTracker()
+	assert.Nil(err)
+
+	item, err := tracker.GetItem(uuid.Must(uuid.FromString("b16ab43e-aa13-4079-b8c5-592e81312c01")))
+	assert.Nil(err)
+	assert.Equal([]string{"photography"}, item.Categories)
+	items, _ := tracker.Size()
+	assert.Equal(2, items)
+}
+
+func TestFileStorage(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	config, err := Load([]string{"-storage", StorageFile}, env(map[string]string{"BIDTRACKER_STORAGE_PATH": path}))
+	assert.Nil(err)
+
+	// WHEN there is no snapshot yet, the seed items are used
+	tracker, err := config.NewTracker()
+	assert.Nil(err)
+	assert.Nil(config.Restore(tracker))
+	itemUUID := config.Items[0].UUID
+	assert.Nil(tracker.InsertBid(&bidtracker.Bid{ItemUUID: itemUUID, UserUUID: uuid.Must(uuid.NewV4()), Amount: 10}))
+	assert.Nil(config.Persist(tracker))
+
+	// THEN a restart restores the bids, and seed items added since then show up too
+	extra := uuid.Must(uuid.NewV4())
+	config.Items = append(config.Items, bidtracker.Item{UUID: extra})
+	restored, err := config.NewTracker()
+	assert.Nil(err)
+	assert.Nil(config.Restore(restored))
+	winning, err := restored.CurrentWinningBid(itemUUID)
+	assert.Nil(err)
+	assert.Equal(10.0, winning.Amount)
+	items, bids := restored.Size()
+	assert.Equal(3, items)
+	assert.Equal(1, bids)
+
+	// THEN a corrupt snapshot fails the restore
+	assert.Nil(os.WriteFile(path, []byte("{"), 0o600))
+	assert.NotNil(config.Restore(restored))
+
+	_, err = Load([]string{"-storage", StorageFile}, env(nil))
+	assert.NotNil(err, "The file backend needs a path")
+}
+
+func TestFileStorageKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	admin := "bootstrap.0123456789abcdef0123"
+	path := filepath.Join(t.TempDir(), "state.json")
+	config, err := Load([]string{"-storage", StorageFile}, env(map[string]string{
+		"BIDTRACKER_STORAGE_PATH":  path,
+		"BIDTRACKER_ADMIN_API_KEY": admin,
+	}))
+	assert.Nil(err)
+
+	// WHEN keys are issued and the admin key is rotated before a restart
+	keys, err := config.NewKeyStore()
+	assert.Nil(err)
+	token, _, err := keys.Create(apikey.Spec{Name: "partner", Role: "bidder", Scopes: []apikey.Scope{apikey.ScopeBidsRead}})
+	assert.Nil(err)
+	rotated, _, err := keys.Rotate("bootstrap", 0, 0)
+	assert.Nil(err)
+	assert.Nil(config.PersistKeys(keys))
+	_, err = os.Stat(path + ".keys")
+	assert.Nil(err)
+
+	// THEN they are restored, and the old admin secret stays invalid
+	restored, err := config.NewKeyStore()
+	assert.Nil(err)
+	_, err = restored.Authenticate(token)
+	assert.Nil(err)
+	_, err = restored.Authenticate(rotated)
+	assert.Nil(err)
+	_, err = restored.Authenticate(admin)
+	assert.Equal(apikey.ErrInvalidKey, err)
+
+	config.Storage.Backend = StorageMemory
+	memory, err := config.NewKeyStore()
+	assert.Nil(err)
+	assert.Len(memory.List(), 1)
+
+	config.Auth.AdminAPIKey = ""
+	disabled, err := config.NewKeyStore()
+	assert.Nil(err)
+	assert.Nil(disabled)
+}
+
+func TestReadinessChecks(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Empty(Default().ReadinessChecks())
+
+	config := Default()
+	config.Storage = Storage{Backend: StorageFile, Path: filepath.Join(t.TempDir(), "state.json")}
+	check := config.ReadinessChecks()["storage"]
+	if assert.NotNil(check) {
+		assert.Nil(check(context.Background()))
+	}
+
+	config.Storage.Path = filepath.Join(t.TempDir(), "missing", "state.json")
+	assert.NotNil(config.ReadinessChecks()["storage"](context.Background()))
+}
@@ -0,0 +1,220 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package rpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/api"
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	pb "github.com/ansrivas/bid-tracker/pkg/rpc/bidtrackerpb"
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// APIKeyMetadata is the metadata key clients send their api key in.
+// A bearer token in the authorization metadata is accepted as well.
+const APIKeyMetadata = "x-api-key"
+
+// methodPermissions maps every method to the permission of the REST route it mirrors
+var methodPermissions = map[string]api.Permission{
+	pb.BidTracker_PlaceBid_FullMethodName:      api.PermissionBidsCreate,
+	pb.BidTracker_ListBids_FullMethodName:      api.PermissionBidsRead,
+	pb.BidTracker_GetWinningBid_FullMethodName: api.PermissionBidsRead,
+	pb.BidTracker_ListUserBids_FullMethodName:  api.PermissionBidsRead,
+	pb.BidTracker_WatchItem_FullMethodName:     api.PermissionBidsRead,
+}
+
+// methodRoutes maps every method to the REST route it mirrors, whose rate limiter it draws from
+var methodRoutes = map[string]string{
+	pb.BidTracker_PlaceBid_FullMethodName:      http.MethodPost + " " + api.URLBidItem,
+	pb.BidTracker_ListBids_FullMethodName:      http.MethodGet + " " + api.URLBidGetAll,
+	pb.BidTracker_GetWinningBid_FullMethodName: http.MethodGet + " " + api.URLBidGetWinning,
+	pb.BidTracker_ListUserBids_FullMethodName:  http.MethodGet + " " + api.URLUserGetAllBids,
+	pb.BidTracker_WatchItem_FullMethodName:     http.MethodGet + " " + api.URLBidGetAll,
+}
+
+// authenticator only lets calls through carrying an api key that is permitted the method,
+// within the rate limits of the matching REST route. Every call is allowed if no api key store
+// has been configured, and every method is unlimited if no rate limits have been configured.
+type authenticator struct {
+	apiKeys *apikey.Store
+	policy  *api.Policy
+
+	// authFailures limits the failed authentications of every IP, along with the REST api
+	authFailures *api.RateLimiter
+	// limiters are keyed by method
+	limiters map[string]*api.RateLimiter
+}
+
+func newAuthenticator(o *options) *authenticator {
+	a := &authenticator{apiKeys: o.apiKeys, policy: o.policy, limiters: make(map[string]*api.RateLimiter)}
+	if o.rateLimits == nil {
+		return a
+	}
+
+	for method, route := range methodRoutes {
+		if limiter := o.rateLimits.Route(route); limiter != nil {
+			a.limiters[method] = limiter
+		}
+	}
+	a.authFailures = o.rateLimits.AuthFailures()
+	return a
+}
+
+func (a *authenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	key, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.rateLimit(ctx, key, info.FullMethod); err != nil {
+		return nil, err
+	}
+	if placeBid, ok := req.(*pb.PlaceBidRequest); ok && a.apiKeys != nil {
+		if err := a.authorizeBidder(key, placeBid.GetBid().GetUserUuid()); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	key, err := a.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	if err := a.rateLimit(ss.Context(), key, info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// authorize returns the api key of the call if it is permitted the method.
+// IPs that used up their budget of failed authentications are refused before the key is checked.
+func (a *authenticator) authorize(ctx context.Context, method string) (apikey.Key, error) {
+	if a.apiKeys == nil {
+		return apikey.Key{}, nil
+	}
+
+	ip := "ip:" + peerIP(ctx)
+	if a.authFailures != nil {
+		if retryAfter := a.authFailures.Wait(ip); retryAfter > 0 {
+			return apikey.Key{}, status.Errorf(codes.ResourceExhausted, "Too many failed authentications, retry in %s", ceilSeconds(retryAfter))
+		}
+	}
+
+	key, err := a.authenticate(ctx)
+	if err != nil {
+		if a.authFailures != nil {
+			a.authFailures.Take(ip)
+		}
+		return key, err
+	}
+
+	permission, ok := methodPermissions[method]
+	if !ok || !a.policy.Permits(key, permission) {
+		return key, status.Errorf(codes.PermissionDenied, "Permission denied, %s is required", permission)
+	}
+	return key, nil
+}
+
+func (a *authenticator) authenticate(ctx context.Context) (apikey.Key, error) {
+	token := apiKeyFromMetadata(ctx)
+	if token == "" {
+		return apikey.Key{}, status.Error(codes.Unauthenticated, "Missing api key")
+	}
+	key, err := a.apiKeys.Authenticate(token)
+	if err != nil {
+		return key, status.Errorf(codes.Unauthenticated, "Failed to authenticate: %s", err)
+	}
+	return key, nil
+}
+
+// rateLimit refuses the call once the client used up the budget of the method
+func (a *authenticator) rateLimit(ctx context.Context, key apikey.Key, method string) error {
+	limiter, ok := a.limiters[method]
+	if !ok {
+		return nil
+	}
+	if retryAfter := limiter.Take(rateLimitClient(ctx, key)); retryAfter > 0 {
+		return status.Errorf(codes.ResourceExhausted, "Rate limit exceeded, retry in %s", ceilSeconds(retryAfter))
+	}
+	return nil
+}
+
+// rateLimitClient identifies the caller like the REST api, preferring the authenticated user over the api key over the IP
+func rateLimitClient(ctx context.Context, key apikey.Key) string {
+	if key.UserUUID != uuid.Nil {
+		return "user:" + key.UserUUID.String()
+	}
+	if key.ID != "" {
+		return "key:" + key.ID
+	}
+	return "ip:" + peerIP(ctx)
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// ceilSeconds rounds a duration up to whole seconds, like the Retry-After header of the REST api
+func ceilSeconds(d time.Duration) time.Duration {
+	return time.Duration(math.Ceil(d.Seconds())) * time.Second
+}
+
+// authorizeBidder only lets moderators bid for other users than the one of their api key, like the REST api.
+// Invalid uuids are left to PlaceBid to report.
+func (a *authenticator) authorizeBidder(key apikey.Key, useruuid string) error {
+	bidder, err := uuid.FromString(useruuid)
+	if err != nil || a.policy.BidsFor(key, bidder) {
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied, "Permission denied, %s is required to bid for another user", api.PermissionBidsCreateAny)
+}
+
+func apiKeyFromMetadata(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(APIKeyMetadata); len(values) > 0 {
+		return values[0]
+	}
+	if values := md.Get("authorization"); len(values) > 0 && strings.HasPrefix(values[0], "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer "))
+	}
+	return ""
+}
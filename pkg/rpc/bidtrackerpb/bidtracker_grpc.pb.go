@@ -0,0 +1,318 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.1
+// source: bidtracker.proto
+
+package bidtrackerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	BidTracker_PlaceBid_FullMethodName      = "/bidtracker.v1.BidTracker/PlaceBid"
+	BidTracker_ListBids_FullMethodName      = "/bidtracker.v1.BidTracker/ListBids"
+	BidTracker_GetWinningBid_FullMethodName = "/bidtracker.v1.BidTracker/GetWinningBid"
+	BidTracker_ListUserBids_FullMethodName  = "/bidtracker.v1.BidTracker/ListUserBids"
+	BidTracker_WatchItem_FullMethodName     = "/bidtracker.v1.BidTracker/WatchItem"
+)
+
+// BidTrackerClient is the client API for BidTracker service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BidTrackerClient interface {
+	// PlaceBid inserts a new bid, like POST /bids
+	PlaceBid(ctx context.Context, in *PlaceBidRequest, opts ...grpc.CallOption) (*PlaceBidResponse, error)
+	// ListBids returns a page of the bids on an item, like GET /bids/{itemuuid}
+	ListBids(ctx context.Context, in *ListBidsRequest, opts ...grpc.CallOption) (*ListBidsResponse, error)
+	// GetWinningBid returns the current winning bid on an item, like GET /bids/{itemuuid}/winning
+	GetWinningBid(ctx context.Context, in *GetWinningBidRequest, opts ...grpc.CallOption) (*GetWinningBidResponse, error)
+	// ListUserBids returns a page of the bids of a user, like GET /users/{useruuid}/bids
+	ListUserBids(ctx context.Context, in *ListUserBidsRequest, opts ...grpc.CallOption) (*ListUserBidsResponse, error)
+	// WatchItem streams the bids accepted on an item until the client cancels.
+	// The stream ends with RESOURCE_EXHAUSTED if the client does not keep up, and UNAVAILABLE on shutdown.
+	WatchItem(ctx context.Context, in *WatchItemRequest, opts ...grpc.CallOption) (BidTracker_WatchItemClient, error)
+}
+
+type bidTrackerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBidTrackerClient(cc grpc.ClientConnInterface) BidTrackerClient {
+	return &bidTrackerClient{cc}
+}
+
+func (c *bidTrackerClient) PlaceBid(ctx context.Context, in *PlaceBidRequest, opts ...grpc.CallOption) (*PlaceBidResponse, error) {
+	out := new(PlaceBidResponse)
+	err := c.cc.Invoke(ctx, BidTracker_PlaceBid_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bidTrackerClient) ListBids(ctx context.Context, in *ListBidsRequest, opts ...grpc.CallOption) (*ListBidsResponse, error) {
+	out := new(ListBidsResponse)
+	err := c.cc.Invoke(ctx, BidTracker_ListBids_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bidTrackerClient) GetWinningBid(ctx context.Context, in *GetWinningBidRequest, opts ...grpc.CallOption) (*GetWinningBidResponse, error) {
+	out := new(GetWinningBidResponse)
+	err := c.cc.Invoke(ctx, BidTracker_GetWinningBid_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bidTrackerClient) ListUserBids(ctx context.Context, in *ListUserBidsRequest, opts ...grpc.CallOption) (*ListUserBidsResponse, error) {
+	out := new(ListUserBidsResponse)
+	err := c.cc.Invoke(ctx, BidTracker_ListUserBids_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bidTrackerClient) WatchItem(ctx context.Context, in *WatchItemRequest, opts ...grpc.CallOption) (BidTracker_WatchItemClient, error) {
+	stream, err := c.cc.NewStream(ctx, &BidTracker_ServiceDesc.Streams[0], BidTracker_WatchItem_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &bidTrackerWatchItemClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type BidTracker_WatchItemClient interface {
+	Recv() (*ItemEvent, error)
+	grpc.ClientStream
+}
+
+type bidTrackerWatchItemClient struct {
+	grpc.ClientStream
+}
+
+func (x *bidTrackerWatchItemClient) Recv() (*ItemEvent, error) {
+	m := new(ItemEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BidTrackerServer is the server API for BidTracker service.
+// All implementations must embed UnimplementedBidTrackerServer
+// for forward compatibility
+type BidTrackerServer interface {
+	// PlaceBid inserts a new bid, like POST /bids
+	PlaceBid(context.Context, *PlaceBidRequest) (*PlaceBidResponse, error)
+	// ListBids returns a page of the bids on an item, like GET /bids/{itemuuid}
+	ListBids(context.Context, *ListBidsRequest) (*ListBidsResponse, error)
+	// GetWinningBid returns the current winning bid on an item, like GET /bids/{itemuuid}/winning
+	GetWinningBid(context.Context, *GetWinningBidRequest) (*GetWinningBidResponse, error)
+	// ListUserBids returns a page of the bids of a user, like GET /users/{useruuid}/bids
+	ListUserBids(context.Context, *ListUserBidsRequest) (*ListUserBidsResponse, error)
+	// WatchItem streams the bids accepted on an item until the client cancels.
+	// The stream ends with RESOURCE_EXHAUSTED if the client does not keep up, and UNAVAILABLE on shutdown.
+	WatchItem(*WatchItemRequest, BidTracker_WatchItemServer) error
+	mustEmbedUnimplementedBidTrackerServer()
+}
+
+// UnimplementedBidTrackerServer must be embedded to have forward compatible implementations.
+type UnimplementedBidTrackerServer struct {
+}
+
+func (UnimplementedBidTrackerServer) PlaceBid(context.Context, *PlaceBidRequest) (*PlaceBidResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PlaceBid not implemented")
+}
+func (UnimplementedBidTrackerServer) ListBids(context.Context, *ListBidsRequest) (*ListBidsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBids not implemented")
+}
+func (UnimplementedBidTrackerServer) GetWinningBid(context.Context, *GetWinningBidRequest) (*GetWinningBidResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWinningBid not implemented")
+}
+func (UnimplementedBidTrackerServer) ListUserBids(context.Context, *ListUserBidsRequest) (*ListUserBidsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserBids not implemented")
+}
+func (UnimplementedBidTrackerServer) WatchItem(*WatchItemRequest, BidTracker_WatchItemServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchItem not implemented")
+}
+func (UnimplementedBidTrackerServer) mustEmbedUnimplementedBidTrackerServer() {}
+
+// UnsafeBidTrackerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BidTrackerServer will
+// result in compilation errors.
+type UnsafeBidTrackerServer interface {
+	mustEmbedUnimplementedBidTrackerServer()
+}
+
+func RegisterBidTrackerServer(s grpc.ServiceRegistrar, srv BidTrackerServer) {
+	s.RegisterService(&BidTracker_ServiceDesc, srv)
+}
+
+func _BidTracker_PlaceBid_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlaceBidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BidTrackerServer).PlaceBid(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BidTracker_PlaceBid_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BidTrackerServer).PlaceBid(ctx, req.(*PlaceBidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BidTracker_ListBids_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBidsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BidTrackerServer).ListBids(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BidTracker_ListBids_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BidTrackerServer).ListBids(ctx, req.(*ListBidsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BidTracker_GetWinningBid_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWinningBidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BidTrackerServer).GetWinningBid(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BidTracker_GetWinningBid_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BidTrackerServer).GetWinningBid(ctx, req.(*GetWinningBidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BidTracker_ListUserBids_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUserBidsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BidTrackerServer).ListUserBids(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BidTracker_ListUserBids_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BidTrackerServer).ListUserBids(ctx, req.(*ListUserBidsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BidTracker_WatchItem_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchItemRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BidTrackerServer).WatchItem(m, &bidTrackerWatchItemServer{stream})
+}
+
+type BidTracker_WatchItemServer interface {
+	Send(*ItemEvent) error
+	grpc.ServerStream
+}
+
+type bidTrackerWatchItemServer struct {
+	grpc.ServerStream
+}
+
+func (x *bidTrackerWatchItemServer) Send(m *ItemEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// BidTracker_ServiceDesc is the grpc.ServiceDesc for BidTracker service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BidTracker_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bidtracker.v1.BidTracker",
+	HandlerType: (*BidTrackerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PlaceBid",
+			Handler:    _BidTracker_PlaceBid_Handler,
+		},
+		{
+			MethodName: "ListBids",
+			Handler:    _BidTracker_ListBids_Handler,
+		},
+		{
+			MethodName: "GetWinningBid",
+			Handler:    _BidTracker_GetWinningBid_Handler,
+		},
+		{
+			MethodName: "ListUserBids",
+			Handler:    _BidTracker_ListUserBids_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchItem",
+			Handler:       _BidTracker_WatchItem_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "bidtracker.proto",
+}
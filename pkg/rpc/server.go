@@ -0,0 +1,291 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package rpc serves the tracker over gRPC next to the REST API, see bidtrackerpb/bidtracker.proto
+package rpc
+
+import (
+	"context"
+	"net"
+
+	"github.com/ansrivas/bid-tracker/pkg/api"
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	pb "github.com/ansrivas/bid-tracker/pkg/rpc/bidtrackerpb"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Option configures a Server
+type Option struct {
+	setup func(o *options)
+}
+
+type options struct {
+	apiKeys    *apikey.Store
+	policy     *api.Policy
+	metrics    *api.Metrics
+	rateLimits *api.RateLimiters
+}
+
+// WithAPIKeys returns an Option that enforces api key authentication on every method using the given store,
+// with the same permissions as the matching REST routes
+func WithAPIKeys(store *apikey.Store) Option {
+	return Option{func(o *options) {
+		o.apiKeys = store
+	}}
+}
+
+// WithPolicy returns an Option that configures the role based access policy.
+// api.DefaultPolicy is used if this option is not provided.
+func WithPolicy(policy *api.Policy) Option {
+	return Option{func(o *options) {
+		o.policy = policy
+	}}
+}
+
+// WithRateLimiters returns an Option that limits every method with the bucket of the matching REST route,
+// and the failed authentications of every IP. Given the limiters of the REST routes, both apis draw from
+// the same buckets. Methods are unlimited if this option is not provided.
+func WithRateLimiters(limiters *api.RateLimiters) Option {
+	return Option{func(o *options) {
+		o.rateLimits = limiters
+	}}
+}
+
+// WithMetrics returns an Option that counts the open WatchItem streams as subscriptions
+func WithMetrics(metrics *api.Metrics) Option {
+	return Option{func(o *options) {
+		o.metrics = metrics
+	}}
+}
+
+// Server is the gRPC server of a tracker
+type Server struct {
+	server  *grpc.Server
+	tracker *bidtracker.BidManagement
+}
+
+// NewServer returns a Server serving tracker, usually the one the REST API serves
+func NewServer(tracker *bidtracker.BidManagement, opts ...Option) (*Server, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt.setup(o)
+	}
+	if o.policy == nil {
+		o.policy = api.DefaultPolicy()
+	}
+	if err := o.policy.Validate(); err != nil {
+		return nil, errors.WithMessage(err, "Failed to configure access policy")
+	}
+
+	auth := newAuthenticator(o)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(auth.unary),
+		grpc.ChainStreamInterceptor(auth.stream),
+	)
+	pb.RegisterBidTrackerServer(server, &bidTrackerServer{tracker: tracker, metrics: o.metrics})
+	return &Server{server: server, tracker: tracker}, nil
+}
+
+// Serve accepts connections on lis until Shutdown is called
+func (s *Server) Serve(lis net.Listener) error {
+	return s.server.Serve(lis)
+}
+
+// Shutdown ends the WatchItem streams, then waits for the other calls to finish until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	// Streams would otherwise keep GracefulStop waiting until ctx is done
+	s.tracker.CloseWatches()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+// bidTrackerServer implements pb.BidTrackerServer
+type bidTrackerServer struct {
+	pb.UnimplementedBidTrackerServer
+	tracker *bidtracker.BidManagement
+	metrics *api.Metrics
+}
+
+func (s *bidTrackerServer) PlaceBid(ctx context.Context, req *pb.PlaceBidRequest) (*pb.PlaceBidResponse, error) {
+	bid, err := fromProto(req.GetBid())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tracker.InsertBidContext(ctx, &bid); err != nil {
+		return nil, errorStatus(err)
+	}
+	return &pb.PlaceBidResponse{Bid: toProto(bid)}, nil
+}
+
+func (s *bidTrackerServer) ListBids(ctx context.Context, req *pb.ListBidsRequest) (*pb.ListBidsResponse, error) {
+	itemuuid, err := parseUUID("item_uuid", req.GetItemUuid())
+	if err != nil {
+		return nil, err
+	}
+	query := bidtracker.BidQuery{Limit: int(req.GetLimit()), Cursor: req.GetCursor()}
+	if err := query.Validate(); err != nil {
+		return nil, errorStatus(err)
+	}
+
+	page, err := s.tracker.ListBids(itemuuid, query)
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+	return &pb.ListBidsResponse{Bids: toProtos(page.Bids), NextCursor: page.NextCursor}, nil
+}
+
+func (s *bidTrackerServer) GetWinningBid(ctx context.Context, req *pb.GetWinningBidRequest) (*pb.GetWinningBidResponse, error) {
+	itemuuid, err := parseUUID("item_uuid", req.GetItemUuid())
+	if err != nil {
+		return nil, err
+	}
+	bid, err := s.tracker.CurrentWinningBid(itemuuid)
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+	return &pb.GetWinningBidResponse{Bid: toProto(*bid)}, nil
+}
+
+func (s *bidTrackerServer) ListUserBids(ctx context.Context, req *pb.ListUserBidsRequest) (*pb.ListUserBidsResponse, error) {
+	useruuid, err := parseUUID("user_uuid", req.GetUserUuid())
+	if err != nil {
+		return nil, err
+	}
+	query := bidtracker.BidQuery{Limit: int(req.GetLimit()), Cursor: req.GetCursor()}
+	if err := query.Validate(); err != nil {
+		return nil, errorStatus(err)
+	}
+
+	page, err := s.tracker.ListBidsByUser(useruuid, query)
+	if err != nil {
+		return nil, errorStatus(err)
+	}
+	return &pb.ListUserBidsResponse{Bids: toProtos(page.Bids), NextCursor: page.NextCursor}, nil
+}
+
+func (s *bidTrackerServer) WatchItem(req *pb.WatchItemRequest, stream pb.BidTracker_WatchItemServer) error {
+	itemuuid, err := parseUUID("item_uuid", req.GetItemUuid())
+	if err != nil {
+		return err
+	}
+	watch, err := s.tracker.WatchItem(itemuuid)
+	if err != nil {
+		return errorStatus(err)
+	}
+	defer watch.Close()
+
+	if s.metrics != nil {
+		s.metrics.SubscriptionOpened()
+		defer s.metrics.SubscriptionClosed()
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-watch.Events():
+			if !ok {
+				return watchStatus(watch.Err())
+			}
+			err := stream.Send(&pb.ItemEvent{
+				Bid:        toProto(event.Bid),
+				Leader:     event.Leader,
+				WinningBid: toProto(event.WinningBid),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchStatus is the status ending a stream whose watch was closed with err
+func watchStatus(err error) error {
+	switch err {
+	case nil:
+		return nil
+	case bidtracker.ErrWatchLagged:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+}
+
+// errorStatus maps an error of the tracker to a status with the code of the REST error table
+func errorStatus(err error) error {
+	return status.Error(api.GRPCCode(err), err.Error())
+}
+
+func parseUUID(field, value string) (uuid.UUID, error) {
+	id, err := uuid.FromString(value)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "%s can not be parsed successfully. %s", field, err)
+	}
+	return id, nil
+}
+
+func fromProto(bid *pb.Bid) (bidtracker.Bid, error) {
+	if bid == nil {
+		return bidtracker.Bid{}, status.Error(codes.InvalidArgument, "bid is required")
+	}
+	itemuuid, err := parseUUID("item_uuid", bid.GetItemUuid())
+	if err != nil {
+		return bidtracker.Bid{}, err
+	}
+	useruuid, err := parseUUID("user_uuid", bid.GetUserUuid())
+	if err != nil {
+		return bidtracker.Bid{}, err
+	}
+	return bidtracker.Bid{ItemUUID: itemuuid, UserUUID: useruuid, Timestamp: bid.GetTimestamp(), Amount: bid.GetAmount()}, nil
+}
+
+func toProto(bid bidtracker.Bid) *pb.Bid {
+	return &pb.Bid{
+		ItemUuid:  bid.ItemUUID.String(),
+		UserUuid:  bid.UserUUID.String(),
+		Timestamp: bid.Timestamp,
+		Amount:    bid.Amount,
+	}
+}
+
+func toProtos(bids []bidtracker.Bid) []*pb.Bid {
+	protos := make([]*pb.Bid, len(bids))
+	for i, bid := range bids {
+		protos[i] = toProto(bid)
+	}
+	return protos
+}
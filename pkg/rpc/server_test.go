@@ -0,0 +1,278 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ansrivas/bid-tracker/pkg/api"
+	"github.com/ansrivas/bid-tracker/pkg/apikey"
+	"github.com/ansrivas/bid-tracker/pkg/bidtracker"
+	pb "github.com/ansrivas/bid-tracker/pkg/rpc/bidtrackerpb"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	testItemUUID = "b2f9ee6d-79fe-4b14-9c19-35a69a89219a"
+	testUserUUID = "ae8f7716-867b-4479-b455-c5769e7475ba"
+)
+
+// newTestClient serves tracker over an in-memory connection and returns a client to it
+func newTestClient(t *testing.T, tracker *bidtracker.BidManagement, opts ...Option) (pb.BidTrackerClient, *Server) {
+	server, err := NewServer(tracker, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewBidTrackerClient(conn), server
+}
+
+func TestBidTrackerServer(t *testing.T) {
+	assert := assert.New(t)
+
+	tracker := bidtracker.NewBidManagement(uuid.Must(uuid.FromString(testItemUUID)))
+	client, _ := newTestClient(t, tracker)
+	ctx := context.Background()
+
+	// WHEN
+	placed, err := client.PlaceBid(ctx, &pb.PlaceBidRequest{Bid: &pb.Bid{ItemUuid: testItemUUID, UserUuid: testUserUUID, Timestamp: 1, Amount: 32}})
+
+	// THEN
+	assert.Nil(err)
+	assert.Equal(32.0, placed.GetBid().GetAmount())
+
+	winning, err := client.GetWinningBid(ctx, &pb.GetWinningBidRequest{ItemUuid: testItemUUID})
+	assert.Nil(err)
+	assert.Equal(testUserUUID, winning.GetBid().GetUserUuid())
+
+	bids, err := client.ListBids(ctx, &pb.ListBidsRequest{ItemUuid: testItemUUID})
+	assert.Nil(err)
+	assert.Len(bids.GetBids(), 1)
+
+	userBids, err := client.ListUserBids(ctx, &pb.ListUserBidsRequest{UserUuid: testUserUUID, Limit: 10})
+	assert.Nil(err)
+	assert.Len(userBids.GetBids(), 1)
+
+	_, err = client.PlaceBid(ctx, &pb.PlaceBidRequest{Bid: &pb.Bid{ItemUuid: "cef31b6b-cdeb-4035-8d42-a4f33b2d02fe", UserUuid: testUserUUID, Amount: 1}})
+	assert.Equal(codes.NotFound, status.Code(err))
+	_, err = client.PlaceBid(ctx, &pb.PlaceBidRequest{Bid: &pb.Bid{ItemUuid: "not-a-uuid", UserUuid: testUserUUID}})
+	assert.Equal(codes.InvalidArgument, status.Code(err))
+	_, err = client.PlaceBid(ctx, &pb.PlaceBidRequest{})
+	assert.Equal(codes.InvalidArgument, status.Code(err))
+	_, err = client.ListBids(ctx, &pb.ListBidsRequest{ItemUuid: testItemUUID, Limit: 5000})
+	assert.Equal(codes.InvalidArgument, status.Code(err))
+	_, err = client.GetWinningBid(ctx, &pb.GetWinningBidRequest{ItemUuid: "cef31b6b-cdeb-4035-8d42-a4f33b2d02fe"})
+	assert.Equal(codes.NotFound, status.Code(err))
+}
+
+func TestWatchItem(t *testing.T) {
+	assert := assert.New(t)
+
+	tracker := bidtracker.NewBidManagement(uuid.Must(uuid.FromString(testItemUUID)))
+	metrics := api.NewMetrics()
+	client, server := newTestClient(t, tracker, WithMetrics(metrics))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchItem(ctx, &pb.WatchItemRequest{ItemUuid: testItemUUID})
+	assert.Nil(err)
+
+	// The watch is only registered once the server handles the call, bids are retried until one is seen
+	events := make(chan *pb.ItemEvent)
+	go func() {
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				close(events)
+				return
+			}
+			events <- event
+		}
+	}()
+	var event *pb.ItemEvent
+	for amount := 1.0; event == nil; amount++ {
+		_, err := client.PlaceBid(ctx, &pb.PlaceBidRequest{Bid: &pb.Bid{ItemUuid: testItemUUID, UserUuid: testUserUUID, Amount: amount}})
+		assert.Nil(err)
+		select {
+		case event = <-events:
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	assert.True(event.GetLeader())
+	assert.Equal(event.GetBid().GetAmount(), event.GetWinningBid().GetAmount())
+
+	// Shutting down ends the stream instead of waiting for the client
+	assert.Nil(server.Shutdown(ctx))
+	for range events {
+	}
+	_, err = stream.Recv()
+	assert.NotNil(err)
+
+	missing, err := client.WatchItem(ctx, &pb.WatchItemRequest{ItemUuid: "cef31b6b-cdeb-4035-8d42-a4f33b2d02fe"})
+	if err == nil {
+		_, err = missing.Recv()
+	}
+	assert.NotNil(err)
+}
+
+func TestAPIKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	store := apikey.NewStore()
+	bidder, _, err := store.Create(apikey.Spec{Name: "bidder", Role: string(api.RoleBidder), Scopes: []apikey.Scope{apikey.ScopeBidsRead}})
+	assert.Nil(err)
+
+	tracker := bidtracker.NewBidManagement(uuid.Must(uuid.FromString(testItemUUID)))
+	client, _ := newTestClient(t, tracker, WithAPIKeys(store))
+	bid := &pb.PlaceBidRequest{Bid: &pb.Bid{ItemUuid: testItemUUID, UserUuid: testUserUUID, Amount: 1}}
+
+	_, err = client.PlaceBid(context.Background(), bid)
+	assert.Equal(codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), APIKeyMetadata, bidder)
+	_, err = client.PlaceBid(ctx, bid)
+	assert.Equal(codes.PermissionDenied, status.Code(err), "the key lacks the bids:write scope")
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+bidder)
+	_, err = client.ListBids(ctx, &pb.ListBidsRequest{ItemUuid: testItemUUID})
+	assert.Nil(err)
+
+	// WHEN a bidder bids for another user than the one of its key, it is denied
+	owner, _, err := store.Create(apikey.Spec{
+		Name:     "owner",
+		Role:     string(api.RoleBidder),
+		UserUUID: uuid.Must(uuid.FromString(testUserUUID)),
+		Scopes:   []apikey.Scope{apikey.ScopeBidsWrite},
+	})
+	assert.Nil(err)
+	ctx = metadata.AppendToOutgoingContext(context.Background(), APIKeyMetadata, owner)
+	_, err = client.PlaceBid(ctx, bid)
+	assert.Nil(err)
+	other := &pb.PlaceBidRequest{Bid: &pb.Bid{ItemUuid: testItemUUID, UserUuid: "f475091b-a8f1-4679-83bd-483b616e5260", Amount: 2}}
+	_, err = client.PlaceBid(ctx, other)
+	assert.Equal(codes.PermissionDenied, status.Code(err))
+
+	// THEN an integration key bound to no user may bid for them
+	integration, _, err := store.Create(apikey.Spec{Name: "partner", Role: string(api.RoleIntegration), Scopes: []apikey.Scope{apikey.ScopeBidsWrite}})
+	assert.Nil(err)
+	other.Bid.Amount = 100
+	_, err = client.PlaceBid(metadata.AppendToOutgoingContext(context.Background(), APIKeyMetadata, integration), other)
+	assert.Nil(err)
+}
+
+func TestRateLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	store := apikey.NewStore()
+	reader, _, err := store.Create(apikey.Spec{Name: "reader", Role: string(api.RoleBidder), Scopes: []apikey.Scope{apikey.ScopeBidsRead}})
+	assert.Nil(err)
+
+	tracker := bidtracker.NewBidManagement(uuid.Must(uuid.FromString(testItemUUID)))
+	client, _ := newTestClient(t, tracker, WithAPIKeys(store), WithRateLimiters(api.NewRateLimiters(api.RateLimits{
+		Read:         api.RateLimit{Requests: 1, Period: time.Minute, Burst: 2},
+		AuthFailures: api.RateLimit{Requests: 1, Period: time.Minute, Burst: 1},
+		Routes: map[string]api.RateLimit{
+			"GET " + api.URLBidGetWinning: {},
+		},
+	})))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), APIKeyMetadata, reader)
+	list := &pb.ListBidsRequest{ItemUuid: testItemUUID}
+
+	// WHEN the read budget is used up, the methods mirroring GET routes share it
+	_, err = client.ListBids(ctx, list)
+	assert.Nil(err)
+	_, err = client.ListUserBids(ctx, &pb.ListUserBidsRequest{UserUuid: testUserUUID})
+	assert.Equal(codes.NotFound, status.Code(err))
+	_, err = client.ListBids(ctx, list)
+	assert.Equal(codes.ResourceExhausted, status.Code(err))
+
+	// THEN the method of the route configured as unlimited is not limited at all
+	for i := 0; i < 3; i++ {
+		_, err = client.GetWinningBid(ctx, &pb.GetWinningBidRequest{ItemUuid: testItemUUID})
+		assert.Equal(codes.NotFound, status.Code(err))
+	}
+
+	// WHEN the budget of failed authentications is used up, even a valid key is refused
+	guess := metadata.AppendToOutgoingContext(context.Background(), APIKeyMetadata, "guess.secret")
+	_, err = client.GetWinningBid(guess, &pb.GetWinningBidRequest{ItemUuid: testItemUUID})
+	assert.Equal(codes.Unauthenticated, status.Code(err))
+	_, err = client.GetWinningBid(guess, &pb.GetWinningBidRequest{ItemUuid: testItemUUID})
+	assert.Equal(codes.ResourceExhausted, status.Code(err))
+	_, err = client.GetWinningBid(ctx, &pb.GetWinningBidRequest{ItemUuid: testItemUUID})
+	assert.Equal(codes.ResourceExhausted, status.Code(err))
+}
+
+func TestRateLimitsSharedWithREST(t *testing.T) {
+	assert := assert.New(t)
+
+	store := apikey.NewStore()
+	reader, _, err := store.Create(apikey.Spec{Name: "reader", Role: string(api.RoleBidder), Scopes: []apikey.Scope{apikey.ScopeBidsRead}})
+	assert.Nil(err)
+
+	tracker := bidtracker.NewBidManagement(uuid.Must(uuid.FromString(testItemUUID)))
+	limiters := api.NewRateLimiters(api.RateLimits{Read: api.RateLimit{Requests: 1, Period: time.Minute, Burst: 2}})
+	rest := api.NewAPIWithSettings(tracker, fiber.New())
+	assert.Nil(api.RegisterRoutes(rest, api.RegisterWithAPIVersion("/api/v1"), api.RegisterWithAPIKeys(store), api.RegisterWithRateLimiters(limiters)))
+	client, _ := newTestClient(t, tracker, WithAPIKeys(store), WithRateLimiters(limiters))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), APIKeyMetadata, reader)
+
+	listREST := func() int {
+		req := httptest.NewRequest("GET", "/api/v1/bids/"+testItemUUID, nil)
+		req.Header.Add(api.APIKeyHeader, reader)
+		resp, err := rest.FiberApp().Test(req)
+		assert.Nil(err)
+		return resp.StatusCode
+	}
+
+	// WHEN the read budget is spent half over REST and half over grpc
+	assert.Equal(fiber.StatusOK, listREST())
+	_, err = client.ListBids(ctx, &pb.ListBidsRequest{ItemUuid: testItemUUID})
+	assert.Nil(err)
+
+	// THEN both apis refuse the next read
+	_, err = client.ListBids(ctx, &pb.ListBidsRequest{ItemUuid: testItemUUID})
+	assert.Equal(codes.ResourceExhausted, status.Code(err))
+	assert.Equal(fiber.StatusTooManyRequests, listREST())
+}
@@ -0,0 +1,141 @@
+//
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package tracing sets up the OpenTelemetry tracer provider of the bid tracker
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// Exporter selects where spans are sent
+type Exporter string
+
+const (
+	// ExporterNone drops every span
+	ExporterNone Exporter = "none"
+
+	// ExporterOTLP sends spans to an OTLP/HTTP collector
+	ExporterOTLP Exporter = "otlp"
+
+	// ExporterStdout writes spans to stdout as json
+	ExporterStdout Exporter = "stdout"
+
+	// ExporterMemory keeps spans in memory, for tests
+	ExporterMemory Exporter = "memory"
+)
+
+// DefaultServiceName is the service name reported when none is configured
+const DefaultServiceName = "bid-tracker"
+
+// Config of the tracer provider
+type Config struct {
+	Exporter Exporter `yaml:"exporter"`
+	// Endpoint of the OTLP collector as host:port. The OTEL_EXPORTER_OTLP_* variables apply when empty.
+	Endpoint string `yaml:"endpoint"`
+	Insecure bool   `yaml:"insecure"`
+	// SampleRatio of the traces started here, every trace when zero.
+	// Traces continued from a caller follow its decision.
+	SampleRatio float64 `yaml:"sampleratio"`
+	ServiceName string  `yaml:"servicename"`
+}
+
+// DefaultConfig does not export anything
+func DefaultConfig() Config {
+	return Config{
+		Exporter:    ExporterNone,
+		ServiceName: DefaultServiceName,
+	}
+}
+
+// Validate checks the config for unknown exporters and out of range ratios
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case "", ExporterNone, ExporterOTLP, ExporterStdout, ExporterMemory:
+	default:
+		return fmt.Errorf("Unknown tracing exporter %q", c.Exporter)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("Sample ratio must be between 0 and 1, got %v", c.SampleRatio)
+	}
+	return nil
+}
+
+// NewExporter returns the span exporter selected by the config, nil for ExporterNone.
+// ExporterMemory returns a *tracetest.InMemoryExporter to read the spans back.
+func NewExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if config.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, options...)
+		return exporter, errors.WithMessage(err, "Failed to create the OTLP exporter")
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, errors.WithMessage(err, "Failed to create the stdout exporter")
+	case ExporterMemory:
+		return tracetest.NewInMemoryExporter(), nil
+	}
+	return nil, fmt.Errorf("Unknown tracing exporter %q", config.Exporter)
+}
+
+// NewTracerProvider returns a tracer provider sending the spans to exporter, which may be nil to drop them.
+// The memory exporter is synchronous so that tests see spans as soon as they end.
+func NewTracerProvider(exporter sdktrace.SpanExporter, config Config) *sdktrace.TracerProvider {
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	sampleRatio := config.SampleRatio
+	if sampleRatio == 0 {
+		sampleRatio = 1
+	}
+
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	}
+	switch exporter.(type) {
+	case nil:
+	case *tracetest.InMemoryExporter:
+		options = append(options, sdktrace.WithSyncer(exporter))
+	default:
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+	return sdktrace.NewTracerProvider(options...)
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2020 Ankur Srivastava
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestConfigValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(DefaultConfig().Validate())
+	assert.Nil(Config{Exporter: ExporterOTLP, SampleRatio: 0.5}.Validate())
+	assert.NotNil(Config{Exporter: "jaeger"}.Validate())
+	assert.NotNil(Config{SampleRatio: 2}.Validate())
+}
+
+func TestNewExporter(t *testing.T) {
+	assert := assert.New(t)
+
+	exporter, err := NewExporter(context.Background(), DefaultConfig())
+	assert.Nil(err)
+	assert.Nil(exporter)
+
+	exporter, err = NewExporter(context.Background(), Config{Exporter: ExporterMemory})
+	assert.Nil(err)
+	memory, ok := exporter.(*tracetest.InMemoryExporter)
+	if !assert.True(ok) {
+		return
+	}
+
+	provider := NewTracerProvider(memory, Config{})
+	_, span := provider.Tracer("test").Start(context.Background(), "span")
+	span.End()
+	assert.Len(memory.GetSpans(), 1)
+}